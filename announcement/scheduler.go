@@ -0,0 +1,73 @@
+// Package announcement menjalankan penjadwalan pengumuman: menerbitkan
+// pengumuman yang waktunya tiba dan menonaktifkan yang sudah kadaluarsa.
+package announcement
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusAktif    = "aktif"
+	StatusNonAktif = "non-aktif"
+)
+
+// transition mengubah status pengumuman yang id-nya dipilih oleh due, lalu
+// menandai kolom meta agar tidak diproses ulang
+func transition(db *gorm.DB, due *gorm.DB, status, markColumn string, now time.Time) (int64, error) {
+	var ids []uint
+	if err := due.Pluck("pengumuman_id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Pengumuman{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"status": status, "updated_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.PengumumanMeta{}).Where("pengumuman_id IN ?", ids).
+			Update(markColumn, now).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// Run menerbitkan pengumuman yang publish_at-nya sudah lewat dan
+// menonaktifkan pengumuman yang expire_at-nya sudah lewat
+func Run(db *gorm.DB, now time.Time) (published, expired int64, err error) {
+	published, err = transition(db,
+		db.Model(&model.PengumumanMeta{}).Where("publish_at <= ? AND published_at IS NULL AND deleted_at IS NULL", now),
+		StatusAktif, "published_at", now)
+	if err != nil {
+		return 0, 0, err
+	}
+	expired, err = transition(db,
+		db.Model(&model.PengumumanMeta{}).Where("expire_at <= ? AND expired_at IS NULL AND deleted_at IS NULL", now),
+		StatusNonAktif, "expired_at", now)
+	return published, expired, err
+}
+
+// StartScheduler menjalankan Run secara berkala di background
+func StartScheduler(cfg config.PengumumanConfig) {
+	go func() {
+		for {
+			if db, err := config.GetDB(); err == nil {
+				published, expired, err := Run(db, time.Now())
+				if err != nil {
+					fmt.Printf("Gagal memproses jadwal pengumuman: %v\n", err)
+				} else if published > 0 || expired > 0 {
+					fmt.Printf("Pengumuman terbit: %d, kadaluarsa: %d\n", published, expired)
+				}
+			}
+			time.Sleep(cfg.SchedulerInterval)
+		}
+	}()
+}
@@ -0,0 +1,189 @@
+// Package audit mencatat aksi user ke tabel audit_logs. Controller mencatat
+// perubahan data dengan Track; middleware Audit menulis catatan tersebut
+// setelah request selesai, sehingga pelaku, IP dan status response ikut
+// tersimpan. Request tulis tanpa Track tetap dicatat sebagai aksi HTTP.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+)
+
+const contextKey = "audit_changes"
+
+// Change adalah satu perubahan data yang dicatat controller
+type Change struct {
+	// Action contoh: "pengumuman.delete", "bimbingan.update_status"
+	Action     string
+	EntityType string
+	EntityID   interface{}
+	// Before dan After adalah isi data sebelum dan sesudah perubahan; nil
+	// untuk data yang baru dibuat atau dihapus
+	Before interface{}
+	After  interface{}
+	Detail string
+	// ActorID dan ActorRole diisi jika request belum memiliki user di
+	// context, misalnya saat login
+	ActorID   uint
+	ActorRole string
+}
+
+// FieldChange adalah nilai lama dan baru sebuah field
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Track mencatat perubahan data untuk ditulis setelah request selesai
+func Track(c *gin.Context, change Change) {
+	var changes []Change
+	if v, ok := c.Get(contextKey); ok {
+		changes = v.([]Change)
+	}
+	c.Set(contextKey, append(changes, change))
+}
+
+// toMap mengubah struct menjadi map sesuai tag json-nya
+func toMap(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Diff membandingkan dua data dan mengembalikan field yang berubah
+func Diff(before, after interface{}) (map[string]FieldChange, error) {
+	old, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]FieldChange{}
+	for k, v := range old {
+		if nv, ok := cur[k]; !ok || !reflect.DeepEqual(v, nv) {
+			diff[k] = FieldChange{Old: v, New: cur[k]}
+		}
+	}
+	for k, v := range cur {
+		if _, ok := old[k]; !ok {
+			diff[k] = FieldChange{New: v}
+		}
+	}
+	return diff, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// entry membuat baris audit dari perubahan dan data request
+func entry(c *gin.Context, change Change) model.AuditLog {
+	e := model.AuditLog{
+		ActorID:    change.ActorID,
+		ActorRole:  change.ActorRole,
+		Action:     change.Action,
+		EntityType: change.EntityType,
+		Method:     c.Request.Method,
+		Path:       truncate(c.Request.URL.Path, 255),
+		StatusCode: c.Writer.Status(),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  truncate(c.Request.UserAgent(), 255),
+		Detail:     change.Detail,
+	}
+	if change.EntityID != nil {
+		e.EntityID = truncate(fmt.Sprint(change.EntityID), 64)
+	}
+	if e.ActorID == 0 {
+		if userID, ok := c.Get("user_id"); ok {
+			e.ActorID, _ = userID.(uint)
+		}
+	}
+	if e.ActorRole == "" {
+		e.ActorRole = c.GetString("user_role")
+	}
+	if id, ok := c.Get("impersonator_id"); ok {
+		if adminID, _ := id.(uint); adminID != 0 {
+			e.ImpersonatorID = &adminID
+		}
+	}
+
+	if change.Before != nil || change.After != nil {
+		diff, err := Diff(change.Before, change.After)
+		if err != nil {
+			fmt.Printf("Gagal membuat diff audit %s: %v\n", change.Action, err)
+		} else if raw, err := json.Marshal(diff); err == nil {
+			e.Changes = raw
+		}
+	}
+	return e
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// Flush menulis semua perubahan yang dicatat selama request. Request tulis
+// tanpa Track dan semua request dengan token impersonasi dicatat sebagai aksi
+// HTTP. Kegagalan hanya ditulis ke log agar response tidak terpengaruh.
+func Flush(c *gin.Context) {
+	var changes []Change
+	if v, ok := c.Get(contextKey); ok {
+		changes = v.([]Change)
+	}
+
+	if len(changes) == 0 {
+		_, impersonated := c.Get("impersonator_id")
+		_, authenticated := c.Get("user_id")
+		if !impersonated && !isWrite(c.Request.Method) {
+			return
+		}
+		// Percobaan gagal tanpa login (misalnya token salah) tidak dicatat
+		if !authenticated && c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		changes = []Change{{Action: c.Request.Method + " " + route}}
+	}
+
+	entries := make([]model.AuditLog, 0, len(changes))
+	for _, change := range changes {
+		entries = append(entries, entry(c, change))
+	}
+
+	db, err := config.GetDB()
+	if err != nil {
+		fmt.Printf("Gagal mencatat audit %s: %v\n", entries[0].Action, err)
+		return
+	}
+	if err := db.Create(&entries).Error; err != nil {
+		fmt.Printf("Gagal mencatat audit %s: %v\n", entries[0].Action, err)
+	}
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"gorm.io/gorm"
+)
+
+// cleanupBatch membatasi jumlah baris yang dihapus per query agar tabel
+// tidak terkunci lama
+const cleanupBatch = 1000
+
+// Cleanup menghapus audit log yang lebih tua dari retention dan mengembalikan
+// jumlah baris yang dihapus
+func Cleanup(db *gorm.DB, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-retention)
+	var total int64
+	for {
+		var ids []uint
+		if err := db.Model(&model.AuditLog{}).Where("created_at < ?", cutoff).
+			Order("id").Limit(cleanupBatch).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		res := db.Where("id IN ?", ids).Delete(&model.AuditLog{})
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+	}
+}
+
+// StartRetention menjalankan Cleanup secara berkala di background
+func StartRetention(cfg config.AuditConfig) {
+	if cfg.Retention <= 0 {
+		return
+	}
+	go func() {
+		for {
+			if db, err := config.GetDB(); err == nil {
+				n, err := Cleanup(db, cfg.Retention)
+				if err != nil {
+					fmt.Printf("Gagal membersihkan audit log: %v\n", err)
+				} else if n > 0 {
+					fmt.Printf("%d audit log lebih tua dari %s dihapus\n", n, cfg.Retention)
+				}
+			}
+			time.Sleep(cfg.CleanupInterval)
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// AppConfig berisi pengaturan umum aplikasi
+type AppConfig struct {
+	// Env: "development", "staging" atau "production"
+	Env string
+	// AdminRoles adalah role yang boleh mengakses endpoint /admin
+	AdminRoles []string
+	// ImpersonationTTL adalah umur default token impersonasi admin
+	ImpersonationTTL time.Duration
+	// ImpersonationMaxTTL adalah umur maksimum yang boleh diminta admin
+	ImpersonationMaxTTL time.Duration
+	// TrustedProxies adalah IP/CIDR reverse proxy yang header X-Forwarded-For
+	// miliknya dipercaya. Kosong berarti aplikasi tidak di belakang proxy dan
+	// IP client diambil dari koneksi langsung.
+	TrustedProxies []string
+}
+
+var App AppConfig
+
+// IsProduction bernilai true jika aplikasi berjalan di mode production
+func (a AppConfig) IsProduction() bool {
+	return strings.EqualFold(a.Env, "production")
+}
+
+// IsAdmin memeriksa apakah role termasuk role admin
+func (a AppConfig) IsAdmin(role string) bool {
+	for _, r := range a.AdminRoles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadAppConfig memuat pengaturan umum dari environment variables
+func LoadAppConfig() {
+	App = AppConfig{
+		Env:        strings.ToLower(envString("APP_ENV", "development")),
+		AdminRoles: envList("ADMIN_ROLES", []string{"Admin"}),
+
+		ImpersonationTTL:    envDuration("IMPERSONATION_TTL", 15*time.Minute),
+		ImpersonationMaxTTL: envDuration("IMPERSONATION_MAX_TTL", time.Hour),
+
+		TrustedProxies: envList("TRUSTED_PROXIES", nil),
+	}
+	if App.ImpersonationMaxTTL < App.ImpersonationTTL {
+		App.ImpersonationMaxTTL = App.ImpersonationTTL
+	}
+	log.Printf("Aplikasi berjalan di mode %s", App.Env)
+}
@@ -0,0 +1,24 @@
+package config
+
+import "time"
+
+// AuditConfig mengatur masa simpan audit log
+type AuditConfig struct {
+	// Retention adalah umur audit log sebelum dihapus; 0 berarti disimpan selamanya
+	Retention time.Duration
+	// CleanupInterval adalah jarak antar pembersihan audit log lama
+	CleanupInterval time.Duration
+}
+
+var Audit AuditConfig
+
+// LoadAuditConfig memuat pengaturan audit log dari environment variables
+func LoadAuditConfig() {
+	Audit = AuditConfig{
+		Retention:       envDuration("AUDIT_RETENTION", 365*24*time.Hour),
+		CleanupInterval: envDuration("AUDIT_CLEANUP_INTERVAL", 24*time.Hour),
+	}
+	if Audit.CleanupInterval < time.Minute {
+		Audit.CleanupInterval = time.Minute
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+// CISVerifyConfig mengatur cache dan circuit breaker verifikasi token CIS
+type CISVerifyConfig struct {
+	// CacheTTL adalah lama hasil verifikasi disimpan; tidak pernah melewati exp token
+	CacheTTL time.Duration
+	// CacheSize adalah jumlah maksimum token yang disimpan di cache
+	CacheSize int
+	// BreakerThreshold adalah jumlah kegagalan berturut-turut sebelum CIS dianggap mati
+	BreakerThreshold int
+	// BreakerCooldown adalah lama request ke CIS dihentikan setelah breaker terbuka
+	BreakerCooldown time.Duration
+}
+
+var CISVerify CISVerifyConfig
+
+// LoadCISVerifyConfig memuat pengaturan verifikasi token CIS dari environment variables
+func LoadCISVerifyConfig() {
+	CISVerify = CISVerifyConfig{
+		CacheTTL:         envDuration("CIS_VERIFY_CACHE_TTL", 5*time.Minute),
+		CacheSize:        int(envInt64("CIS_VERIFY_CACHE_SIZE", 10000)),
+		BreakerThreshold: int(envInt64("CIS_BREAKER_THRESHOLD", 5)),
+		BreakerCooldown:  envDuration("CIS_BREAKER_COOLDOWN", 30*time.Second),
+	}
+	if CISVerify.CacheSize < 1 {
+		CISVerify.CacheSize = 1
+	}
+	if CISVerify.BreakerThreshold < 1 {
+		CISVerify.BreakerThreshold = 1
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envString mengembalikan nilai environment variable atau nilai default jika kosong
+func envString(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt64 membaca environment variable sebagai int64
+func envInt64(key string, def int64) int64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.Printf("Warning: %s=%q bukan angka, menggunakan default %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// envBool membaca environment variable sebagai boolean
+func envBool(key string, def bool) bool {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Warning: %s=%q bukan boolean, menggunakan default %t", key, v, def)
+		return def
+	}
+	return b
+}
+
+// envDuration membaca environment variable sebagai durasi (contoh: 30s, 5m)
+func envDuration(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Warning: %s=%q bukan durasi valid, menggunakan default %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+// envList membaca environment variable yang dipisah koma
+func envList(key string, def []string) []string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	return SplitList(v)
+}
+
+// SplitList memecah string yang dipisah koma dan membuang item kosong
+func SplitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// JWTPreviousKey adalah kunci lama yang masih diterima untuk verifikasi token
+type JWTPreviousKey struct {
+	KID string
+	// Secret untuk kunci HMAC, atau PublicKeyFile untuk kunci RS256/EdDSA
+	Secret        string
+	PublicKeyFile string
+}
+
+// JWTConfig mengatur penandatanganan token internal
+type JWTConfig struct {
+	// Algorithm: "HS256", "RS256" atau "EdDSA"
+	Algorithm string
+	// KID adalah id kunci aktif yang dipakai untuk menandatangani token baru
+	KID string
+	// Secret dipakai untuk HS256 (JWT_SECRET)
+	Secret string
+	// PrivateKeyFile adalah file PEM kunci privat untuk RS256/EdDSA
+	PrivateKeyFile string
+	// PreviousKeys tetap diterima saat verifikasi agar rotasi kunci tidak
+	// membuat semua user logout
+	PreviousKeys []JWTPreviousKey
+	// LegacySecret adalah secret HS256 yang dulu tertanam di kode. Token tanpa
+	// kid yang ditandatangani dengannya hanya diverifikasi, tidak pernah
+	// dipakai menandatangani, dan hanya sampai LegacyUntil. Isi LegacyUntil
+	// dengan waktu deploy ditambah 6 jam (umur token lama); setelah itu,
+	// atau jika dikosongkan, user dengan token lama harus login ulang.
+	LegacySecret string
+	LegacyUntil  time.Time
+	// TTL adalah umur access token; dibuat singkat karena bisa diperpanjang
+	// dengan refresh token
+	TTL time.Duration
+	// RefreshTTL adalah umur refresh token sejak terakhir dirotasi
+	RefreshTTL time.Duration
+	// RefreshGrace adalah jeda setelah rotasi di mana refresh token lama masih
+	// menghasilkan pengganti yang sama, untuk client yang mengirim refresh
+	// bersamaan atau kehilangan respons
+	RefreshGrace time.Duration
+	Issuer       string
+}
+
+var JWT JWTConfig
+
+// parsePreviousKeys membaca JWT_PREVIOUS_KEYS dengan format
+// "kid=secret" untuk HMAC atau "kid=@/path/public.pem" untuk RS256/EdDSA
+func parsePreviousKeys(items []string) []JWTPreviousKey {
+	var keys []JWTPreviousKey
+	for _, item := range items {
+		kid, value, ok := strings.Cut(item, "=")
+		kid = strings.TrimSpace(kid)
+		if !ok || kid == "" || value == "" {
+			log.Fatalf("JWT_PREVIOUS_KEYS: format %q tidak valid, gunakan kid=secret atau kid=@file.pem", item)
+		}
+		key := JWTPreviousKey{KID: kid}
+		if strings.HasPrefix(value, "@") {
+			key.PublicKeyFile = strings.TrimPrefix(value, "@")
+		} else {
+			key.Secret = value
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// LoadJWTConfig memuat pengaturan token internal. Harus dipanggil setelah
+// LoadJwtSecret.
+func LoadJWTConfig() {
+	JWT = JWTConfig{
+		Algorithm:      envString("JWT_ALG", "HS256"),
+		KID:            envString("JWT_KID", "default"),
+		Secret:         JwtSecret,
+		PrivateKeyFile: envString("JWT_PRIVATE_KEY_FILE", ""),
+		PreviousKeys:   parsePreviousKeys(envList("JWT_PREVIOUS_KEYS", nil)),
+		TTL:            envDuration("JWT_TTL", 15*time.Minute),
+		RefreshTTL:     envDuration("JWT_REFRESH_TTL", 30*24*time.Hour),
+		RefreshGrace:   envDuration("JWT_REFRESH_GRACE", 30*time.Second),
+		Issuer:         envString("JWT_ISSUER", ""),
+		LegacySecret:   envString("JWT_LEGACY_SECRET", ""),
+	}
+
+	if JWT.LegacySecret != "" {
+		until, err := time.Parse(time.RFC3339, envString("JWT_LEGACY_UNTIL", ""))
+		if err != nil {
+			log.Fatalf("JWT_LEGACY_UNTIL harus diisi waktu RFC3339 jika JWT_LEGACY_SECRET diisi: %v", err)
+		}
+		JWT.LegacyUntil = until
+		log.Printf("Warning: token lama tanpa kid masih diterima sampai %s", until.Format(time.RFC3339))
+	}
+
+	switch JWT.Algorithm {
+	case "HS256":
+	case "RS256", "EdDSA":
+		if JWT.PrivateKeyFile == "" {
+			log.Fatalf("JWT_PRIVATE_KEY_FILE harus diisi untuk JWT_ALG=%s", JWT.Algorithm)
+		}
+	default:
+		log.Fatalf("JWT_ALG %q tidak didukung (HS256, RS256, EdDSA)", JWT.Algorithm)
+	}
+	for _, k := range JWT.PreviousKeys {
+		if k.KID == JWT.KID {
+			log.Fatalf("JWT_PREVIOUS_KEYS: kid %q sama dengan kunci aktif", k.KID)
+		}
+	}
+	log.Printf("Token internal: %s, kid %s, %d kunci lama", JWT.Algorithm, JWT.KID, len(JWT.PreviousKeys))
+}
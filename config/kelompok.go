@@ -0,0 +1,30 @@
+package config
+
+// KelompokConfig mengatur aturan pembentukan kelompok PA
+type KelompokConfig struct {
+	// MaxAnggota adalah jumlah maksimum mahasiswa dalam satu kelompok
+	MaxAnggota int
+	// StatusAktif adalah nilai kolom kelompok.status untuk kelompok yang masih berjalan
+	StatusAktif string
+	// TahunMasukAktif adalah nilai kolom tahun_masuk.Status untuk tahun yang sedang berjalan
+	TahunMasukAktif string
+	// MaxBimbinganDosen adalah jumlah maksimum kelompok yang dibimbing satu
+	// dosen dalam satu angkatan saat penetapan topik. 0 berarti tanpa batas.
+	MaxBimbinganDosen int
+}
+
+var Kelompok KelompokConfig
+
+// LoadKelompokConfig memuat aturan kelompok dari environment variables
+func LoadKelompokConfig() {
+	Kelompok = KelompokConfig{
+		MaxAnggota:      int(envInt64("KELOMPOK_MAX_ANGGOTA", 5)),
+		StatusAktif:     envString("KELOMPOK_STATUS_AKTIF", "Aktif"),
+		TahunMasukAktif: envString("TAHUN_MASUK_STATUS_AKTIF", "Aktif"),
+
+		MaxBimbinganDosen: int(envInt64("KELOMPOK_MAX_BIMBINGAN_DOSEN", 0)),
+	}
+	if Kelompok.MaxAnggota < 1 {
+		Kelompok.MaxAnggota = 1
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"log"
+
+	"github.com/rudychandra/lagi/model"
+)
+
+// Migrate membuat tabel yang dikelola oleh service Go ini.
+// Tabel milik Laravel (tugas, kelompok, pengumuman, dst.) tidak diubah di sini.
+func Migrate() {
+	if err := DB.AutoMigrate(
+		&model.TugasUploadRule{},
+		&model.RefreshToken{},
+		&model.RevokedSession{},
+		&model.RateLimitCounter{},
+		&model.AuditLog{},
+		&model.KelompokKetua{},
+		&model.KelompokMahasiswaLock{},
+		&model.KelompokFormasi{},
+		&model.TopikDosen{},
+		&model.TopikUsulan{},
+		&model.TopikPilihan{},
+		&model.TopikPenetapan{},
+		&model.PengumumanMeta{},
+		&model.PengumumanTarget{},
+		&model.PengumumanRevisi{},
+		&model.PengumumanBaca{},
+		&model.Lampiran{},
+		&model.SearchDokumen{},
+	); err != nil {
+		log.Fatal("Gagal migrasi tabel:", err)
+	}
+	log.Println("Migrasi tabel selesai!")
+}
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// PengumumanConfig mengatur penjadwalan pengumuman
+type PengumumanConfig struct {
+	// SchedulerInterval adalah jarak antar pemeriksaan pengumuman yang
+	// waktunya terbit atau kadaluarsa
+	SchedulerInterval time.Duration
+}
+
+var Pengumuman PengumumanConfig
+
+// LoadPengumumanConfig memuat pengaturan pengumuman dari environment variables
+func LoadPengumumanConfig() {
+	Pengumuman = PengumumanConfig{
+		SchedulerInterval: envDuration("PENGUMUMAN_SCHEDULER_INTERVAL", time.Minute),
+	}
+	if Pengumuman.SchedulerInterval < 10*time.Second {
+		Pengumuman.SchedulerInterval = 10 * time.Second
+	}
+}
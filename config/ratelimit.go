@@ -0,0 +1,59 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// Quota adalah batas jumlah request dalam satu sliding window
+type Quota struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitConfig mengatur pembatasan login dan kuota per user
+type RateLimitConfig struct {
+	// Store: "memory" (default, per instance) atau "database" (dibagi antar instance)
+	Store string
+
+	// LoginIP membatasi semua percobaan login dari satu IP
+	LoginIP Quota
+	// LoginUser membatasi login gagal untuk satu username sebelum dikunci
+	LoginUser Quota
+	// LockoutBase adalah lama kunci pertama; kunci berikutnya berlipat dua
+	LockoutBase time.Duration
+	// LockoutMax adalah batas atas lama kunci
+	LockoutMax time.Duration
+
+	// Upload dan Notification adalah kuota per user
+	Upload       Quota
+	Notification Quota
+}
+
+var RateLimit RateLimitConfig
+
+func envQuota(prefix string, limit int64, window time.Duration) Quota {
+	return Quota{
+		Limit:  int(envInt64(prefix+"_LIMIT", limit)),
+		Window: envDuration(prefix+"_WINDOW", window),
+	}
+}
+
+// LoadRateLimitConfig memuat pengaturan rate limit dari environment variables.
+// Limit 0 mematikan pembatasan yang bersangkutan.
+func LoadRateLimitConfig() {
+	RateLimit = RateLimitConfig{
+		Store:        strings.ToLower(envString("RATE_LIMIT_STORE", "memory")),
+		LoginIP:      envQuota("LOGIN_IP", 30, 15*time.Minute),
+		LoginUser:    envQuota("LOGIN_USER", 5, 15*time.Minute),
+		LockoutBase:  envDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LockoutMax:   envDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		Upload:       envQuota("UPLOAD_QUOTA", 60, time.Hour),
+		Notification: envQuota("NOTIFICATION_QUOTA", 30, time.Hour),
+	}
+	if RateLimit.LockoutMax < RateLimit.LockoutBase {
+		RateLimit.LockoutMax = RateLimit.LockoutBase
+	}
+	log.Printf("Rate limit menggunakan store %s", RateLimit.Store)
+}
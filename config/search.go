@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// SearchConfig mengatur indeks pencarian teks penuh
+type SearchConfig struct {
+	// IndexInterval adalah jarak antar sinkronisasi indeks dengan tabel
+	// pengumuman, tugas dan request_bimbingan
+	IndexInterval time.Duration
+}
+
+var Search SearchConfig
+
+// LoadSearchConfig memuat pengaturan pencarian dari environment variables
+func LoadSearchConfig() {
+	Search = SearchConfig{
+		IndexInterval: envDuration("SEARCH_INDEX_INTERVAL", time.Minute),
+	}
+	if Search.IndexInterval < 10*time.Second {
+		Search.IndexInterval = 10 * time.Second
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+// SignedURLConfig mengatur URL file bertanda tangan (tanpa header Authorization)
+type SignedURLConfig struct {
+	Secret     string
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	// SessionWindow adalah lama URL sekali pakai masih menerima request Range
+	// dari IP yang sama setelah dipakai pertama kali
+	SessionWindow time.Duration
+}
+
+var SignedURL SignedURLConfig
+
+// LoadSignedURLConfig memuat pengaturan signed URL. Jika FILE_URL_SECRET kosong,
+// JWT_SECRET dipakai sebagai kunci HMAC.
+func LoadSignedURLConfig() {
+	SignedURL = SignedURLConfig{
+		Secret:        envString("FILE_URL_SECRET", JwtSecret),
+		DefaultTTL:    envDuration("FILE_URL_TTL", 5*time.Minute),
+		MaxTTL:        envDuration("FILE_URL_MAX_TTL", time.Hour),
+		SessionWindow: envDuration("FILE_URL_SESSION_WINDOW", 10*time.Minute),
+	}
+	if SignedURL.Secret == "" {
+		log.Fatal("FILE_URL_SECRET atau JWT_SECRET harus diisi untuk signed URL")
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+// StorageConfig menentukan backend penyimpanan file upload
+type StorageConfig struct {
+	// Driver: "local" atau "s3"
+	Driver string
+	// Root adalah direktori upload untuk driver local
+	Root string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+	S3Prefix    string
+
+	// LaravelCacheDir menyimpan salinan file dari storage Laravel
+	LaravelCacheDir string
+	// LaravelCacheMaxSize adalah batas ukuran cache Laravel; 0 berarti cache dimatikan
+	LaravelCacheMaxSize int64
+	// LaravelCacheMaxObject adalah ukuran file terbesar yang disimpan di cache;
+	// file yang lebih besar dialirkan langsung dari Laravel
+	LaravelCacheMaxObject int64
+	// LaravelCacheFresh adalah lama file cache dipakai tanpa revalidasi ke Laravel
+	LaravelCacheFresh time.Duration
+}
+
+var Storage StorageConfig
+
+// LoadStorageConfig memuat pengaturan storage dari environment variables
+func LoadStorageConfig() {
+	Storage = StorageConfig{
+		Driver:      envString("STORAGE_DRIVER", "local"),
+		Root:        envString("STORAGE_ROOT", "uploads"),
+		S3Endpoint:  envString("S3_ENDPOINT", ""),
+		S3Region:    envString("S3_REGION", "us-east-1"),
+		S3Bucket:    envString("S3_BUCKET", ""),
+		S3AccessKey: envString("S3_ACCESS_KEY", ""),
+		S3SecretKey: envString("S3_SECRET_KEY", ""),
+		S3PathStyle: envBool("S3_PATH_STYLE", true),
+		S3Prefix:    envString("S3_PREFIX", ""),
+
+		LaravelCacheDir:       envString("LARAVEL_CACHE_DIR", "cache/files"),
+		LaravelCacheMaxSize:   envInt64("LARAVEL_CACHE_MAX_MB", 1024) << 20,
+		LaravelCacheMaxObject: envInt64("LARAVEL_CACHE_MAX_OBJECT_MB", 64) << 20,
+		LaravelCacheFresh:     envDuration("LARAVEL_CACHE_FRESH", 30*time.Second),
+	}
+	log.Printf("Konfigurasi storage dimuat (driver: %s)", Storage.Driver)
+}
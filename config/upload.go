@@ -0,0 +1,78 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+const megabyte = 1024 * 1024
+
+// UploadConfig menyimpan batasan dan pengaturan pemindaian file upload
+type UploadConfig struct {
+	MaxTugasSize          int64
+	MaxPengumumanSize     int64
+	TugasAllowedMIME      []string
+	PengumumanAllowedMIME []string
+	QuarantineDir         string
+
+	// Lampiran tambahan pengumuman dan tugas
+	MaxLampiranSize     int64
+	MaxLampiran         int
+	LampiranAllowedMIME []string
+
+	// Scanner: "none" atau "clamav"
+	Scanner       string
+	ClamAVAddress string
+	ClamAVTimeout time.Duration
+	// ScanFailOpen menerima file jika scanner tidak bisa dihubungi
+	ScanFailOpen bool
+}
+
+var Upload UploadConfig
+
+// LoadUploadConfig memuat pengaturan upload dari environment variables
+func LoadUploadConfig() {
+	Upload = UploadConfig{
+		MaxTugasSize:      envInt64("UPLOAD_MAX_TUGAS_MB", 100) * megabyte,
+		MaxPengumumanSize: envInt64("UPLOAD_MAX_PENGUMUMAN_MB", 20) * megabyte,
+		TugasAllowedMIME: envList("UPLOAD_TUGAS_ALLOWED_MIME", []string{
+			"application/pdf",
+			"application/msword",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"application/zip",
+			"image/jpeg",
+			"image/png",
+		}),
+		PengumumanAllowedMIME: envList("UPLOAD_PENGUMUMAN_ALLOWED_MIME", []string{
+			"application/pdf",
+			"application/msword",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"application/vnd.ms-excel",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"application/vnd.ms-powerpoint",
+			"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+			"image/jpeg",
+			"image/png",
+		}),
+		QuarantineDir:   envString("UPLOAD_QUARANTINE_DIR", "quarantine"),
+		MaxLampiranSize: envInt64("UPLOAD_MAX_LAMPIRAN_MB", 20) * megabyte,
+		MaxLampiran:     int(envInt64("UPLOAD_MAX_LAMPIRAN", 10)),
+		LampiranAllowedMIME: envList("UPLOAD_LAMPIRAN_ALLOWED_MIME", []string{
+			"application/pdf",
+			"application/msword",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"application/vnd.ms-excel",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"application/vnd.ms-powerpoint",
+			"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+			"application/zip",
+			"image/jpeg",
+			"image/png",
+		}),
+		Scanner:       envString("UPLOAD_SCANNER", "none"),
+		ClamAVAddress: envString("CLAMAV_ADDRESS", "tcp://127.0.0.1:3310"),
+		ClamAVTimeout: envDuration("CLAMAV_TIMEOUT", 30*time.Second),
+		ScanFailOpen:  envBool("UPLOAD_SCAN_FAIL_OPEN", false),
+	}
+	log.Printf("Konfigurasi upload dimuat (scanner: %s)", Upload.Scanner)
+}
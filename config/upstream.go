@@ -0,0 +1,219 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// TLSConfig mengatur koneksi TLS ke sebuah upstream
+type TLSConfig struct {
+	// CAFile adalah file PEM berisi CA tambahan untuk memverifikasi server
+	CAFile string `json:"ca_file"`
+	// ServerName menimpa nama host yang dicocokkan dengan sertifikat
+	ServerName string `json:"server_name"`
+	// InsecureSkipVerify mematikan verifikasi sertifikat (hanya untuk pengujian lokal)
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+// UpstreamConfig adalah pengaturan satu layanan eksternal
+type UpstreamConfig struct {
+	BaseURL string
+	Timeout time.Duration
+	// Retries adalah jumlah percobaan ulang setelah percobaan pertama gagal
+	Retries      int
+	RetryBackoff time.Duration
+	TLS          TLSConfig
+}
+
+// URL menggabungkan BaseURL dengan path endpoint
+func (u UpstreamConfig) URL(endpoint string) string {
+	return strings.TrimRight(u.BaseURL, "/") + "/" + strings.TrimLeft(endpoint, "/")
+}
+
+// UpstreamsConfig berisi semua layanan eksternal yang dipanggil service ini
+type UpstreamsConfig struct {
+	// CISAuth adalah API JWT CIS (do-auth, verify-token)
+	CISAuth UpstreamConfig
+	// CISStudent adalah API data mahasiswa CIS
+	CISStudent UpstreamConfig
+	// Laravel adalah aplikasi web Laravel yang menyimpan file tugas
+	Laravel UpstreamConfig
+	// LaravelStoragePath adalah path storage publik Laravel, relatif terhadap BaseURL
+	LaravelStoragePath string
+	// LaravelTugasDir adalah folder file tugas di storage publik Laravel
+	LaravelTugasDir string
+}
+
+// LaravelStorageURL mengembalikan URL lengkap storage publik Laravel
+func (u UpstreamsConfig) LaravelStorageURL() string {
+	return u.Laravel.URL(u.LaravelStoragePath)
+}
+
+var Upstreams UpstreamsConfig
+
+func defaultUpstreams() UpstreamsConfig {
+	def := func(baseURL string) UpstreamConfig {
+		return UpstreamConfig{
+			BaseURL:      baseURL,
+			Timeout:      15 * time.Second,
+			Retries:      2,
+			RetryBackoff: 300 * time.Millisecond,
+		}
+	}
+	return UpstreamsConfig{
+		CISAuth:            def("https://cis-dev.del.ac.id/api/jwt-api"),
+		CISStudent:         def("https://cis-dev.del.ac.id/api/library-api"),
+		Laravel:            def("https://vokasitera.d4trpl-itdel.id"),
+		LaravelStoragePath: "storage",
+		LaravelTugasDir:    "tugas_files",
+	}
+}
+
+// upstreamFile adalah format file konfigurasi JSON; field yang kosong
+// tidak mengubah nilai default
+type upstreamFile struct {
+	CISAuth            *upstreamFileEntry `json:"cis_auth"`
+	CISStudent         *upstreamFileEntry `json:"cis_student"`
+	Laravel            *upstreamFileEntry `json:"laravel"`
+	LaravelStoragePath string             `json:"laravel_storage_path"`
+	LaravelTugasDir    string             `json:"laravel_tugas_dir"`
+}
+
+type upstreamFileEntry struct {
+	BaseURL      string     `json:"base_url"`
+	Timeout      string     `json:"timeout"`
+	Retries      *int       `json:"retries"`
+	RetryBackoff string     `json:"retry_backoff"`
+	TLS          *TLSConfig `json:"tls"`
+}
+
+func (f *upstreamFileEntry) apply(name string, u *UpstreamConfig) error {
+	if f == nil {
+		return nil
+	}
+	if f.BaseURL != "" {
+		u.BaseURL = f.BaseURL
+	}
+	if f.Timeout != "" {
+		d, err := time.ParseDuration(f.Timeout)
+		if err != nil {
+			return fmt.Errorf("%s.timeout: %w", name, err)
+		}
+		u.Timeout = d
+	}
+	if f.Retries != nil {
+		u.Retries = *f.Retries
+	}
+	if f.RetryBackoff != "" {
+		d, err := time.ParseDuration(f.RetryBackoff)
+		if err != nil {
+			return fmt.Errorf("%s.retry_backoff: %w", name, err)
+		}
+		u.RetryBackoff = d
+	}
+	if f.TLS != nil {
+		u.TLS = *f.TLS
+	}
+	return nil
+}
+
+// applyUpstreamEnv menimpa pengaturan upstream dengan environment variable berawalan prefix,
+// contoh CIS_AUTH_URL, CIS_AUTH_TIMEOUT, CIS_AUTH_RETRIES, CIS_AUTH_TLS_CA_FILE
+func applyUpstreamEnv(prefix string, u *UpstreamConfig) {
+	u.BaseURL = envString(prefix+"_URL", u.BaseURL)
+	u.Timeout = envDuration(prefix+"_TIMEOUT", u.Timeout)
+	u.Retries = int(envInt64(prefix+"_RETRIES", int64(u.Retries)))
+	u.RetryBackoff = envDuration(prefix+"_RETRY_BACKOFF", u.RetryBackoff)
+	u.TLS.CAFile = envString(prefix+"_TLS_CA_FILE", u.TLS.CAFile)
+	u.TLS.ServerName = envString(prefix+"_TLS_SERVER_NAME", u.TLS.ServerName)
+	u.TLS.InsecureSkipVerify = envBool(prefix+"_TLS_INSECURE", u.TLS.InsecureSkipVerify)
+}
+
+func (u UpstreamConfig) validate(name string) error {
+	parsed, err := url.Parse(u.BaseURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("%s: base URL %q tidak valid", name, u.BaseURL)
+	}
+	if u.Timeout <= 0 {
+		return fmt.Errorf("%s: timeout harus lebih dari 0", name)
+	}
+	if u.Retries < 0 {
+		return fmt.Errorf("%s: retries tidak boleh negatif", name)
+	}
+	if u.RetryBackoff < 0 {
+		return fmt.Errorf("%s: retry backoff tidak boleh negatif", name)
+	}
+	if u.TLS.CAFile != "" {
+		if _, err := os.Stat(u.TLS.CAFile); err != nil {
+			return fmt.Errorf("%s: CA file: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Validate memeriksa semua upstream sebelum server dijalankan
+func (u UpstreamsConfig) Validate() error {
+	errs := []error{
+		u.CISAuth.validate("cis_auth"),
+		u.CISStudent.validate("cis_student"),
+		u.Laravel.validate("laravel"),
+	}
+	if strings.Contains(u.LaravelStoragePath, "..") {
+		errs = append(errs, fmt.Errorf("laravel_storage_path %q tidak valid", u.LaravelStoragePath))
+	}
+	if u.LaravelTugasDir == "" || strings.Contains(u.LaravelTugasDir, "..") {
+		errs = append(errs, fmt.Errorf("laravel_tugas_dir %q tidak valid", u.LaravelTugasDir))
+	}
+	return errors.Join(errs...)
+}
+
+// LoadUpstreamConfig memuat pengaturan upstream: nilai default, lalu file JSON
+// dari UPSTREAM_CONFIG_FILE (jika ada), lalu environment variables.
+// Aplikasi berhenti jika hasilnya tidak valid.
+func LoadUpstreamConfig() {
+	cfg := defaultUpstreams()
+
+	if file := envString("UPSTREAM_CONFIG_FILE", ""); file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatalf("Gagal membaca %s: %v", file, err)
+		}
+		var f upstreamFile
+		if err := json.Unmarshal(raw, &f); err != nil {
+			log.Fatalf("Format %s tidak valid: %v", file, err)
+		}
+		err = errors.Join(
+			f.CISAuth.apply("cis_auth", &cfg.CISAuth),
+			f.CISStudent.apply("cis_student", &cfg.CISStudent),
+			f.Laravel.apply("laravel", &cfg.Laravel),
+		)
+		if err != nil {
+			log.Fatalf("Konfigurasi %s tidak valid: %v", file, err)
+		}
+		if f.LaravelStoragePath != "" {
+			cfg.LaravelStoragePath = f.LaravelStoragePath
+		}
+		if f.LaravelTugasDir != "" {
+			cfg.LaravelTugasDir = f.LaravelTugasDir
+		}
+	}
+
+	applyUpstreamEnv("CIS_AUTH", &cfg.CISAuth)
+	applyUpstreamEnv("CIS_STUDENT", &cfg.CISStudent)
+	applyUpstreamEnv("LARAVEL", &cfg.Laravel)
+	cfg.LaravelStoragePath = envString("LARAVEL_STORAGE_PATH", cfg.LaravelStoragePath)
+	cfg.LaravelTugasDir = envString("LARAVEL_TUGAS_DIR", cfg.LaravelTugasDir)
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Konfigurasi upstream tidak valid:\n%v", err)
+	}
+	Upstreams = cfg
+	log.Printf("Upstream CIS auth: %s, CIS mahasiswa: %s, Laravel: %s",
+		cfg.CISAuth.BaseURL, cfg.CISStudent.BaseURL, cfg.Laravel.BaseURL)
+}
@@ -4,10 +4,88 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"gorm.io/gorm"
 )
 
+// kelompokInScope memeriksa apakah kelompok termasuk prodi dan jenis PA
+// yang menjadi cakupan permission user
+func kelompokInScope(db *gorm.DB, subject *policy.Subject, p policy.Permission, kelompokID uint) (bool, error) {
+	if subject.CanGlobal(p) {
+		return true, nil
+	}
+	var kelompok model.Kelompok
+	if err := db.Where("id = ?", kelompokID).Limit(1).Find(&kelompok).Error; err != nil || kelompok.ID == 0 {
+		return false, err
+	}
+	return subject.CanForCohort(db, p, kelompok.ProdiID, kelompok.KPAID)
+}
+
+// kelompokIDsInScope mengembalikan id kelompok yang termasuk cakupan permission
+func kelompokIDsInScope(db *gorm.DB, subject *policy.Subject, p policy.Permission) ([]uint, error) {
+	var kelompok []model.Kelompok
+	if err := db.Select("id", "prodi_id", "KPA_id").Find(&kelompok).Error; err != nil {
+		return nil, err
+	}
+	inScope := kelompokScopeFilter(db, subject, p)
+	ids := []uint{}
+	for _, k := range kelompok {
+		allowed, err := inScope(k)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			ids = append(ids, k.ID)
+		}
+	}
+	return ids, nil
+}
+
+// kelompokDibimbing mengembalikan kelompok yang dibimbing user menurut tabel
+// pembimbing. Permission bimbingan:approve hanya membuka bimbingan kelompok
+// tersebut, bukan seluruh prodi dan jenis PA-nya.
+func kelompokDibimbing(db *gorm.DB, subject *policy.Subject) ([]uint, error) {
+	ids := []uint{}
+	if !subject.Can(policy.BimbinganApprove) {
+		return ids, nil
+	}
+	err := db.Model(&model.Pembimbing{}).Where("user_id = ?", subject.UserID).Pluck("kelompok_id", &ids).Error
+	return ids, err
+}
+
+// isPembimbing memeriksa apakah user membimbing kelompok tersebut
+func isPembimbing(db *gorm.DB, subject *policy.Subject, kelompokID uint) (bool, error) {
+	if !subject.Can(policy.BimbinganApprove) {
+		return false, nil
+	}
+	var count int64
+	err := db.Model(&model.Pembimbing{}).
+		Where("user_id = ? AND kelompok_id = ?", subject.UserID, kelompokID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// bimbinganKelompokIDs mengembalikan kelompok yang bimbingannya boleh dilihat
+// selain bimbingan milik sendiri: cakupan bimbingan:view_all dan kelompok
+// yang dibimbing
+func bimbinganKelompokIDs(db *gorm.DB, subject *policy.Subject) ([]uint, error) {
+	ids, err := kelompokDibimbing(db, subject)
+	if err != nil {
+		return nil, err
+	}
+	if subject.Can(policy.BimbinganViewAll) {
+		scoped, err := kelompokIDsInScope(db, subject, policy.BimbinganViewAll)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, scoped...)
+	}
+	return ids, nil
+}
+
 func GetUpdateBimbingan(c *gin.Context) {
 	db := config.DB
 
@@ -17,16 +95,27 @@ func GetUpdateBimbingan(c *gin.Context) {
 		return
 	}
 
-	role, _ := c.Get("user_role")
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
 	var bimbinganList []model.Bimbingan
 
-	if role == "Dosen" {
+	if subject.CanGlobal(policy.BimbinganViewAll) {
 		if err := db.Find(&bimbinganList).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 	} else {
-		if err := db.Where("user_id = ?", userID.(uint)).Find(&bimbinganList).Error; err != nil {
+		// Koordinator melihat bimbingan kelompok di prodi dan jenis PA-nya,
+		// pembimbing melihat bimbingan kelompok yang dibimbingnya
+		ids, err := bimbinganKelompokIDs(db, subject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("kelompok_id IN ? OR user_id = ?", ids, userID.(uint)).Find(&bimbinganList).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -44,7 +133,11 @@ func GetUpdateBimbinganByID(c *gin.Context) {
 		return
 	}
 
-	role, _ := c.Get("user_role")
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
 
 	var bimbingan model.Bimbingan
 	if err := db.Where("bimbingan_id = ?", id).First(&bimbingan).Error; err != nil {
@@ -52,9 +145,19 @@ func GetUpdateBimbinganByID(c *gin.Context) {
 		return
 	}
 
-	if role != "Dosen" && bimbingan.UserID != userID.(uint) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki akses ke bimbingan ini"})
-		return
+	if bimbingan.UserID != userID.(uint) {
+		allowed, err := kelompokInScope(db, subject, policy.BimbinganViewAll, bimbingan.KelompokID)
+		if err == nil && !allowed {
+			allowed, err = isPembimbing(db, subject, bimbingan.KelompokID)
+		}
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		if !allowed {
+			policy.Deny(c, policy.BimbinganViewAll)
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, bimbingan)
@@ -64,18 +167,28 @@ func UpdateRequestBimbingan(c *gin.Context) {
 	db := config.DB
 	id := c.Param("id")
 
-	role, _ := c.Get("user_role")
-	if role != "Dosen" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya dosen yang bisa mengubah status"})
-		return
-	}
-
 	var bimbingan model.Bimbingan
 	if err := db.Where("bimbingan_id = ?", id).First(&bimbingan).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Bimbingan tidak ditemukan"})
 		return
 	}
 
+	// Pembimbing hanya boleh mengubah bimbingan di prodi dan jenis PA-nya
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	allowed, err := kelompokInScope(db, subject, policy.BimbinganApprove, bimbingan.KelompokID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if !allowed {
+		policy.Deny(c, policy.BimbinganApprove)
+		return
+	}
+
 	var request struct {
 		Status string `json:"status"`
 	}
@@ -85,10 +198,12 @@ func UpdateRequestBimbingan(c *gin.Context) {
 		return
 	}
 
+	before := bimbingan
 	if err := db.Model(&bimbingan).Update("status", request.Status).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	audit.Track(c, audit.Change{Action: "bimbingan.update_status", EntityType: "bimbingan", EntityID: bimbingan.ID, Before: before, After: bimbingan})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Status diperbarui", "data": bimbingan})
 }
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+)
+
+const (
+	auditDefaultPerPage = 50
+	auditMaxPerPage     = 200
+)
+
+// parseAuditTime menerima RFC3339 atau tanggal (YYYY-MM-DD)
+func parseAuditTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
+// GetAuditLogs menampilkan audit log dengan filter dan pagination.
+// Filter: actor_id, impersonator_id, impersonated (true), action, entity_type,
+// entity_id, method, status, from, to. Pagination: page, per_page.
+func GetAuditLogs(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	query := db.Model(&model.AuditLog{})
+	for param, column := range map[string]string{
+		"actor_id":        "actor_id",
+		"impersonator_id": "impersonator_id",
+		"action":          "action",
+		"entity_type":     "entity_type",
+		"entity_id":       "entity_id",
+		"method":          "method",
+		"status":          "status_code",
+	} {
+		if v := c.Query(param); v != "" {
+			query = query.Where(column+" = ?", v)
+		}
+	}
+	if c.Query("impersonated") == "true" {
+		query = query.Where("impersonator_id IS NOT NULL")
+	}
+	if v := c.Query("from"); v != "" {
+		from, err := parseAuditTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format from tidak valid (RFC3339 atau YYYY-MM-DD)"})
+			return
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseAuditTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format to tidak valid (RFC3339 atau YYYY-MM-DD)"})
+			return
+		}
+		// Tanggal saja berarti sampai akhir hari tersebut
+		if len(v) == len("2006-01-02") {
+			to = to.AddDate(0, 0, 1)
+		}
+		query = query.Where("created_at < ?", to)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(auditDefaultPerPage)))
+	if perPage < 1 {
+		perPage = auditDefaultPerPage
+	}
+	if perPage > auditMaxPerPage {
+		perPage = auditMaxPerPage
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung audit log"})
+		return
+	}
+
+	logs := []model.AuditLog{}
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * perPage).Limit(perPage).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   logs,
+		"pagination": gin.H{
+			"page":        page,
+			"per_page":    perPage,
+			"total":       total,
+			"total_pages": (total + int64(perPage) - 1) / int64(perPage),
+		},
+		"retention": config.Audit.Retention.String(),
+	})
+}
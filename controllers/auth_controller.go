@@ -9,9 +9,10 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
-	"github.com/rudychandra/lagi/utils"
+	"github.com/rudychandra/lagi/ratelimit"
 )
 
 // Struktur untuk request login
@@ -19,6 +20,7 @@ type LoginRequest struct {
 	Username    string `form:"username" binding:"required"`
 	Password    string `form:"password" binding:"required"`
 	DeviceToken string `form:"device_token"` // FCM device token (optional)
+	DeviceID    string `form:"device_id"`    // ID perangkat, refresh token hanya berlaku dari perangkat ini (optional)
 }
 
 // Struktur response dari API eksternal (CIS)
@@ -44,8 +46,20 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Batasi percobaan login per IP dan kunci username setelah terlalu banyak gagal
+	limiter := ratelimit.Default
+	if limiter != nil {
+		res, err := limiter.LoginAllowed(c.Request.Context(), c.ClientIP(), loginReq.Username)
+		if err != nil {
+			fmt.Printf("Rate limit login gagal diperiksa: %v\n", err)
+		} else if !res.Allowed {
+			ratelimit.Reject(c, res, "Terlalu banyak percobaan login, coba lagi nanti")
+			return
+		}
+	}
+
 	// URL API eksternal CIS
-	apiURL := "https://cis-dev.del.ac.id/api/jwt-api/do-auth"
+	apiURL := upstreams.CISAuth.URL("do-auth")
 
 	// Buat body form-data
 	var requestBody bytes.Buffer
@@ -55,7 +69,7 @@ func Login(c *gin.Context) {
 	writer.Close()
 
 	// Buat HTTP request
-	req, err := http.NewRequest("POST", apiURL, &requestBody)
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", apiURL, &requestBody)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat request ke server autentikasi"})
 		return
@@ -63,8 +77,7 @@ func Login(c *gin.Context) {
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	// Kirim request ke API eksternal
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := cisAuthClient.Do(req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal terhubung ke server autentikasi"})
 		return
@@ -90,12 +103,39 @@ func Login(c *gin.Context) {
 
 	// Jika login gagal
 	if !loginRes.Result {
+		if limiter != nil {
+			lockedFor, err := limiter.LoginFailed(c.Request.Context(), loginReq.Username)
+			if err != nil {
+				fmt.Printf("Gagal mencatat login gagal: %v\n", err)
+			} else if lockedFor > 0 {
+				ratelimit.Reject(c, ratelimit.Result{RetryAfter: lockedFor}, "Terlalu banyak login gagal, akun dikunci sementara")
+				return
+			}
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": loginRes.Error})
 		return
 	}
 
-	// Generate token internal
-	internalToken, err := utils.GenerateInternalToken(uint(loginRes.User.UserID), loginRes.User.Role)
+	if limiter != nil {
+		if err := limiter.LoginSucceeded(c.Request.Context(), loginReq.Username); err != nil {
+			fmt.Printf("Gagal menghapus riwayat login gagal: %v\n", err)
+		}
+	}
+
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	// Simpan profil user secara lokal; login tetap berhasil walaupun gagal
+	// karena autentikasi sudah dilakukan oleh CIS
+	if _, err := provisionUser(db, uint(loginRes.User.UserID), loginRes.User.Username, loginRes.User.Email, loginRes.User.Role); err != nil {
+		fmt.Printf("Gagal menyimpan profil user %d: %v\n", loginRes.User.UserID, err)
+	}
+
+	// Generate access token dan refresh token internal
+	tokens, err := issueSession(c, db, uint(loginRes.User.UserID), loginRes.User.Role, loginReq.DeviceID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat token internal"})
 		return
@@ -109,18 +149,24 @@ func Login(c *gin.Context) {
 		}()
 	}
 
-	// Response sukses
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "Login berhasil",
-		"external_token": loginRes.Token,
-		"internal_token": internalToken,
-		"user": gin.H{
-			"user_id":  loginRes.User.UserID,
-			"username": loginRes.User.Username,
-			"email":    loginRes.User.Email,
-			"role":     loginRes.User.Role,
-		},
+	audit.Track(c, audit.Change{
+		Action:     "auth.login",
+		EntityType: "user",
+		EntityID:   loginRes.User.UserID,
+		ActorID:    uint(loginRes.User.UserID),
+		ActorRole:  loginRes.User.Role,
 	})
+
+	// Response sukses
+	tokens["message"] = "Login berhasil"
+	tokens["external_token"] = loginRes.Token
+	tokens["user"] = gin.H{
+		"user_id":  loginRes.User.UserID,
+		"username": loginRes.User.Username,
+		"email":    loginRes.User.Email,
+		"role":     loginRes.User.Role,
+	}
+	c.JSON(http.StatusOK, tokens)
 }
 
 // saveDeviceTokenForUser automatically saves or updates device token for user
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errRefreshReused = errors.New("refresh token reused")
+
+// RefreshRequest adalah body untuk /auth/refresh dan /auth/logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token"`
+	DeviceID     string `json:"device_id" form:"device_id"`
+}
+
+// issueTokens membuat access token dan refresh token baru untuk sebuah sesi
+// (family) dan menyimpan hash refresh token-nya. refreshToken kosong berarti
+// token dibuat acak; saat rotasi diisi pengganti turunan token lama.
+func issueTokens(c *gin.Context, tx *gorm.DB, userID uint, role, familyID, deviceID, refreshToken string) (gin.H, error) {
+	var hash string
+	if refreshToken == "" {
+		var err error
+		refreshToken, hash, err = utils.NewRefreshToken()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		hash = utils.HashRefreshToken(refreshToken)
+	}
+
+	stored := model.RefreshToken{
+		UserID:    userID,
+		Role:      role,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		DeviceID:  deviceID,
+		UserAgent: truncate(c.Request.UserAgent(), 255),
+		IPAddress: c.ClientIP(),
+		ExpiresAt: time.Now().Add(config.JWT.RefreshTTL),
+	}
+	if err := tx.Create(&stored).Error; err != nil {
+		return nil, err
+	}
+	return tokenResponse(stored, refreshToken)
+}
+
+// tokenResponse membuat access token baru untuk refresh token yang tersimpan
+func tokenResponse(stored model.RefreshToken, refreshToken string) (gin.H, error) {
+	accessToken, accessExp, err := utils.GenerateInternalToken(stored.UserID, stored.Role, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"internal_token":     accessToken,
+		"expires_at":         accessExp.Format(time.RFC3339),
+		"expires_in":         int(time.Until(accessExp).Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": stored.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// issueSession memulai sesi login baru untuk user
+func issueSession(c *gin.Context, db *gorm.DB, userID uint, role, deviceID string) (gin.H, error) {
+	sessionID, err := utils.NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	return issueTokens(c, db, userID, role, sessionID, deviceID, "")
+}
+
+// revokeSession mencabut semua refresh token dalam sebuah sesi dan menolak
+// access token serta signed URL sesi tersebut yang belum kadaluarsa
+func revokeSession(db *gorm.DB, sessionID string, userID uint, reason string) error {
+	if sessionID == "" {
+		return nil
+	}
+	now := time.Now()
+	// Catatan pencabutan disimpan selama access token atau signed URL sesi
+	// ini masih bisa berlaku
+	keep := utils.AccessTokenTTL()
+	if config.SignedURL.MaxTTL > keep {
+		keep = config.SignedURL.MaxTTL
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", sessionID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		// Baris lama sudah tidak berguna karena access token-nya sudah kadaluarsa
+		if err := tx.Where("expires_at < ?", now).Delete(&model.RevokedSession{}).Error; err != nil {
+			return err
+		}
+		revoked := model.RevokedSession{
+			SessionID: sessionID,
+			UserID:    userID,
+			Reason:    reason,
+			ExpiresAt: now.Add(keep),
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error
+	})
+}
+
+// RefreshToken menukar refresh token dengan access token dan refresh token
+// baru. Refresh token yang sudah pernah dipakai berarti kemungkinan dicuri,
+// sehingga seluruh sesinya dicabut.
+func RefreshToken(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req RefreshRequest
+	if err := c.ShouldBind(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token diperlukan"})
+		return
+	}
+
+	var stored model.RefreshToken
+	if err := db.Where("token_hash = ?", utils.HashRefreshToken(req.RefreshToken)).First(&stored).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token tidak valid"})
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case stored.RevokedAt != nil:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesi sudah berakhir, silakan login kembali"})
+		return
+	case stored.UsedAt != nil:
+		respondRefreshRetry(c, db, stored, req)
+		return
+	case now.After(stored.ExpiresAt):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token sudah kadaluarsa, silakan login kembali"})
+		return
+	case stored.DeviceID != "" && stored.DeviceID != req.DeviceID:
+		rejectReusedRefresh(c, db, stored, "device_mismatch")
+		return
+	}
+
+	// Role diambil ulang dari users agar perubahan role berlaku pada refresh
+	// berikutnya, bukan baru setelah login ulang
+	var user model.User
+	if err := db.Select("id", "role").Where("id = ?", stored.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// User sudah dihapus, sesinya ikut diakhiri
+			if err := revokeSession(db, stored.FamilyID, stored.UserID, "user_deleted"); err != nil {
+				fmt.Printf("Gagal mencabut sesi %s: %v\n", stored.FamilyID, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesi sudah berakhir, silakan login kembali"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui token"})
+		return
+	}
+	role := user.Role
+	if role == "" {
+		role = stored.Role
+	}
+
+	var tokens gin.H
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Tandai token lama terpakai; jika request lain sudah memakainya lebih dulu, ini reuse
+		res := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", stored.ID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errRefreshReused
+		}
+		successor, _ := utils.SuccessorRefreshToken(req.RefreshToken)
+		var err error
+		tokens, err = issueTokens(c, tx, stored.UserID, role, stored.FamilyID, stored.DeviceID, successor)
+		return err
+	})
+	if errors.Is(err, errRefreshReused) {
+		// Request lain baru saja merotasi token ini; muat ulang untuk used_at-nya
+		if err := db.First(&stored, stored.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui token"})
+			return
+		}
+		respondRefreshRetry(c, db, stored, req)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui token"})
+		return
+	}
+
+	tokens["message"] = "Token berhasil diperbarui"
+	c.JSON(http.StatusOK, tokens)
+}
+
+// respondRefreshRetry menangani refresh token yang sudah dirotasi. Dalam
+// JWT_REFRESH_GRACE sejak rotasi, dari perangkat yang sama dan selama
+// penggantinya belum dipakai, client mendapat pengganti yang sama: dua
+// request refresh bersamaan atau respons yang hilang bukan tanda pencurian.
+// Di luar itu dianggap reuse dan seluruh sesinya dicabut.
+func respondRefreshRetry(c *gin.Context, db *gorm.DB, stored model.RefreshToken, req RefreshRequest) {
+	inGrace := stored.UsedAt != nil && time.Since(*stored.UsedAt) <= config.JWT.RefreshGrace &&
+		(stored.DeviceID == "" || stored.DeviceID == req.DeviceID)
+	if !inGrace {
+		rejectReusedRefresh(c, db, stored, "refresh_reuse")
+		return
+	}
+
+	successor, hash := utils.SuccessorRefreshToken(req.RefreshToken)
+	var next model.RefreshToken
+	err := db.Where("token_hash = ? AND family_id = ? AND used_at IS NULL AND revoked_at IS NULL", hash, stored.FamilyID).
+		First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rejectReusedRefresh(c, db, stored, "refresh_reuse")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui token"})
+		return
+	}
+
+	tokens, err := tokenResponse(next, successor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui token"})
+		return
+	}
+	tokens["message"] = "Token berhasil diperbarui"
+	c.JSON(http.StatusOK, tokens)
+}
+
+// rejectReusedRefresh mencabut seluruh sesi milik refresh token yang dicurigai dicuri
+func rejectReusedRefresh(c *gin.Context, db *gorm.DB, stored model.RefreshToken, reason string) {
+	fmt.Printf("Refresh token %s terdeteksi untuk user %d (sesi %s), sesi dicabut\n", reason, stored.UserID, stored.FamilyID)
+	if err := revokeSession(db, stored.FamilyID, stored.UserID, reason); err != nil {
+		fmt.Printf("Gagal mencabut sesi %s: %v\n", stored.FamilyID, err)
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token tidak valid, silakan login kembali"})
+}
+
+// Logout mengakhiri sesi login saat ini
+func Logout(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	sessionID := c.GetString("session_id")
+
+	// Refresh token di body boleh dikirim untuk token yang dibuat sebelum ada sid
+	var req RefreshRequest
+	_ = c.ShouldBind(&req)
+	if sessionID == "" && req.RefreshToken != "" {
+		var stored model.RefreshToken
+		if err := db.Where("token_hash = ? AND user_id = ?", utils.HashRefreshToken(req.RefreshToken), userID).
+			First(&stored).Error; err == nil {
+			sessionID = stored.FamilyID
+		}
+	}
+
+	if err := revokeSession(db, sessionID, userID.(uint), "logout"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal logout"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "auth.logout", EntityType: "session", EntityID: sessionID})
+	c.JSON(http.StatusOK, gin.H{"message": "Logout berhasil"})
+}
+
+// LogoutAll mengakhiri semua sesi login user di semua perangkat
+func LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var sessions []string
+	if err := db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Distinct().Pluck("family_id", &sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil sesi"})
+		return
+	}
+	if current := c.GetString("session_id"); current != "" {
+		sessions = append(sessions, current)
+	}
+
+	revoked := map[string]bool{}
+	for _, sessionID := range sessions {
+		if revoked[sessionID] {
+			continue
+		}
+		if err := revokeSession(db, sessionID, userID.(uint), "logout_all"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal logout dari semua perangkat"})
+			return
+		}
+		revoked[sessionID] = true
+	}
+
+	audit.Track(c, audit.Change{Action: "auth.logout_all", EntityType: "user", EntityID: userID, Detail: fmt.Sprintf("revoked_sessions=%d", len(revoked))})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Logout dari semua perangkat berhasil",
+		"revoked_sessions": len(revoked),
+	})
+}
+
+// truncate memotong s menjadi paling banyak n karakter. Pemotongan per rune
+// agar karakter UTF-8 tidak terbelah, karena MySQL menolak string yang tidak valid.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
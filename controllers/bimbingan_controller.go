@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
 )
@@ -20,8 +22,25 @@ func GetBimbingan(c *gin.Context) {
 
 	userID := userIDInterface.(uint)
 
-	var km model.KelompokMahasiswa
-	if err := config.DB.Where("user_id = ?", userID).First(&km).Error; err != nil {
+	// Kelompok dipilih lewat ?kelompok_id, ?all=true untuk semua kelompok,
+	// atau kelompok aktif tahun masuk berjalan
+	var kelompokIDs []uint
+	var err error
+	if wantsAllKelompok(c) {
+		var kelompok []model.Kelompok
+		kelompok, err = memberKelompok(config.DB, userID)
+		for _, k := range kelompok {
+			kelompokIDs = append(kelompokIDs, k.ID)
+		}
+		if err == nil && len(kelompokIDs) == 0 {
+			err = errBelumBerkelompok
+		}
+	} else {
+		var kelompok model.Kelompok
+		kelompok, err = resolveKelompok(c, config.DB, userID)
+		kelompokIDs = []uint{kelompok.ID}
+	}
+	if errors.Is(err, errBelumBerkelompok) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Mahasiswa belum memiliki kelompok",
 			"status":  "no_group",
@@ -29,10 +48,14 @@ func GetBimbingan(c *gin.Context) {
 		})
 		return
 	}
+	if err != nil {
+		respondKelompokContextError(c, err)
+		return
+	}
 
 	var bimbingans []model.Bimbingan
 	if err := config.DB.
-		Where("kelompok_id = ?", km.KelompokID).
+		Where("kelompok_id IN ?", kelompokIDs).
 		Preload("Kelompok").
 		Preload("Ruangan"). // preload ruangan juga
 		Find(&bimbingans).Error; err != nil {
@@ -75,19 +98,32 @@ func GetBimbingan(c *gin.Context) {
 func CreateBimbingan(c *gin.Context) {
 	userID := c.MustGet("user_id").(uint)
 
-	var km model.KelompokMahasiswa
-	if err := config.DB.Where("user_id = ?", userID).First(&km).Error; err != nil {
+	var req model.Bimbingan
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Println("BindJSON error:", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// kelompok_id di body opsional; tanpa itu dipakai kelompok aktif
+	requested := req.KelompokID
+	if requested == 0 {
+		var err error
+		if requested, err = requestedKelompokID(c); err != nil {
+			respondKelompokContextError(c, err)
+			return
+		}
+	}
+	kelompok, err := resolveKelompokID(config.DB, userID, requested)
+	if errors.Is(err, errBelumBerkelompok) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Mahasiswa belum tergabung dalam kelompok",
 			"status":  "no_group",
 		})
 		return
 	}
-
-	var req model.Bimbingan
-	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Println("BindJSON error:", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err != nil {
+		respondKelompokContextError(c, err)
 		return
 	}
 
@@ -98,7 +134,7 @@ func CreateBimbingan(c *gin.Context) {
 	// Ensure times are stored as UTC in the database
 	// The client should already be sending UTC times
 	req.UserID = userID
-	req.KelompokID = km.KelompokID
+	req.KelompokID = kelompok.ID
 	req.Status = "menunggu"
 	req.CreatedAt = time.Now().UTC()
 	req.UpdatedAt = time.Now().UTC()
@@ -111,6 +147,8 @@ func CreateBimbingan(c *gin.Context) {
 		return
 	}
 
+	audit.Track(c, audit.Change{Action: "bimbingan.create", EntityType: "bimbingan", EntityID: req.ID, After: req})
+
 	// Debug: Print stored time values
 	fmt.Printf("Stored RencanaMulai: %v\n", req.RencanaMulai)
 	fmt.Printf("Stored RencanaSelesai: %v\n", req.RencanaSelesai)
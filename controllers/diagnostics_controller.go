@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/storage"
+)
+
+// diagnosticsTimeout membatasi lama setiap pemeriksaan agar endpoint tetap cepat
+const diagnosticsTimeout = 5 * time.Second
+
+// checkResult adalah hasil satu pemeriksaan diagnostik
+type checkResult struct {
+	OK         bool        `json:"ok"`
+	LatencyMS  int64       `json:"latency_ms"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Detail     interface{} `json:"detail,omitempty"`
+}
+
+// timed menjalankan fn dengan batas waktu dan mencatat latensinya
+func timed(ctx context.Context, fn func(ctx context.Context) checkResult) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsTimeout)
+	defer cancel()
+	start := time.Now()
+	res := fn(ctx)
+	res.LatencyMS = time.Since(start).Milliseconds()
+	return res
+}
+
+// checkUpstream memeriksa apakah server upstream bisa dihubungi. Status HTTP
+// apa pun dianggap terjangkau; hanya gagal koneksi yang dihitung error.
+func checkUpstream(client *http.Client, cfg config.UpstreamConfig) func(ctx context.Context) checkResult {
+	return func(ctx context.Context) checkResult {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, cfg.BaseURL, nil)
+		if err != nil {
+			return checkResult{Error: err.Error()}
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return checkResult{Error: err.Error()}
+		}
+		resp.Body.Close()
+		return checkResult{OK: true, StatusCode: resp.StatusCode}
+	}
+}
+
+// checkStorage memeriksa apakah storage bisa dibaca
+func checkStorage(store storage.Storage) func(ctx context.Context) checkResult {
+	return func(ctx context.Context) checkResult {
+		if store == nil {
+			return checkResult{Error: "storage belum diinisialisasi"}
+		}
+		detail := gin.H{"backend": store.Name()}
+		if cached, ok := store.(*storage.CachedStorage); ok {
+			detail["cache"] = cached.Stats()
+		}
+		if _, err := store.List(ctx, ""); err != nil && !errors.Is(err, storage.ErrReadOnly) {
+			return checkResult{Error: err.Error(), Detail: detail}
+		}
+		return checkResult{OK: true, Detail: detail}
+	}
+}
+
+func checkDatabase(ctx context.Context) checkResult {
+	db, err := config.GetDB()
+	if err != nil {
+		return checkResult{Error: err.Error()}
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return checkResult{Error: err.Error()}
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return checkResult{Error: err.Error()}
+	}
+	return checkResult{OK: true, Detail: sqlDB.Stats()}
+}
+
+// redact menyembunyikan nilai rahasia dan hanya menunjukkan apakah nilainya diisi
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "***"
+}
+
+func upstreamSummary(u config.UpstreamConfig) gin.H {
+	return gin.H{
+		"base_url":      u.BaseURL,
+		"timeout":       u.Timeout.String(),
+		"retries":       u.Retries,
+		"retry_backoff": u.RetryBackoff.String(),
+		"tls": gin.H{
+			"ca_file":              u.TLS.CAFile,
+			"server_name":          u.TLS.ServerName,
+			"insecure_skip_verify": u.TLS.InsecureSkipVerify,
+		},
+	}
+}
+
+func quotaSummary(q config.Quota) gin.H {
+	return gin.H{"limit": q.Limit, "window": q.Window.String()}
+}
+
+// previousKIDs hanya menampilkan id kunci lama, tanpa secret-nya
+func previousKIDs() []string {
+	kids := []string{}
+	for _, k := range config.JWT.PreviousKeys {
+		kids = append(kids, k.KID)
+	}
+	return kids
+}
+
+// legacyUntil menampilkan batas penerimaan token lama, kosong jika tidak aktif
+func legacyUntil() string {
+	if config.JWT.LegacySecret == "" {
+		return ""
+	}
+	return config.JWT.LegacyUntil.Format(time.RFC3339)
+}
+
+// redactedConfig mengembalikan konfigurasi aktif tanpa secret
+func redactedConfig() gin.H {
+	return gin.H{
+		"app": gin.H{
+			"env":                   config.App.Env,
+			"admin_roles":           config.App.AdminRoles,
+			"impersonation_ttl":     config.App.ImpersonationTTL.String(),
+			"impersonation_max_ttl": config.App.ImpersonationMaxTTL.String(),
+		},
+		"jwt": gin.H{
+			"secret":           redact(config.JWT.Secret),
+			"algorithm":        config.JWT.Algorithm,
+			"kid":              config.JWT.KID,
+			"private_key_file": config.JWT.PrivateKeyFile,
+			"previous_kids":    previousKIDs(),
+			"legacy_secret":    redact(config.JWT.LegacySecret),
+			"legacy_until":     legacyUntil(),
+			"ttl":              config.JWT.TTL.String(),
+			"refresh_grace":    config.JWT.RefreshGrace.String(),
+			"issuer":           config.JWT.Issuer,
+		},
+		"upload": gin.H{
+			"max_tugas_size":          config.Upload.MaxTugasSize,
+			"max_pengumuman_size":     config.Upload.MaxPengumumanSize,
+			"tugas_allowed_mime":      config.Upload.TugasAllowedMIME,
+			"pengumuman_allowed_mime": config.Upload.PengumumanAllowedMIME,
+			"quarantine_dir":          config.Upload.QuarantineDir,
+			"scanner":                 config.Upload.Scanner,
+			"clamav_address":          config.Upload.ClamAVAddress,
+			"clamav_timeout":          config.Upload.ClamAVTimeout.String(),
+			"scan_fail_open":          config.Upload.ScanFailOpen,
+		},
+		"storage": gin.H{
+			"driver":                   config.Storage.Driver,
+			"root":                     config.Storage.Root,
+			"s3_endpoint":              config.Storage.S3Endpoint,
+			"s3_region":                config.Storage.S3Region,
+			"s3_bucket":                config.Storage.S3Bucket,
+			"s3_access_key":            redact(config.Storage.S3AccessKey),
+			"s3_secret_key":            redact(config.Storage.S3SecretKey),
+			"s3_path_style":            config.Storage.S3PathStyle,
+			"s3_prefix":                config.Storage.S3Prefix,
+			"laravel_cache_dir":        config.Storage.LaravelCacheDir,
+			"laravel_cache_max_size":   config.Storage.LaravelCacheMaxSize,
+			"laravel_cache_max_object": config.Storage.LaravelCacheMaxObject,
+			"laravel_cache_fresh":      config.Storage.LaravelCacheFresh.String(),
+		},
+		"signed_url": gin.H{
+			"secret":         redact(config.SignedURL.Secret),
+			"default_ttl":    config.SignedURL.DefaultTTL.String(),
+			"max_ttl":        config.SignedURL.MaxTTL.String(),
+			"session_window": config.SignedURL.SessionWindow.String(),
+		},
+		"audit": gin.H{
+			"retention":        config.Audit.Retention.String(),
+			"cleanup_interval": config.Audit.CleanupInterval.String(),
+		},
+		"rate_limit": gin.H{
+			"store":        config.RateLimit.Store,
+			"login_ip":     quotaSummary(config.RateLimit.LoginIP),
+			"login_user":   quotaSummary(config.RateLimit.LoginUser),
+			"lockout_base": config.RateLimit.LockoutBase.String(),
+			"lockout_max":  config.RateLimit.LockoutMax.String(),
+			"upload":       quotaSummary(config.RateLimit.Upload),
+			"notification": quotaSummary(config.RateLimit.Notification),
+		},
+		"upstreams": gin.H{
+			"cis_auth":             upstreamSummary(upstreams.CISAuth),
+			"cis_student":          upstreamSummary(upstreams.CISStudent),
+			"laravel":              upstreamSummary(upstreams.Laravel),
+			"laravel_storage_path": upstreams.LaravelStoragePath,
+			"laravel_tugas_dir":    upstreams.LaravelTugasDir,
+		},
+	}
+}
+
+// GetDiagnostics melaporkan keterjangkauan upstream, kesehatan storage dan
+// database, serta konfigurasi aktif dengan secret disembunyikan. Hanya untuk
+// admin dan tidak didaftarkan di mode production.
+func GetDiagnostics(c *gin.Context) {
+	checks := map[string]func(ctx context.Context) checkResult{
+		"database":             checkDatabase,
+		"upstream_cis_auth":    checkUpstream(cisAuthClient, upstreams.CISAuth),
+		"upstream_cis_student": checkUpstream(cisStudentClient, upstreams.CISStudent),
+		"upstream_laravel":     checkUpstream(laravelClient, upstreams.Laravel),
+		"storage_uploads":      checkStorage(storage.Uploads),
+		"storage_quarantine":   checkStorage(storage.Quarantine),
+		"storage_laravel":      checkStorage(storage.Laravel),
+	}
+
+	// Semua pemeriksaan dijalankan bersamaan
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = map[string]checkResult{}
+		healthy = true
+	)
+	for name, fn := range checks {
+		wg.Add(1)
+		go func(name string, fn func(ctx context.Context) checkResult) {
+			defer wg.Done()
+			res := timed(c.Request.Context(), fn)
+			mu.Lock()
+			results[name] = res
+			healthy = healthy && res.OK
+			mu.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"healthy":    healthy,
+		"checked_at": time.Now().Format(time.RFC3339),
+		"checks":     results,
+		"config":     redactedConfig(),
+	})
+}
@@ -1,24 +1,36 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/policy"
+	"github.com/rudychandra/lagi/storage"
 )
 
-// Configuration for direct file access
-const (
-	// Update this to the actual path to your uploads directory
-	UploadsPath = "D:/Semester 4/PA II/go/uploads/tugas"
-)
+// mobileFileKey converts a cleaned path used by the mobile app into a storage key.
+// Older app versions send bare filenames relative to the tugas directory.
+func mobileFileKey(key string) string {
+	if !strings.Contains(key, "/") {
+		return path.Join("tugas", key)
+	}
+	return key
+}
+
+// setMobileCORSHeaders allows direct access from the mobile app
+func setMobileCORSHeaders(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
+	c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Range, If-Range, If-None-Match, If-Modified-Since")
+	c.Header("Access-Control-Expose-Headers", "Content-Length, Content-Range, Accept-Ranges, ETag, Last-Modified, Content-Disposition")
+}
 
-// MobileFileView serves files directly from the filesystem
+// MobileFileView serves files directly from upload storage
 func MobileFileView(c *gin.Context) {
 	// Get the file path from the URL
 	filePath := c.Param("path")
@@ -27,79 +39,25 @@ func MobileFileView(c *gin.Context) {
 		return
 	}
 
-	// Remove leading slash if present
-	filePath = strings.TrimPrefix(filePath, "/")
-
 	// Print the requested file path for debugging
 	fmt.Printf("Requested file path: %s\n", filePath)
 
-	// Construct the full path to the file in the filesystem
-	fullPath := filepath.Join(UploadsPath, filePath)
-	
-	fmt.Printf("Accessing file at: %s\n", fullPath)
-	
-	// Check if file exists
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "File not found",
-			"path": fullPath,
-		})
-		return
-	}
-	
-	// Open the file
-	file, err := os.Open(fullPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to open file",
-			"details": err.Error(),
-			"path": fullPath,
-		})
+	setMobileCORSHeaders(c)
+
+	// Reject path traversal and check that the user may access this file
+	key, ok := cleanFilePath(c, filePath)
+	if !ok {
 		return
 	}
-	defer file.Close()
-	
-	// Get file info for content length
-	fileInfo, err := file.Stat()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get file info",
-			"details": err.Error(),
-		})
+	key = mobileFileKey(key)
+	if !checkFileAccess(c, key) {
 		return
 	}
-	
-	// Set content type based on file extension
-	extension := strings.ToLower(filepath.Ext(fullPath))
-	contentType := "application/octet-stream" // Default
-	
-	switch extension {
-	case ".pdf":
-		contentType = "application/pdf"
-	case ".doc", ".docx":
-		contentType = "application/msword"
-	case ".zip":
-		contentType = "application/zip"
-	}
-	
-	// Set CORS headers to allow direct access from mobile app
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
-	c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-	
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	
-	// Set content disposition for viewing
-	filename := filepath.Base(filePath)
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", filename))
-	
-	// Stream the file to the client
-	c.Status(http.StatusOK)
-	io.Copy(c.Writer, file)
+
+	serveStorageFile(c, storage.Uploads, key, "inline")
 }
 
-// MobileFileDownload serves files directly from the filesystem with download header
+// MobileFileDownload serves files directly from upload storage with download header
 func MobileFileDownload(c *gin.Context) {
 	// Get the file path from the URL
 	filePath := c.Param("path")
@@ -108,150 +66,22 @@ func MobileFileDownload(c *gin.Context) {
 		return
 	}
 
-	// Remove leading slash if present
-	filePath = strings.TrimPrefix(filePath, "/")
-
 	// Print the requested file path for debugging
 	fmt.Printf("Requested file path for download: %s\n", filePath)
 
-	// Construct the full path to the file in the filesystem
-	fullPath := filepath.Join(UploadsPath, filePath)
-	
-	fmt.Printf("Accessing file at: %s\n", fullPath)
-	
-	// Check if file exists
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "File not found",
-			"path": fullPath,
-		})
-		return
-	}
-	
-	// Open the file
-	file, err := os.Open(fullPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to open file",
-			"details": err.Error(),
-			"path": fullPath,
-		})
+	setMobileCORSHeaders(c)
+
+	// Reject path traversal and check that the user may access this file
+	key, ok := cleanFilePath(c, filePath)
+	if !ok {
 		return
 	}
-	defer file.Close()
-	
-	// Get file info for content length
-	fileInfo, err := file.Stat()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get file info",
-			"details": err.Error(),
-		})
+	key = mobileFileKey(key)
+	if !checkFileAccess(c, key) {
 		return
 	}
-	
-	// Set content type based on file extension
-	extension := strings.ToLower(filepath.Ext(fullPath))
-	contentType := "application/octet-stream" // Default
-	
-	switch extension {
-	case ".pdf":
-		contentType = "application/pdf"
-	case ".doc", ".docx":
-		contentType = "application/msword"
-	case ".zip":
-		contentType = "application/zip"
-	}
-	
-	// Set CORS headers to allow direct access from mobile app
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
-	c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-	
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	
-	// Set content disposition for download
-	filename := filepath.Base(filePath)
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	
-	// Stream the file to the client
-	c.Status(http.StatusOK)
-	io.Copy(c.Writer, file)
-}
 
-// MobileTestFileAccess tests if a file can be accessed directly
-func MobileTestFileAccess(c *gin.Context) {
-	// Test file paths
-	testPaths := []string{
-		"tugas/4_20250513102545_Doc1.docx",
-		"tugas/3_20250513085737_mpdf (1) (9).pdf",
-	}
-	
-	results := make([]map[string]interface{}, 0)
-	
-	for _, testPath := range testPaths {
-		fullPath := filepath.Join(UploadsPath, testPath)
-		
-		// Check if file exists
-		fileInfo, err := os.Stat(fullPath)
-		
-		result := map[string]interface{}{
-			"path": testPath,
-			"full_path": fullPath,
-			"exists": err == nil,
-		}
-		
-		if err == nil {
-			result["size"] = fileInfo.Size()
-			result["modified"] = fileInfo.ModTime().Format(time.RFC3339)
-			result["is_dir"] = fileInfo.IsDir()
-			
-			// Generate URLs for testing
-			result["view_url"] = fmt.Sprintf("%s/mobile-files/view/%s", c.Request.Host, testPath)
-			result["download_url"] = fmt.Sprintf("%s/mobile-files/download/%s", c.Request.Host, testPath)
-		} else {
-			result["error"] = err.Error()
-		}
-		
-		results = append(results, result)
-	}
-	
-	// Also check the uploads directory itself
-	uploadsInfo, err := os.Stat(UploadsPath)
-	uploadsResult := map[string]interface{}{
-		"path": UploadsPath,
-		"exists": err == nil,
-	}
-	
-	if err == nil {
-		uploadsResult["is_dir"] = uploadsInfo.IsDir()
-		uploadsResult["modified"] = uploadsInfo.ModTime().Format(time.RFC3339)
-		
-		// List files in the uploads directory
-		if uploadsInfo.IsDir() {
-			files, err := os.ReadDir(UploadsPath)
-			if err == nil {
-				fileList := make([]string, 0)
-				for _, file := range files {
-					fileList = append(fileList, file.Name())
-				}
-				uploadsResult["files"] = fileList
-			} else {
-				uploadsResult["list_error"] = err.Error()
-			}
-		}
-	} else {
-		uploadsResult["error"] = err.Error()
-	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Mobile file access test",
-		"uploads_path": UploadsPath,
-		"test_results": results,
-		"uploads_directory": uploadsResult,
-		"server_time": time.Now().Format(time.RFC3339),
-	})
+	serveStorageFile(c, storage.Uploads, key, "attachment")
 }
 
 // ListMobileFiles lists files in a directory
@@ -262,62 +92,73 @@ func ListMobileFiles(c *gin.Context) {
 		dirPath = "tugas" // Default to tugas directory
 	}
 	
-	// Construct the full path to the directory
-	fullPath := filepath.Join(UploadsPath, dirPath)
-	
-	// Check if directory exists
-	dirInfo, err := os.Stat(fullPath)
+	// Reject path traversal outside the upload storage
+	dirPath, err := storage.CleanKey(dirPath)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Directory not found",
-			"path": fullPath,
-			"details": err.Error(),
-		})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid directory path"})
 		return
 	}
 	
-	// Make sure it's a directory
-	if !dirInfo.IsDir() {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Path is not a directory",
-			"path": fullPath,
-		})
+	// Students only see files they are allowed to open
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
 		return
 	}
+	restricted := !subject.CanGlobal(policy.FileReadAll)
 	
 	// Read directory contents
-	files, err := os.ReadDir(fullPath)
+	files, err := storage.Uploads.List(c.Request.Context(), dirPath)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Directory not found",
+			"path": dirPath,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to read directory",
-			"path": fullPath,
+			"path": dirPath,
 			"details": err.Error(),
 		})
 		return
 	}
 	
+	// Resolve the owners of every file at once instead of one lookup per file
+	var access map[string]error
+	if restricted {
+		keys := make([]string, 0, len(files))
+		for _, file := range files {
+			if !file.IsDir {
+				keys = append(keys, file.Key)
+			}
+		}
+		if access, err = authorizeFileKeys(c, keys); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check file access"})
+			return
+		}
+	}
+	
 	// Format the response
 	fileList := make([]map[string]interface{}, 0)
 	for _, file := range files {
-		fileInfo, err := file.Info()
-		if err != nil {
+		if restricted && (file.IsDir || access[file.Key] != nil) {
 			continue
 		}
 		
-		filePath := filepath.Join(dirPath, file.Name())
-		
 		fileData := map[string]interface{}{
-			"name": file.Name(),
-			"path": filePath,
-			"is_dir": file.IsDir(),
-			"size": fileInfo.Size(),
-			"modified": fileInfo.ModTime().Format(time.RFC3339),
+			"name": path.Base(file.Key),
+			"path": file.Key,
+			"is_dir": file.IsDir,
+			"size": file.Size,
+			"modified": file.ModTime.Format(time.RFC3339),
 		}
 		
 		// Add URLs for files
-		if !file.IsDir() {
-			fileData["view_url"] = fmt.Sprintf("/mobile-files/view/%s", filePath)
-			fileData["download_url"] = fmt.Sprintf("/mobile-files/download/%s", filePath)
+		if !file.IsDir {
+			fileData["view_url"] = fmt.Sprintf("/mobile-files/view/%s", file.Key)
+			fileData["download_url"] = fmt.Sprintf("/mobile-files/download/%s", file.Key)
 		}
 		
 		fileList = append(fileList, fileData)
@@ -326,7 +167,6 @@ func ListMobileFiles(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Directory listing",
 		"path": dirPath,
-		"full_path": fullPath,
 		"files": fileList,
 	})
-}
\ No newline at end of file
+}
@@ -2,18 +2,14 @@ package controllers
 
 import (
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/storage"
 )
 
-// Ganti path ini sesuai lokasi file kamu
-const LaravelStoragePath = "https://vokasitera.d4trpl-itdel.id/storage/tugas_files/"
-
 // Fungsi untuk akses file langsung (view di browser)
 func DirectFileAccess(c *gin.Context) {
 	filePath := c.Param("path")
@@ -28,61 +24,16 @@ func DirectFileAccess(c *gin.Context) {
 	// Debug: cek path yang diminta
 	fmt.Printf("Requested file path: %s\n", filePath)
 
-	// Gabungkan path storage dengan filePath
-	fullPath := filepath.Join(LaravelStoragePath, filePath)
-	fmt.Printf("Accessing file at: %s\n", fullPath)
-
-	// Cek file ada atau tidak
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "File not found",
-			"path":  fullPath,
-		})
-		return
-	}
-
-	// Buka file
-	file, err := os.Open(fullPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to open file",
-			"details": err.Error(),
-			"path":    fullPath,
-		})
+	key, ok := cleanFilePath(c, filePath)
+	if !ok {
 		return
 	}
-	defer file.Close()
-
-	// Info file
-	fileInfo, err := file.Stat()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get file info",
-			"details": err.Error(),
-		})
+	key = path.Join(upstreams.LaravelTugasDir, key)
+	if !checkFileAccess(c, key) {
 		return
 	}
 
-	// Tentukan content type
-	extension := strings.ToLower(filepath.Ext(fullPath))
-	contentType := "application/octet-stream"
-	switch extension {
-	case ".pdf":
-		contentType = "application/pdf"
-	case ".doc", ".docx":
-		contentType = "application/msword"
-	case ".zip":
-		contentType = "application/zip"
-	}
-
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	filename := filepath.Base(filePath)
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", filename))
-
-	// Kirim file ke client
-	c.Status(http.StatusOK)
-	io.Copy(c.Writer, file)
+	serveStorageFile(c, storage.Laravel, key, "inline")
 }
 
 // Fungsi untuk download file (force download)
@@ -95,53 +46,15 @@ func DirectFileDownload(c *gin.Context) {
 
 	filePath = strings.TrimPrefix(filePath, "/")
 	fmt.Printf("Requested file path for download: %s\n", filePath)
-	fullPath := filepath.Join(LaravelStoragePath, filePath)
-	fmt.Printf("Accessing file at: %s\n", fullPath)
-
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "File not found",
-			"path":  fullPath,
-		})
-		return
-	}
 
-	file, err := os.Open(fullPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to open file",
-			"details": err.Error(),
-			"path":    fullPath,
-		})
+	key, ok := cleanFilePath(c, filePath)
+	if !ok {
 		return
 	}
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get file info",
-			"details": err.Error(),
-		})
+	key = path.Join(upstreams.LaravelTugasDir, key)
+	if !checkFileAccess(c, key) {
 		return
 	}
 
-	extension := strings.ToLower(filepath.Ext(fullPath))
-	contentType := "application/octet-stream"
-	switch extension {
-	case ".pdf":
-		contentType = "application/pdf"
-	case ".doc", ".docx":
-		contentType = "application/msword"
-	case ".zip":
-		contentType = "application/zip"
-	}
-
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	filename := filepath.Base(filePath)
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-
-	c.Status(http.StatusOK)
-	io.Copy(c.Writer, file)
+	serveStorageFile(c, storage.Laravel, key, "attachment")
 }
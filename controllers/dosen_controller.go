@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
 
@@ -50,6 +51,7 @@ func CreateDosenRoles(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	audit.Track(c, audit.Change{Action: "dosen_role.create", EntityType: "dosen_role", EntityID: dosenRole.ID, After: dosenRole})
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Role berhasil ditambahkan",
@@ -89,6 +91,7 @@ func UpdateDosenrole(c *gin.Context) {
 	}
 
 	// **Update field yang diperlukan**
+	before := dosenRole
 	dosenRole.UserID = req.UserID
 	dosenRole.RoleID = req.RoleID
 	dosenRole.Prodi = req.Prodi
@@ -97,7 +100,11 @@ func UpdateDosenrole(c *gin.Context) {
 	dosenRole.JenisPA =req.JenisPA
 
 	// **Simpan perubahan**
-	config.DB.Save(&dosenRole)	
+	if err := config.DB.Save(&dosenRole).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "dosen_role.update", EntityType: "dosen_role", EntityID: dosenRole.ID, Before: before, After: dosenRole})
 	c.JSON(http.StatusOK, gin.H{"message": "Role updated", "data": dosenRole})
 }
 
@@ -143,6 +150,10 @@ func DeleteDosenRole(c *gin.Context) {
 	}
 
 	// **Hapus dari database**
-	config.DB.Delete(&dosenRole)
+	if err := config.DB.Delete(&dosenRole).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "dosen_role.delete", EntityType: "dosen_role", EntityID: dosenRole.ID, Before: dosenRole})
 	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
 }
\ No newline at end of file
@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"github.com/rudychandra/lagi/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	errFileNotMapped    = errors.New("file is not linked to any tugas, pengumpulan, pengumuman or lampiran")
+	errFileAccessDenied = errors.New("file access denied")
+)
+
+// userKelompok mengembalikan semua kelompok tempat user terdaftar
+func userKelompok(db *gorm.DB, userID interface{}) ([]model.Kelompok, error) {
+	var kelompok []model.Kelompok
+	err := db.
+		Joins("JOIN kelompok_mahasiswa km ON km.kelompok_id = kelompok.id").
+		Where("km.user_id = ?", userID).
+		Find(&kelompok).Error
+	return kelompok, err
+}
+
+// fileOwnerPaths mengembalikan kemungkinan bentuk path sebuah key di database.
+// File upload disimpan sebagai "uploads/<key>", file Laravel disimpan apa adanya.
+func fileOwnerPaths(key string) []string {
+	return []string{key, storage.DBPath(key)}
+}
+
+// authorizeFileAccess memetakan key file ke tugas, pengumpulan tugas, pengumuman
+// atau lampiran pemiliknya, lalu mengecek apakah user boleh mengaksesnya. Mahasiswa
+// hanya boleh mengakses pengumpulan kelompoknya sendiri, file tugas angkatannya dan
+// file pengumuman yang ditujukan kepadanya.
+func authorizeFileAccess(c *gin.Context, key string) error {
+	results, err := authorizeFileKeys(c, []string{key})
+	if err != nil {
+		return err
+	}
+	return results[key]
+}
+
+// fileOwners adalah baris pemilik file, dikelompokkan per key
+type fileOwners struct {
+	pengumpulan map[string][]model.PengumpulanTugas
+	tugas       map[string][]model.Tugas
+	pengumuman  map[string][]model.Pengumuman
+	lampiran    map[string][]model.Lampiran
+}
+
+// loadFileOwners mengambil pemilik semua key dengan satu query IN per tabel
+func loadFileOwners(db *gorm.DB, keys []string) (fileOwners, error) {
+	owners := fileOwners{
+		pengumpulan: map[string][]model.PengumpulanTugas{},
+		tugas:       map[string][]model.Tugas{},
+		pengumuman:  map[string][]model.Pengumuman{},
+		lampiran:    map[string][]model.Lampiran{},
+	}
+	keyOf := map[string]string{}
+	paths := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		for _, p := range fileOwnerPaths(key) {
+			keyOf[p] = key
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return owners, nil
+	}
+
+	var pengumpulan []model.PengumpulanTugas
+	if err := db.Where("file_path IN ?", paths).Find(&pengumpulan).Error; err != nil {
+		return owners, err
+	}
+	for _, p := range pengumpulan {
+		owners.pengumpulan[keyOf[p.FilePath]] = append(owners.pengumpulan[keyOf[p.FilePath]], p)
+	}
+	var tugas []model.Tugas
+	if err := db.Where("file IN ?", paths).Find(&tugas).Error; err != nil {
+		return owners, err
+	}
+	for _, t := range tugas {
+		owners.tugas[keyOf[t.File]] = append(owners.tugas[keyOf[t.File]], t)
+	}
+	var pengumuman []model.Pengumuman
+	if err := db.Where("file IN ?", paths).Find(&pengumuman).Error; err != nil {
+		return owners, err
+	}
+	for _, p := range pengumuman {
+		owners.pengumuman[keyOf[p.File]] = append(owners.pengumuman[keyOf[p.File]], p)
+	}
+	var lampiran []model.Lampiran
+	if err := db.Where("file_path IN ?", paths).Find(&lampiran).Error; err != nil {
+		return owners, err
+	}
+	for _, l := range lampiran {
+		owners.lampiran[keyOf[l.FilePath]] = append(owners.lampiran[keyOf[l.FilePath]], l)
+	}
+	return owners, nil
+}
+
+// authorizeFileKeys menjalankan aturan authorizeFileAccess untuk beberapa key
+// sekaligus, misalnya saat menampilkan isi direktori. Pemilik file diambil
+// dengan satu query per tabel, bukan per file. Hasilnya adalah error per key
+// (nil jika boleh diakses).
+func authorizeFileKeys(c *gin.Context, keys []string) (map[string]error, error) {
+	db, err := config.GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := c.Get("user_id")
+	subject, err := policy.Load(c)
+	if err != nil {
+		return nil, err
+	}
+	// User tanpa file:read_all (mahasiswa) dibatasi ke kelompok dan angkatannya
+	restricted := !subject.CanGlobal(policy.FileReadAll)
+
+	owners, err := loadFileOwners(db, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var kelompok []model.Kelompok
+	if restricted {
+		if kelompok, err = userKelompok(db, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tugas dan pengumuman pemilik lampiran tambahan
+	lampiranTugas := map[uint]model.Tugas{}
+	lampiranPengumuman := map[uint]model.Pengumuman{}
+	if restricted {
+		var tugasIDs, pengumumanIDs []uint
+		for _, list := range owners.lampiran {
+			for _, l := range list {
+				if l.Pemilik == model.LampiranTugas {
+					tugasIDs = append(tugasIDs, l.PemilikID)
+				} else {
+					pengumumanIDs = append(pengumumanIDs, l.PemilikID)
+				}
+			}
+		}
+		if len(tugasIDs) > 0 {
+			var tugas []model.Tugas
+			if err := db.Where("id IN ?", tugasIDs).Find(&tugas).Error; err != nil {
+				return nil, err
+			}
+			for _, t := range tugas {
+				lampiranTugas[t.ID] = t
+			}
+		}
+		if len(pengumumanIDs) > 0 {
+			var pengumuman []model.Pengumuman
+			if err := db.Where("id IN ?", pengumumanIDs).Find(&pengumuman).Error; err != nil {
+				return nil, err
+			}
+			for _, p := range pengumuman {
+				lampiranPengumuman[p.ID] = p
+			}
+		}
+	}
+
+	// Pengumuman yang bisa dibuka user, dihitung sekali untuk semua key
+	var openable map[uint]bool
+	if restricted {
+		all := []model.Pengumuman{}
+		for _, list := range owners.pengumuman {
+			all = append(all, list...)
+		}
+		for _, p := range lampiranPengumuman {
+			all = append(all, p)
+		}
+		if openable, err = openablePengumuman(c, db, all); err != nil {
+			return nil, err
+		}
+	}
+	pengumumanAllowed := func(pengumuman []model.Pengumuman) error {
+		for _, p := range pengumuman {
+			if openable[p.ID] {
+				return nil
+			}
+		}
+		return errFileAccessDenied
+	}
+
+	results := make(map[string]error, len(keys))
+	for _, key := range keys {
+		results[key] = func() error {
+			// Pengumpulan tugas: hanya anggota kelompok yang mengumpulkan
+			if pengumpulan := owners.pengumpulan[key]; len(pengumpulan) > 0 {
+				if !restricted {
+					return nil
+				}
+				for _, p := range pengumpulan {
+					for _, k := range kelompok {
+						if p.KelompokID == k.ID {
+							return nil
+						}
+					}
+				}
+				return errFileAccessDenied
+			}
+
+			// File tugas: mahasiswa di prodi dan angkatan yang sama
+			if tugas := owners.tugas[key]; len(tugas) > 0 {
+				if !restricted || tugasFileAllowed(tugas, kelompok) {
+					return nil
+				}
+				return errFileAccessDenied
+			}
+
+			// Lampiran pengumuman: mahasiswa yang termasuk target pengumuman
+			if pengumuman := owners.pengumuman[key]; len(pengumuman) > 0 {
+				if !restricted {
+					return nil
+				}
+				return pengumumanAllowed(pengumuman)
+			}
+
+			// Lampiran tambahan: mengikuti akses pengumuman atau tugas pemiliknya
+			if lampiran := owners.lampiran[key]; len(lampiran) > 0 {
+				if !restricted {
+					return nil
+				}
+				var tugas []model.Tugas
+				var pengumuman []model.Pengumuman
+				for _, l := range lampiran {
+					if l.Pemilik == model.LampiranTugas {
+						if t, ok := lampiranTugas[l.PemilikID]; ok {
+							tugas = append(tugas, t)
+						}
+					} else if p, ok := lampiranPengumuman[l.PemilikID]; ok {
+						pengumuman = append(pengumuman, p)
+					}
+				}
+				if tugasFileAllowed(tugas, kelompok) {
+					return nil
+				}
+				return pengumumanAllowed(pengumuman)
+			}
+
+			return errFileNotMapped
+		}()
+	}
+	return results, nil
+}
+
+// tugasFileAllowed: file tugas boleh diakses mahasiswa di prodi dan angkatan
+// yang sama dengan salah satu kelompoknya
+func tugasFileAllowed(tugas []model.Tugas, kelompok []model.Kelompok) bool {
+	for _, t := range tugas {
+		for _, k := range kelompok {
+			if t.ProdiID == k.ProdiID && t.TMID == k.TMID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// openablePengumuman mengembalikan id pengumuman yang bisa dibuka user
+// dengan aturan yang sama seperti canOpen. Pengumuman yang belum dihapus
+// diperiksa dengan satu query untuk semuanya.
+func openablePengumuman(c *gin.Context, db *gorm.DB, pengumuman []model.Pengumuman) (map[uint]bool, error) {
+	openable := map[uint]bool{}
+	if len(pengumuman) == 0 {
+		return openable, nil
+	}
+	audience, err := loadAudience(c, db)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(pengumuman))
+	for _, p := range pengumuman {
+		ids = append(ids, p.ID)
+	}
+	metas, _, err := loadPengumumanExtras(db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	check := []uint{}
+	for _, p := range pengumuman {
+		meta := metas[p.ID]
+		switch {
+		case meta != nil && meta.DeletedAt != nil:
+			ok, err := audience.canOpen(p, meta)
+			if err != nil {
+				return nil, err
+			}
+			openable[p.ID] = ok
+		case p.UserID == audience.subject.UserID || audience.subject.Admin:
+			openable[p.ID] = true
+		default:
+			check = append(check, p.ID)
+		}
+	}
+	if len(check) == 0 {
+		return openable, nil
+	}
+	query, err := audience.filterVisible(publishedPengumuman(db, time.Now()).Where("pengumuman.id IN ?", check))
+	if err != nil {
+		return nil, err
+	}
+	var visible []uint
+	if err := query.Pluck("pengumuman.id", &visible).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range visible {
+		openable[id] = true
+	}
+	return openable, nil
+}
+
+// checkFileAccess menjalankan authorizeFileAccess dan menulis response error jika
+// akses ditolak. Mengembalikan true jika file boleh dilayani.
+func checkFileAccess(c *gin.Context, key string) bool {
+	err := authorizeFileAccess(c, key)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errFileNotMapped):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found", "path": key})
+	case errors.Is(err, errFileAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki akses ke file ini"})
+	default:
+		fmt.Printf("Error authorizing file %s: %v\n", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check file access"})
+	}
+	return false
+}
+
+// cleanFilePath memvalidasi path dari request dan menolak path traversal ("..")
+func cleanFilePath(c *gin.Context, filePath string) (string, bool) {
+	key, err := storage.CleanKey(filePath)
+	if err != nil || key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file path"})
+		return "", false
+	}
+	return key, true
+}
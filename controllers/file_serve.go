@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/storage"
+)
+
+// contentTypeFor menentukan content type berdasarkan ekstensi file
+func contentTypeFor(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".pdf":
+		return "application/pdf"
+	case ".doc", ".docx":
+		return "application/msword"
+	case ".zip":
+		return "application/zip"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	}
+	return "application/octet-stream"
+}
+
+// serveStorageFile mengirim file dari storage ke client dengan disposition
+// "inline" (untuk dilihat) atau "attachment" (untuk diunduh). Range (termasuk
+// multi-range), If-None-Match, If-Modified-Since dan If-Range ditangani oleh
+// http.ServeContent, jadi video dan PDF besar bisa di-seek dan unduhan bisa
+// dilanjutkan. Untuk storage remote, Range diteruskan ke server asal.
+func serveStorageFile(c *gin.Context, store storage.Storage, key, disposition string) {
+	obj, info, err := store.Open(c.Request.Context(), key)
+	if err != nil {
+		respondStorageError(c, err, key)
+		return
+	}
+	defer obj.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = contentTypeFor(key)
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%s", disposition, path.Base(key)))
+	// File hanya untuk user yang berhak: boleh disimpan browser, tapi wajib divalidasi ulang
+	c.Header("Cache-Control", "private, no-cache")
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+
+	http.ServeContent(c.Writer, c.Request, path.Base(key), info.ModTime, obj)
+}
+
+// respondStorageError menerjemahkan error storage menjadi response HTTP
+func respondStorageError(c *gin.Context, err error, key string) {
+	var statusErr *storage.StatusError
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found", "path": key})
+	case errors.Is(err, storage.ErrInvalidKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file path", "path": key})
+	case errors.As(err, &statusErr):
+		c.JSON(statusErr.StatusCode, gin.H{
+			"error":       "File not found or access denied",
+			"status_code": statusErr.StatusCode,
+			"path":        key,
+		})
+	default:
+		fmt.Printf("Error opening file %s: %v\n", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch file", "details": err.Error()})
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/utils"
+	"gorm.io/gorm"
+)
+
+// ImpersonationRequest adalah body untuk POST /admin/impersonate
+type ImpersonationRequest struct {
+	UserID     uint   `json:"user_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+	AllowWrite bool   `json:"allow_write"`
+	TTLSeconds int    `json:"ttl_seconds"`
+}
+
+// StartImpersonation membuat token berumur pendek agar admin bisa melihat
+// aplikasi sebagai user lain tanpa password-nya. Token tidak punya refresh
+// token, hanya-baca kecuali allow_write diisi, dan semua request dengan
+// token tersebut dicatat di audit log.
+func StartImpersonation(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	// Token impersonasi tidak boleh dipakai untuk impersonasi berantai
+	if _, nested := c.Get("impersonator_id"); nested {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Tidak bisa memulai impersonasi dari token impersonasi"})
+		return
+	}
+
+	var req ImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id dan reason wajib diisi"})
+		return
+	}
+	if req.UserID == adminID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tidak bisa impersonasi diri sendiri"})
+		return
+	}
+
+	ttl := config.App.ImpersonationTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > config.App.ImpersonationMaxTTL {
+		ttl = config.App.ImpersonationMaxTTL
+	}
+
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	// Role diambil dari profil lokal yang tersimpan saat user terakhir login
+	var target model.User
+	if err := db.Where("id = ?", req.UserID).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User belum pernah login, profil tidak ditemukan"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data user"})
+		return
+	}
+	if config.App.IsAdmin(target.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin lain tidak bisa di-impersonasi"})
+		return
+	}
+
+	sessionID, err := utils.NewSessionID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat token impersonasi"})
+		return
+	}
+	token, expiresAt, err := utils.GenerateImpersonationToken(target.ID, target.Role, sessionID, adminID.(uint), req.AllowWrite, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat token impersonasi"})
+		return
+	}
+
+	audit.Track(c, audit.Change{
+		Action:     "impersonation.start",
+		EntityType: "user",
+		EntityID:   target.ID,
+		Detail:     fmt.Sprintf("reason=%q allow_write=%t ttl=%s", req.Reason, req.AllowWrite, ttl),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Token impersonasi dibuat",
+		"impersonation":  true,
+		"internal_token": token,
+		"expires_at":     expiresAt.Format(time.RFC3339),
+		"expires_in":     int(time.Until(expiresAt).Seconds()),
+		"session_id":     sessionID,
+		"allow_write":    req.AllowWrite,
+		"reason":         req.Reason,
+		"user":           target,
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
 )
@@ -355,6 +356,14 @@ func CreateJadwal(c *gin.Context) {
 		return
 	}
 
+	audit.Track(c, audit.Change{
+		Action:     "jadwal.create",
+		EntityType: "jadwal",
+		EntityID:   jadwal.ID,
+		After:      jadwal,
+		Detail:     fmt.Sprintf("penguji=%v", request.Penguji),
+	})
+
 	// Send notification if needed
 	// This is optional and can be implemented separately
 
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/utils"
+)
+
+// GetJWKS mengembalikan kunci publik token internal dalam format JWKS.
+// Jika token ditandatangani dengan HS256, daftar kunci kosong.
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.JWKS()})
+}
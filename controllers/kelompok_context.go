@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"gorm.io/gorm"
+)
+
+// errBelumBerkelompok dikembalikan jika mahasiswa belum menjadi anggota
+// kelompok mana pun. Setiap endpoint menjawabnya dengan response masing-masing.
+var errBelumBerkelompok = errors.New("mahasiswa belum tergabung dalam kelompok")
+
+// kelompokContextError adalah kesalahan saat menentukan kelompok yang dipakai
+// request, misalnya kelompok_id bukan milik mahasiswa atau pilihan kelompok
+// tidak bisa ditentukan otomatis
+type kelompokContextError struct {
+	status  int
+	msg     string
+	options []model.Kelompok
+}
+
+func (e *kelompokContextError) Error() string { return e.msg }
+
+// respondKelompokContextError menulis response untuk error dari
+// resolveKelompok selain errBelumBerkelompok
+func respondKelompokContextError(c *gin.Context, err error) {
+	var ctxErr *kelompokContextError
+	if errors.As(err, &ctxErr) {
+		body := gin.H{"error": ctxErr.msg}
+		if ctxErr.options != nil {
+			body["kelompok"] = ctxErr.options
+		}
+		c.JSON(ctxErr.status, body)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kelompok"})
+}
+
+// requestedKelompokID membaca kelompok_id dari query string atau form.
+// Mengembalikan 0 jika tidak diisi.
+func requestedKelompokID(c *gin.Context) (uint, error) {
+	raw := c.Query("kelompok_id")
+	if raw == "" {
+		raw = c.PostForm("kelompok_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id == 0 {
+		return 0, &kelompokContextError{status: http.StatusBadRequest, msg: "kelompok_id tidak valid"}
+	}
+	return uint(id), nil
+}
+
+// wantsAllKelompok bernilai true jika client meminta data dari semua kelompok
+func wantsAllKelompok(c *gin.Context) bool {
+	all, _ := strconv.ParseBool(c.Query("all"))
+	return all
+}
+
+// memberKelompok mengambil semua kelompok tempat mahasiswa menjadi anggota,
+// diurutkan dari yang terbaru
+func memberKelompok(db *gorm.DB, userID interface{}) ([]model.Kelompok, error) {
+	var kelompok []model.Kelompok
+	err := db.Joins("JOIN kelompok_mahasiswa km ON km.kelompok_id = kelompok.id").
+		Where("km.user_id = ?", userID).
+		Order("kelompok.id DESC").
+		Find(&kelompok).Error
+	return kelompok, err
+}
+
+// pickKelompok memilih satu kelompok dari kandidat. Lebih dari satu kandidat
+// berarti mahasiswa harus memilih sendiri lewat kelompok_id.
+func pickKelompok(candidates []model.Kelompok) (model.Kelompok, error) {
+	if len(candidates) > 1 {
+		return model.Kelompok{}, &kelompokContextError{
+			status:  http.StatusConflict,
+			msg:     "Mahasiswa tergabung di beberapa kelompok aktif, pilih kelompok dengan kelompok_id",
+			options: candidates,
+		}
+	}
+	return candidates[0], nil
+}
+
+// defaultKelompok memilih kelompok aktif pada tahun masuk yang sedang
+// berjalan. Jika tidak ada, dipakai kelompok aktif lain, lalu kelompok
+// terbaru.
+func defaultKelompok(db *gorm.DB, kelompok []model.Kelompok) (model.Kelompok, error) {
+	var tahunAktif []uint
+	if err := db.Model(&model.Tahun_Masuk{}).
+		Where("Status = ?", config.Kelompok.TahunMasukAktif).
+		Pluck("id", &tahunAktif).Error; err != nil {
+		return model.Kelompok{}, err
+	}
+	isTahunAktif := map[uint]bool{}
+	for _, id := range tahunAktif {
+		isTahunAktif[id] = true
+	}
+
+	var aktif, aktifTahunIni []model.Kelompok
+	for _, k := range kelompok {
+		if !strings.EqualFold(k.Status, config.Kelompok.StatusAktif) {
+			continue
+		}
+		aktif = append(aktif, k)
+		if isTahunAktif[k.TMID] {
+			aktifTahunIni = append(aktifTahunIni, k)
+		}
+	}
+	if len(aktifTahunIni) > 0 {
+		return pickKelompok(aktifTahunIni)
+	}
+	if len(aktif) > 0 {
+		return pickKelompok(aktif)
+	}
+	return kelompok[0], nil
+}
+
+// resolveKelompok menentukan kelompok yang dipakai request mahasiswa.
+// kelompok_id dari request harus kelompok milik mahasiswa; tanpa kelompok_id
+// dipakai defaultKelompok.
+func resolveKelompok(c *gin.Context, db *gorm.DB, userID interface{}) (model.Kelompok, error) {
+	requested, err := requestedKelompokID(c)
+	if err != nil {
+		return model.Kelompok{}, err
+	}
+	return resolveKelompokID(db, userID, requested)
+}
+
+// resolveKelompokID sama dengan resolveKelompok dengan kelompok_id yang sudah
+// dibaca dari request, misalnya dari body JSON
+func resolveKelompokID(db *gorm.DB, userID interface{}, requested uint) (model.Kelompok, error) {
+	kelompok, err := memberKelompok(db, userID)
+	if err != nil {
+		return model.Kelompok{}, err
+	}
+	if len(kelompok) == 0 {
+		return model.Kelompok{}, errBelumBerkelompok
+	}
+
+	if requested != 0 {
+		for _, k := range kelompok {
+			if k.ID == requested {
+				return k, nil
+			}
+		}
+		return model.Kelompok{}, &kelompokContextError{status: http.StatusForbidden, msg: "Mahasiswa bukan anggota kelompok tersebut"}
+	}
+	return defaultKelompok(db, kelompok)
+}
+
+// resolveKelompokForTugas menentukan kelompok untuk tugas tertentu. Tanpa
+// kelompok_id dipakai kelompok mahasiswa yang sesuai prodi, kategori PA dan
+// tahun masuk tugas; kelompok_id yang tidak sesuai tugas ditolak.
+func resolveKelompokForTugas(c *gin.Context, db *gorm.DB, userID interface{}, tugas model.Tugas) (model.Kelompok, error) {
+	requested, err := requestedKelompokID(c)
+	if err != nil {
+		return model.Kelompok{}, err
+	}
+	if requested != 0 {
+		k, err := resolveKelompokID(db, userID, requested)
+		if err != nil {
+			return k, err
+		}
+		if !kelompokMatchesTugas(k, tugas) {
+			return model.Kelompok{}, &kelompokContextError{status: http.StatusBadRequest, msg: "Tugas ini bukan untuk kelompok tersebut"}
+		}
+		return k, nil
+	}
+
+	kelompok, err := memberKelompok(db, userID)
+	if err != nil {
+		return model.Kelompok{}, err
+	}
+	if len(kelompok) == 0 {
+		return model.Kelompok{}, errBelumBerkelompok
+	}
+	var matches []model.Kelompok
+	for _, k := range kelompok {
+		if kelompokMatchesTugas(k, tugas) {
+			matches = append(matches, k)
+		}
+	}
+	if len(matches) > 0 {
+		return pickKelompok(matches)
+	}
+	return model.Kelompok{}, &kelompokContextError{status: http.StatusForbidden, msg: "Tugas ini bukan untuk kelompok mahasiswa"}
+}
+
+// kelompokMatchesTugas memeriksa apakah tugas ditujukan untuk angkatan
+// kelompok. Tugas tanpa kategori PA berlaku untuk semua kategori.
+func kelompokMatchesTugas(k model.Kelompok, tugas model.Tugas) bool {
+	return k.ProdiID == tugas.ProdiID && k.TMID == tugas.TMID &&
+		(tugas.KPAID == 0 || k.KPAID == tugas.KPAID)
+}
@@ -0,0 +1,650 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kelompokRuleError adalah pelanggaran aturan kelompok yang dikembalikan ke
+// client apa adanya dengan status 409
+type kelompokRuleError struct {
+	msg string
+}
+
+func (e *kelompokRuleError) Error() string { return e.msg }
+
+func ruleErrorf(format string, args ...interface{}) error {
+	return &kelompokRuleError{msg: fmt.Sprintf(format, args...)}
+}
+
+// respondKelompokError menulis response untuk error dari transaksi kelompok
+func respondKelompokError(c *gin.Context, err error, fallback string) {
+	var rule *kelompokRuleError
+	if errors.As(err, &rule) {
+		c.JSON(http.StatusConflict, gin.H{"error": rule.msg})
+		return
+	}
+	fmt.Printf("%s: %v\n", fallback, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
+
+// KelompokRequest adalah body untuk POST /kelompok
+type KelompokRequest struct {
+	NomorKelompok string `json:"nomor_kelompok" binding:"required"`
+	ProdiID       uint   `json:"prodi_id" binding:"required"`
+	KPAID         uint   `json:"kpa_id" binding:"required"`
+	TMID          uint   `json:"tm_id" binding:"required"`
+	Anggota       []uint `json:"anggota"`
+	KetuaID       uint   `json:"ketua_id"`
+}
+
+// AnggotaRequest adalah body untuk menambah anggota atau mengganti ketua
+type AnggotaRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// kelompokScopeFilter mengembalikan fungsi untuk memeriksa apakah kelompok
+// termasuk cakupan permission user. Hasil per prodi/KPA disimpan agar nama
+// prodi tidak dibaca berulang kali.
+func kelompokScopeFilter(db *gorm.DB, subject *policy.Subject, p policy.Permission) func(k model.Kelompok) (bool, error) {
+	type cohort struct{ prodiID, kpaID uint }
+	checked := map[cohort]bool{}
+	return func(k model.Kelompok) (bool, error) {
+		if subject.CanGlobal(p) {
+			return true, nil
+		}
+		key := cohort{k.ProdiID, k.KPAID}
+		if allowed, ok := checked[key]; ok {
+			return allowed, nil
+		}
+		allowed, err := subject.CanForCohort(db, p, k.ProdiID, k.KPAID)
+		if err != nil {
+			return false, err
+		}
+		checked[key] = allowed
+		return allowed, nil
+	}
+}
+
+// loadKelompok mengambil kelompok dari parameter :id dan memeriksa apakah
+// user boleh melakukan aksi p pada kelompok tersebut. Response error sudah
+// ditulis jika ok bernilai false.
+func loadKelompok(c *gin.Context, db *gorm.DB, p policy.Permission) (kelompok model.Kelompok, ok bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid kelompok ID"})
+		return kelompok, false
+	}
+	if err := db.First(&kelompok, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan"})
+		return kelompok, false
+	}
+
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return kelompok, false
+	}
+	allowed, err := subject.CanForCohort(db, p, kelompok.ProdiID, kelompok.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return kelompok, false
+	}
+	if !allowed {
+		policy.Deny(c, p)
+		return kelompok, false
+	}
+	return kelompok, true
+}
+
+// lockKelompok mengunci baris kelompok sampai transaksi selesai agar
+// perubahan anggota pada kelompok yang sama tidak saling balapan
+func lockKelompok(tx *gorm.DB, id uint) (model.Kelompok, error) {
+	var kelompok model.Kelompok
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&kelompok, id).Error
+	return kelompok, err
+}
+
+// lockMahasiswa mengunci baris kunci mahasiswa sampai transaksi selesai.
+// Upsert pada primary key langsung mengambil kunci eksklusif, termasuk untuk
+// baris yang baru dibuat request lain dan belum di-commit. Id diurutkan agar
+// transaksi yang mengunci beberapa mahasiswa tidak saling deadlock.
+func lockMahasiswa(tx *gorm.DB, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	ids := append([]uint(nil), userIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	rows := make([]model.KelompokMahasiswaLock, 0, len(ids))
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		rows = append(rows, model.KelompokMahasiswaLock{UserID: id})
+	}
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"user_id"}),
+	}).Create(&rows).Error
+}
+
+// isKelompokAktif membandingkan status kelompok tanpa memperhatikan huruf besar
+func isKelompokAktif(k model.Kelompok) bool {
+	return strings.EqualFold(k.Status, config.Kelompok.StatusAktif)
+}
+
+// addAnggota menambahkan mahasiswa ke kelompok setelah memeriksa ukuran
+// kelompok dan keanggotaan di kelompok aktif lain dengan KPA yang sama
+func addAnggota(tx *gorm.DB, kelompok model.Kelompok, userID uint) (model.KelompokMahasiswa, error) {
+	var member model.KelompokMahasiswa
+
+	// Kunci mahasiswa sebelum memeriksa kelompok aktif lain; kunci kelompok
+	// saja tidak cukup karena request lain bisa menambah ke kelompok berbeda
+	if err := lockMahasiswa(tx, []uint{userID}); err != nil {
+		return member, err
+	}
+
+	// Hanya mahasiswa yang bisa menjadi anggota; user yang belum pernah
+	// login tidak punya profil lokal dan tetap diterima
+	var user model.User
+	if err := tx.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return member, err
+	}
+	if user.ID != 0 && user.Role != policy.RoleMahasiswa {
+		return member, ruleErrorf("User %d bukan mahasiswa", userID)
+	}
+
+	var count int64
+	if err := tx.Model(&model.KelompokMahasiswa{}).
+		Where("kelompok_id = ? AND user_id = ?", kelompok.ID, userID).
+		Count(&count).Error; err != nil {
+		return member, err
+	}
+	if count > 0 {
+		return member, ruleErrorf("Mahasiswa %d sudah menjadi anggota kelompok ini", userID)
+	}
+
+	if err := tx.Model(&model.KelompokMahasiswa{}).
+		Where("kelompok_id = ?", kelompok.ID).
+		Count(&count).Error; err != nil {
+		return member, err
+	}
+	if int(count) >= config.Kelompok.MaxAnggota {
+		return member, ruleErrorf("Kelompok sudah penuh (maksimal %d anggota)", config.Kelompok.MaxAnggota)
+	}
+
+	// Satu mahasiswa hanya boleh di satu kelompok aktif per kategori PA
+	if isKelompokAktif(kelompok) {
+		var other model.Kelompok
+		if err := tx.Joins("JOIN kelompok_mahasiswa km ON km.kelompok_id = kelompok.id").
+			Where("km.user_id = ? AND kelompok.KPA_id = ? AND kelompok.id <> ? AND kelompok.status = ?",
+				userID, kelompok.KPAID, kelompok.ID, config.Kelompok.StatusAktif).
+			Limit(1).Find(&other).Error; err != nil {
+			return member, err
+		}
+		if other.ID != 0 {
+			return member, ruleErrorf("Mahasiswa %d sudah tergabung di kelompok aktif %s untuk kategori PA yang sama", userID, other.NomorKelompok)
+		}
+	}
+
+	member = model.KelompokMahasiswa{KelompokID: kelompok.ID, UserID: userID}
+	if err := tx.Omit("Kelompok").Create(&member).Error; err != nil {
+		return member, err
+	}
+	return member, nil
+}
+
+// checkMaksProject mengunci baris prodi lalu memastikan penambahan
+// sejumlah kelompok aktif tidak melewati Prodi.MaksProject untuk angkatan
+// tersebut. Kunci prodi mencegah request bersamaan melewati batas.
+func checkMaksProject(tx *gorm.DB, prodiID, kpaID, tmID uint, tambahan int) error {
+	var prodi model.Prodi
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&prodi, prodiID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ruleErrorf("Prodi %d tidak ditemukan", prodiID)
+		}
+		return err
+	}
+	if prodi.MaksProject <= 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&model.Kelompok{}).
+		Where("prodi_id = ? AND KPA_id = ? AND TM_id = ? AND status = ?", prodiID, kpaID, tmID, config.Kelompok.StatusAktif).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count)+tambahan > prodi.MaksProject {
+		return ruleErrorf("Prodi %s dibatasi %d kelompok, sudah ada %d kelompok aktif", prodi.NamaProdi, prodi.MaksProject, count)
+	}
+	return nil
+}
+
+// insertKelompok membuat kelompok beserta anggotanya. Nomor kelompok harus
+// unik dalam satu angkatan.
+func insertKelompok(tx *gorm.DB, kelompok *model.Kelompok, anggota []uint) error {
+	var count int64
+	if err := tx.Model(&model.Kelompok{}).
+		Where("prodi_id = ? AND KPA_id = ? AND TM_id = ? AND nomor_kelompok = ?", kelompok.ProdiID, kelompok.KPAID, kelompok.TMID, kelompok.NomorKelompok).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ruleErrorf("Nomor kelompok %s sudah dipakai di angkatan ini", kelompok.NomorKelompok)
+	}
+
+	if err := lockMahasiswa(tx, anggota); err != nil {
+		return err
+	}
+	if err := tx.Create(kelompok).Error; err != nil {
+		return err
+	}
+	for _, userID := range anggota {
+		if _, err := addAnggota(tx, *kelompok, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setKetua menjadikan anggota kelompok sebagai ketua
+func setKetua(tx *gorm.DB, kelompokID, userID uint) error {
+	var count int64
+	if err := tx.Model(&model.KelompokMahasiswa{}).
+		Where("kelompok_id = ? AND user_id = ?", kelompokID, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ruleErrorf("Ketua harus anggota kelompok")
+	}
+	ketua := model.KelompokKetua{KelompokID: kelompokID, UserID: userID}
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "updated_at"}),
+	}).Create(&ketua).Error
+}
+
+// kelompokDetail menyusun data kelompok beserta anggota dan ketuanya
+func kelompokDetail(db *gorm.DB, kelompok model.Kelompok) (gin.H, error) {
+	var members []model.KelompokMahasiswa
+	if err := db.Where("kelompok_id = ?", kelompok.ID).Order("id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	var ketua model.KelompokKetua
+	if err := db.Where("kelompok_id = ?", kelompok.ID).Limit(1).Find(&ketua).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID)
+	}
+	var users []model.User
+	if len(ids) > 0 {
+		if err := db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+	profiles := map[uint]model.User{}
+	for _, u := range users {
+		profiles[u.ID] = u
+	}
+
+	anggota := make([]gin.H, 0, len(members))
+	for _, m := range members {
+		u := profiles[m.UserID]
+		anggota = append(anggota, gin.H{
+			"user_id":   m.UserID,
+			"username":  u.Username,
+			"email":     u.Email,
+			"is_ketua":  ketua.UserID == m.UserID,
+			"joined_at": m.CreatedAt,
+		})
+	}
+
+	var ketuaID interface{}
+	if ketua.KelompokID != 0 {
+		ketuaID = ketua.UserID
+	}
+	return gin.H{
+		"kelompok":    kelompok,
+		"anggota":     anggota,
+		"ketua_id":    ketuaID,
+		"max_anggota": config.Kelompok.MaxAnggota,
+	}, nil
+}
+
+// GetKelompokList menampilkan kelompok di prodi dan kategori PA yang menjadi
+// cakupan user. Filter: prodi_id, kpa_id, tm_id, status.
+func GetKelompokList(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+
+	query := db.Model(&model.Kelompok{})
+	for param, column := range map[string]string{
+		"prodi_id": "prodi_id",
+		"kpa_id":   "KPA_id",
+		"tm_id":    "TM_id",
+		"status":   "status",
+	} {
+		if v := c.Query(param); v != "" {
+			query = query.Where(column+" = ?", v)
+		}
+	}
+
+	var all []model.Kelompok
+	if err := query.Order("nomor_kelompok").Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kelompok"})
+		return
+	}
+
+	inScope := kelompokScopeFilter(db, subject, policy.KelompokView)
+	kelompok := make([]model.Kelompok, 0, len(all))
+	for _, k := range all {
+		allowed, err := inScope(k)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		if allowed {
+			kelompok = append(kelompok, k)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   kelompok,
+	})
+}
+
+// GetKelompokDetail menampilkan kelompok beserta anggotanya. Anggota
+// kelompok boleh melihat kelompoknya sendiri.
+func GetKelompokDetail(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid kelompok ID"})
+		return
+	}
+	userID, _ := c.Get("user_id")
+	var count int64
+	if err := db.Model(&model.KelompokMahasiswa{}).
+		Where("kelompok_id = ? AND user_id = ?", id, userID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kelompok"})
+		return
+	}
+
+	var kelompok model.Kelompok
+	if count > 0 {
+		if err := db.First(&kelompok, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan"})
+			return
+		}
+	} else {
+		var ok bool
+		if kelompok, ok = loadKelompok(c, db, policy.KelompokView); !ok {
+			return
+		}
+	}
+
+	detail, err := kelompokDetail(db, kelompok)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil anggota kelompok"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   detail,
+	})
+}
+
+// CreateKelompok membuat kelompok baru untuk satu angkatan prodi dan
+// kategori PA, sekaligus anggota dan ketuanya. Jumlah kelompok aktif dalam
+// angkatan tersebut dibatasi Prodi.MaksProject.
+func CreateKelompok(c *gin.Context) {
+	var req KelompokRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	allowed, err := subject.CanForCohort(db, policy.KelompokManage, req.ProdiID, req.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if !allowed {
+		policy.Deny(c, policy.KelompokManage)
+		return
+	}
+
+	// Buang anggota ganda, urutan dari request dipertahankan
+	seen := map[uint]bool{}
+	anggota := make([]uint, 0, len(req.Anggota))
+	for _, id := range req.Anggota {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			anggota = append(anggota, id)
+		}
+	}
+	if len(anggota) > config.Kelompok.MaxAnggota {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Maksimal %d anggota per kelompok", config.Kelompok.MaxAnggota)})
+		return
+	}
+	if req.KetuaID != 0 && !seen[req.KetuaID] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ketua harus termasuk dalam anggota"})
+		return
+	}
+
+	kelompok := model.Kelompok{
+		NomorKelompok: req.NomorKelompok,
+		ProdiID:       req.ProdiID,
+		KPAID:         req.KPAID,
+		TMID:          req.TMID,
+		Status:        config.Kelompok.StatusAktif,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := checkMaksProject(tx, req.ProdiID, req.KPAID, req.TMID, 1); err != nil {
+			return err
+		}
+		if err := insertKelompok(tx, &kelompok, anggota); err != nil {
+			return err
+		}
+		if req.KetuaID != 0 {
+			return setKetua(tx, kelompok.ID, req.KetuaID)
+		}
+		return nil
+	})
+	if err != nil {
+		respondKelompokError(c, err, "Gagal membuat kelompok")
+		return
+	}
+
+	audit.Track(c, audit.Change{
+		Action:     "kelompok.create",
+		EntityType: "kelompok",
+		EntityID:   kelompok.ID,
+		After:      kelompok,
+		Detail:     fmt.Sprintf("anggota=%v ketua=%d", anggota, req.KetuaID),
+	})
+
+	detail, err := kelompokDetail(db, kelompok)
+	if err != nil {
+		detail = gin.H{"kelompok": kelompok}
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Kelompok berhasil dibuat",
+		"data":    detail,
+	})
+}
+
+// AddKelompokAnggota menambahkan mahasiswa ke kelompok
+func AddKelompokAnggota(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	kelompok, ok := loadKelompok(c, db, policy.KelompokManage)
+	if !ok {
+		return
+	}
+
+	var req AnggotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id wajib diisi"})
+		return
+	}
+
+	var member model.KelompokMahasiswa
+	err = db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockKelompok(tx, kelompok.ID)
+		if err != nil {
+			return err
+		}
+		member, err = addAnggota(tx, locked, req.UserID)
+		return err
+	})
+	if err != nil {
+		respondKelompokError(c, err, "Gagal menambahkan anggota")
+		return
+	}
+
+	audit.Track(c, audit.Change{Action: "kelompok.add_anggota", EntityType: "kelompok", EntityID: kelompok.ID, After: member})
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Anggota berhasil ditambahkan",
+		"data":    member,
+	})
+}
+
+// RemoveKelompokAnggota mengeluarkan mahasiswa dari kelompok. Jika mahasiswa
+// tersebut ketua, kelompok menjadi tanpa ketua.
+func RemoveKelompokAnggota(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	kelompok, ok := loadKelompok(c, db, policy.KelompokManage)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var member model.KelompokMahasiswa
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockKelompok(tx, kelompok.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("kelompok_id = ? AND user_id = ?", kelompok.ID, userID).First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ruleErrorf("Mahasiswa %d bukan anggota kelompok ini", userID)
+			}
+			return err
+		}
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+		return tx.Where("kelompok_id = ? AND user_id = ?", kelompok.ID, userID).Delete(&model.KelompokKetua{}).Error
+	})
+	if err != nil {
+		respondKelompokError(c, err, "Gagal mengeluarkan anggota")
+		return
+	}
+
+	audit.Track(c, audit.Change{Action: "kelompok.remove_anggota", EntityType: "kelompok", EntityID: kelompok.ID, Before: member})
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Anggota berhasil dikeluarkan",
+	})
+}
+
+// SetKelompokKetua menjadikan salah satu anggota sebagai ketua kelompok
+func SetKelompokKetua(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	kelompok, ok := loadKelompok(c, db, policy.KelompokManage)
+	if !ok {
+		return
+	}
+
+	var req AnggotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id wajib diisi"})
+		return
+	}
+
+	var before model.KelompokKetua
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockKelompok(tx, kelompok.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("kelompok_id = ?", kelompok.ID).Limit(1).Find(&before).Error; err != nil {
+			return err
+		}
+		return setKetua(tx, kelompok.ID, req.UserID)
+	})
+	if err != nil {
+		respondKelompokError(c, err, "Gagal mengganti ketua kelompok")
+		return
+	}
+
+	audit.Track(c, audit.Change{
+		Action:     "kelompok.set_ketua",
+		EntityType: "kelompok",
+		EntityID:   kelompok.ID,
+		Before:     gin.H{"ketua_id": before.UserID},
+		After:      gin.H{"ketua_id": req.UserID},
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Ketua kelompok diperbarui",
+		"data":    gin.H{"kelompok_id": kelompok.ID, "ketua_id": req.UserID},
+	})
+}
@@ -0,0 +1,526 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/grouping"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	formasiDraft     = "draft"
+	formasiCommitted = "committed"
+	formasiDiscarded = "discarded"
+)
+
+// FormasiRequest adalah body untuk membuat usulan pembagian kelompok
+type FormasiRequest struct {
+	ProdiID uint `json:"prodi_id" binding:"required"`
+	KPAID   uint `json:"kpa_id" binding:"required"`
+	TMID    uint `json:"tm_id" binding:"required"`
+	grouping.Input
+}
+
+// formasiKelompok adalah satu kelompok dalam usulan
+type formasiKelompok struct {
+	NomorKelompok string `json:"nomor_kelompok"`
+	Anggota       []uint `json:"anggota"`
+}
+
+// FormasiUpdateRequest adalah body untuk mengubah pembagian dalam usulan
+type FormasiUpdateRequest struct {
+	Kelompok []formasiKelompok `json:"kelompok" binding:"required"`
+}
+
+func decodeFormasi(f model.KelompokFormasi) (grouping.Input, []formasiKelompok, error) {
+	var in grouping.Input
+	var groups []formasiKelompok
+	if err := json.Unmarshal(f.Input, &in); err != nil {
+		return in, nil, err
+	}
+	if err := json.Unmarshal(f.Kelompok, &groups); err != nil {
+		return in, nil, err
+	}
+	return in, groups, nil
+}
+
+func anggotaOf(groups []formasiKelompok) [][]uint {
+	out := make([][]uint, len(groups))
+	for i, g := range groups {
+		out[i] = g.Anggota
+	}
+	return out
+}
+
+// validateFormasi memeriksa pembagian terhadap batasan input, ukuran
+// maksimum kelompok dan nomor kelompok
+func validateFormasi(in grouping.Input, groups []formasiKelompok) []string {
+	violations := grouping.Validate(in, anggotaOf(groups), config.Kelompok.MaxAnggota)
+	seen := map[string]bool{}
+	for i, g := range groups {
+		nomor := strings.TrimSpace(g.NomorKelompok)
+		if nomor == "" {
+			violations = append(violations, fmt.Sprintf("kelompok %d belum memiliki nomor", i+1))
+			continue
+		}
+		if seen[nomor] {
+			violations = append(violations, fmt.Sprintf("nomor kelompok %s dipakai lebih dari sekali", nomor))
+		}
+		seen[nomor] = true
+	}
+	return violations
+}
+
+// formasiConflicts mencari mahasiswa yang tidak bisa dimasukkan ke kelompok
+// angkatan ini: bukan mahasiswa, atau sudah di kelompok aktif dengan
+// kategori PA yang sama. Aturan yang sama diperiksa lagi saat commit.
+func formasiConflicts(db *gorm.DB, in grouping.Input, kpaID uint) ([]string, error) {
+	ids := make([]uint, 0, len(in.Students))
+	for _, s := range in.Students {
+		ids = append(ids, s.UserID)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var conflicts []string
+	var users []model.User
+	if err := db.Where("id IN ? AND role <> ?", ids, policy.RoleMahasiswa).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		conflicts = append(conflicts, fmt.Sprintf("user %d bukan mahasiswa", u.ID))
+	}
+
+	var existing []struct {
+		UserID        uint
+		NomorKelompok string
+	}
+	if err := db.Table("kelompok_mahasiswa km").
+		Select("km.user_id, k.nomor_kelompok").
+		Joins("JOIN kelompok k ON k.id = km.kelompok_id").
+		Where("km.user_id IN ? AND k.KPA_id = ? AND k.status = ?", ids, kpaID, config.Kelompok.StatusAktif).
+		Scan(&existing).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range existing {
+		conflicts = append(conflicts, fmt.Sprintf("mahasiswa %d sudah tergabung di kelompok aktif %s", e.UserID, e.NomorKelompok))
+	}
+	return conflicts, nil
+}
+
+// nextNomorKelompok membuat n nomor kelompok berurutan yang belum dipakai
+// di angkatan tersebut
+func nextNomorKelompok(db *gorm.DB, prodiID, kpaID, tmID uint, n int) ([]string, error) {
+	var used []string
+	if err := db.Model(&model.Kelompok{}).
+		Where("prodi_id = ? AND KPA_id = ? AND TM_id = ?", prodiID, kpaID, tmID).
+		Pluck("nomor_kelompok", &used).Error; err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	for _, nomor := range used {
+		taken[strings.TrimSpace(nomor)] = true
+	}
+	out := make([]string, 0, n)
+	for i := 1; len(out) < n; i++ {
+		if nomor := strconv.Itoa(i); !taken[nomor] {
+			out = append(out, nomor)
+		}
+	}
+	return out, nil
+}
+
+// formasiPreview menyusun response usulan beserta ringkasan nilai tiap
+// kelompok dan pelanggaran batasan jika ada
+func formasiPreview(f model.KelompokFormasi) (gin.H, error) {
+	in, groups, err := decodeFormasi(f)
+	if err != nil {
+		return nil, err
+	}
+	nilai := map[uint]float64{}
+	var total float64
+	for _, s := range in.Students {
+		nilai[s.UserID] = s.Nilai
+		total += s.Nilai
+	}
+
+	summaries := grouping.Summarize(in, anggotaOf(groups))
+	kelompok := make([]gin.H, 0, len(groups))
+	for i, g := range groups {
+		anggota := make([]gin.H, 0, len(g.Anggota))
+		for _, id := range g.Anggota {
+			anggota = append(anggota, gin.H{"user_id": id, "nilai": nilai[id]})
+		}
+		kelompok = append(kelompok, gin.H{
+			"nomor_kelompok": g.NomorKelompok,
+			"anggota":        anggota,
+			"jumlah":         summaries[i].Jumlah,
+			"rata_rata":      summaries[i].RataRata,
+		})
+	}
+
+	var rataRata float64
+	if len(in.Students) > 0 {
+		rataRata = total / float64(len(in.Students))
+	}
+	return gin.H{
+		"id":           f.ID,
+		"status":       f.Status,
+		"prodi_id":     f.ProdiID,
+		"kpa_id":       f.KPAID,
+		"tm_id":        f.TMID,
+		"ukuran":       in.Size,
+		"bersama":      in.Together,
+		"pisah":        in.Apart,
+		"kelompok":     kelompok,
+		"pelanggaran":  validateFormasi(in, groups),
+		"created_by":   f.CreatedBy,
+		"committed_at": f.CommittedAt,
+		"ringkasan": gin.H{
+			"jumlah_mahasiswa": len(in.Students),
+			"jumlah_kelompok":  len(groups),
+			"rata_rata":        rataRata,
+		},
+	}, nil
+}
+
+// loadFormasi mengambil usulan dari parameter :id dan memeriksa cakupan
+// permission pengelola kelompok. Response error sudah ditulis jika ok false.
+func loadFormasi(c *gin.Context, db *gorm.DB) (f model.KelompokFormasi, ok bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid formasi ID"})
+		return f, false
+	}
+	if err := db.First(&f, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usulan kelompok tidak ditemukan"})
+		return f, false
+	}
+
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return f, false
+	}
+	allowed, err := subject.CanForCohort(db, policy.KelompokManage, f.ProdiID, f.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return f, false
+	}
+	if !allowed {
+		policy.Deny(c, policy.KelompokManage)
+		return f, false
+	}
+	return f, true
+}
+
+func respondFormasi(c *gin.Context, status int, message string, f model.KelompokFormasi) {
+	preview, err := formasiPreview(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Data usulan kelompok rusak"})
+		return
+	}
+	c.JSON(status, gin.H{
+		"status":  "success",
+		"message": message,
+		"data":    preview,
+	})
+}
+
+// CreateKelompokFormasi membuat usulan pembagian kelompok untuk satu
+// angkatan. Usulan disimpan sebagai draft dan belum mengubah tabel kelompok.
+func CreateKelompokFormasi(c *gin.Context) {
+	var req FormasiRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Size < 1 || req.Size > config.Kelompok.MaxAnggota {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ukuran kelompok harus antara 1 dan %d", config.Kelompok.MaxAnggota)})
+		return
+	}
+
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	allowed, err := subject.CanForCohort(db, policy.KelompokManage, req.ProdiID, req.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if !allowed {
+		policy.Deny(c, policy.KelompokManage)
+		return
+	}
+
+	conflicts, err := formasiConflicts(db, req.Input, req.KPAID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa keanggotaan mahasiswa"})
+		return
+	}
+	if len(conflicts) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Sebagian mahasiswa tidak dapat dikelompokkan", "pelanggaran": conflicts})
+		return
+	}
+
+	// Batas MaksProject diperiksa lebih awal agar koordinator tahu sebelum
+	// menyusun usulan; commit tetap memeriksa ulang
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return checkMaksProject(tx, req.ProdiID, req.KPAID, req.TMID, req.GroupCount())
+	}); err != nil {
+		respondKelompokError(c, err, "Gagal memeriksa batas kelompok prodi")
+		return
+	}
+
+	anggota, err := grouping.Form(req.Input)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	nomor, err := nextNomorKelompok(db, req.ProdiID, req.KPAID, req.TMID, len(anggota))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyusun nomor kelompok"})
+		return
+	}
+	groups := make([]formasiKelompok, len(anggota))
+	for i := range anggota {
+		groups[i] = formasiKelompok{NomorKelompok: nomor[i], Anggota: anggota[i]}
+	}
+
+	input, err := json.Marshal(req.Input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan usulan kelompok"})
+		return
+	}
+	kelompok, err := json.Marshal(groups)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan usulan kelompok"})
+		return
+	}
+	formasi := model.KelompokFormasi{
+		ProdiID:   req.ProdiID,
+		KPAID:     req.KPAID,
+		TMID:      req.TMID,
+		Status:    formasiDraft,
+		Input:     input,
+		Kelompok:  kelompok,
+		CreatedBy: c.GetUint("user_id"),
+	}
+	if err := db.Create(&formasi).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan usulan kelompok"})
+		return
+	}
+
+	respondFormasi(c, http.StatusCreated, "Usulan kelompok berhasil dibuat", formasi)
+}
+
+// GetKelompokFormasi menampilkan pratinjau usulan pembagian kelompok
+func GetKelompokFormasi(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	formasi, ok := loadFormasi(c, db)
+	if !ok {
+		return
+	}
+	respondFormasi(c, http.StatusOK, "Usulan kelompok ditemukan", formasi)
+}
+
+// UpdateKelompokFormasi mengganti pembagian dalam usulan, misalnya setelah
+// koordinator memindahkan mahasiswa atau mengganti nomor kelompok.
+// Pembagian yang melanggar batasan ditolak.
+func UpdateKelompokFormasi(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	formasi, ok := loadFormasi(c, db)
+	if !ok {
+		return
+	}
+	if formasi.Status != formasiDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "Usulan kelompok sudah " + formasi.Status})
+		return
+	}
+
+	var req FormasiUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	in, _, err := decodeFormasi(formasi)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Data usulan kelompok rusak"})
+		return
+	}
+	for i := range req.Kelompok {
+		req.Kelompok[i].NomorKelompok = strings.TrimSpace(req.Kelompok[i].NomorKelompok)
+	}
+	if violations := validateFormasi(in, req.Kelompok); len(violations) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Pembagian kelompok melanggar batasan", "pelanggaran": violations})
+		return
+	}
+
+	kelompok, err := json.Marshal(req.Kelompok)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan usulan kelompok"})
+		return
+	}
+	before := formasi
+	// Status ikut di kondisi agar usulan yang baru saja di-commit tidak ditimpa
+	result := db.Model(&formasi).Where("status = ?", formasiDraft).Update("kelompok", kelompok)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan usulan kelompok"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Usulan kelompok sudah tidak berstatus draft"})
+		return
+	}
+	formasi.Kelompok = kelompok
+	audit.Track(c, audit.Change{Action: "kelompok.formasi_update", EntityType: "kelompok_formasi", EntityID: formasi.ID, Before: before, After: formasi})
+
+	respondFormasi(c, http.StatusOK, "Usulan kelompok diperbarui", formasi)
+}
+
+// CommitKelompokFormasi menulis usulan ke tabel kelompok dan
+// kelompok_mahasiswa dalam satu transaksi. Jika satu kelompok gagal, tidak
+// ada kelompok yang dibuat.
+func CommitKelompokFormasi(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	formasi, ok := loadFormasi(c, db)
+	if !ok {
+		return
+	}
+
+	var created []model.Kelompok
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var locked model.KelompokFormasi
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, formasi.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status != formasiDraft {
+			return ruleErrorf("Usulan kelompok sudah %s", locked.Status)
+		}
+		in, groups, err := decodeFormasi(locked)
+		if err != nil {
+			return err
+		}
+		if violations := validateFormasi(in, groups); len(violations) > 0 {
+			return ruleErrorf("Pembagian kelompok melanggar batasan: %s", strings.Join(violations, "; "))
+		}
+		if err := checkMaksProject(tx, locked.ProdiID, locked.KPAID, locked.TMID, len(groups)); err != nil {
+			return err
+		}
+		// Kunci semua mahasiswa sekaligus dalam urutan id agar tidak deadlock
+		// dengan perubahan anggota lain yang berjalan bersamaan
+		semua := []uint{}
+		for _, g := range groups {
+			semua = append(semua, g.Anggota...)
+		}
+		if err := lockMahasiswa(tx, semua); err != nil {
+			return err
+		}
+
+		for _, g := range groups {
+			kelompok := model.Kelompok{
+				NomorKelompok: g.NomorKelompok,
+				ProdiID:       locked.ProdiID,
+				KPAID:         locked.KPAID,
+				TMID:          locked.TMID,
+				Status:        config.Kelompok.StatusAktif,
+			}
+			if err := insertKelompok(tx, &kelompok, g.Anggota); err != nil {
+				return err
+			}
+			created = append(created, kelompok)
+		}
+
+		now := time.Now()
+		formasi = locked
+		formasi.Status = formasiCommitted
+		formasi.CommittedBy = c.GetUint("user_id")
+		formasi.CommittedAt = &now
+		return tx.Model(&locked).Updates(map[string]interface{}{
+			"status":       formasi.Status,
+			"committed_by": formasi.CommittedBy,
+			"committed_at": now,
+		}).Error
+	})
+	if err != nil {
+		respondKelompokError(c, err, "Gagal menyimpan kelompok")
+		return
+	}
+
+	ids := make([]uint, 0, len(created))
+	for _, k := range created {
+		ids = append(ids, k.ID)
+	}
+	audit.Track(c, audit.Change{
+		Action:     "kelompok.formasi_commit",
+		EntityType: "kelompok_formasi",
+		EntityID:   formasi.ID,
+		Detail:     fmt.Sprintf("kelompok=%v", ids),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": fmt.Sprintf("%d kelompok berhasil dibuat", len(created)),
+		"data":    created,
+	})
+}
+
+// DeleteKelompokFormasi membatalkan usulan yang belum di-commit
+func DeleteKelompokFormasi(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	formasi, ok := loadFormasi(c, db)
+	if !ok {
+		return
+	}
+
+	result := db.Model(&formasi).Where("status = ?", formasiDraft).Update("status", formasiDiscarded)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membatalkan usulan kelompok"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Usulan kelompok sudah " + formasi.Status})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "kelompok.formasi_discard", EntityType: "kelompok_formasi", EntityID: formasi.ID})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Usulan kelompok dibatalkan",
+	})
+}
@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"github.com/rudychandra/lagi/storage"
+	"github.com/rudychandra/lagi/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errLampiranPenuh dikembalikan jika upload melebihi batas jumlah lampiran
+var errLampiranPenuh = errors.New("jumlah lampiran melebihi batas")
+
+// loadLampiran mengambil lampiran beberapa pengumuman atau tugas sesuai urutan
+func loadLampiran(db *gorm.DB, pemilik string, ids []uint) (map[uint][]model.Lampiran, error) {
+	result := map[uint][]model.Lampiran{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var rows []model.Lampiran
+	if err := db.Where("pemilik = ? AND pemilik_id IN ?", pemilik, ids).
+		Order("urutan, id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range rows {
+		result[l.PemilikID] = append(result[l.PemilikID], l)
+	}
+	return result, nil
+}
+
+// canManageTugas memeriksa apakah user boleh mengubah lampiran tugas:
+// dosen pembuat tugas atau pemegang tugas:manage_upload_rule pada cohort tugas
+func canManageTugas(db *gorm.DB, subject *policy.Subject, tugas model.Tugas) (bool, error) {
+	if tugas.UserID == subject.UserID {
+		return true, nil
+	}
+	return subject.CanForCohort(db, policy.TugasManageUploadRule, tugas.ProdiID, tugas.KPAID)
+}
+
+// loadLampiranOwner memuat pengumuman atau tugas dari parameter :id dan
+// memeriksa hak kelola user. Jika gagal, response error sudah ditulis.
+func loadLampiranOwner(c *gin.Context, db *gorm.DB, pemilik string) (uint, *policy.Subject, bool) {
+	if pemilik == model.LampiranPengumuman {
+		pengumuman, meta, subject, ok := loadManagedPengumuman(c, db)
+		if !ok {
+			return 0, nil, false
+		}
+		if meta.DeletedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Pengumuman sudah dihapus, pulihkan terlebih dahulu"})
+			return 0, nil, false
+		}
+		return pengumuman.ID, subject, true
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tugas ID"})
+		return 0, nil, false
+	}
+	var tugas model.Tugas
+	if err := db.First(&tugas, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tugas tidak ditemukan"})
+		return 0, nil, false
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return 0, nil, false
+	}
+	allowed, err := canManageTugas(db, subject, tugas)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return 0, nil, false
+	}
+	if !allowed {
+		policy.Deny(c, policy.TugasManageUploadRule)
+		return 0, nil, false
+	}
+	return tugas.ID, subject, true
+}
+
+// respondLampiran menulis daftar lampiran terbaru milik pengumuman atau tugas
+func respondLampiran(c *gin.Context, db *gorm.DB, pemilik string, pemilikID uint, status int) {
+	lampiran, err := loadLampiran(db, pemilik, []uint{pemilikID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil lampiran"})
+		return
+	}
+	data := lampiran[pemilikID]
+	if data == nil {
+		data = []model.Lampiran{}
+	}
+	c.JSON(status, gin.H{"status": "success", "data": data})
+}
+
+// lockLampiranOwner mengunci baris pengumuman atau tugas pemilik lampiran
+// sampai transaksi selesai, sehingga upload bersamaan ke pemilik yang sama
+// dihitung satu per satu
+func lockLampiranOwner(tx *gorm.DB, pemilik string, pemilikID uint) error {
+	locked := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if pemilik == model.LampiranPengumuman {
+		return locked.First(&model.Pengumuman{}, pemilikID).Error
+	}
+	return locked.First(&model.Tugas{}, pemilikID).Error
+}
+
+// insertLampiran menyimpan lampiran baru di akhir urutan. Jumlah dan urutan
+// terakhir dibaca setelah pemilik dikunci, jadi upload bersamaan tidak bisa
+// melewati MaxLampiran atau mendapat urutan yang sama, termasuk setelah ada
+// lampiran yang dihapus.
+func insertLampiran(db *gorm.DB, pemilik string, pemilikID uint, lampiran []model.Lampiran) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := lockLampiranOwner(tx, pemilik, pemilikID); err != nil {
+			return err
+		}
+		var current struct {
+			Jumlah int
+			Urutan int
+		}
+		if err := tx.Model(&model.Lampiran{}).
+			Select("COUNT(*) AS jumlah, COALESCE(MAX(urutan), 0) AS urutan").
+			Where("pemilik = ? AND pemilik_id = ?", pemilik, pemilikID).
+			Scan(&current).Error; err != nil {
+			return err
+		}
+		if current.Jumlah+len(lampiran) > config.Upload.MaxLampiran {
+			return errLampiranPenuh
+		}
+		for i := range lampiran {
+			lampiran[i].Urutan = current.Urutan + i + 1
+		}
+		return tx.Create(&lampiran).Error
+	})
+}
+
+// uploadLampiran menerima satu atau beberapa file pada field "files" (atau
+// "file") dengan nama tampilan opsional pada field "nama" sesuai urutan file.
+// Semua file divalidasi lebih dulu agar upload tidak tersimpan sebagian.
+func uploadLampiran(c *gin.Context, pemilik string) {
+	db := config.DB
+
+	pemilikID, subject, ok := loadLampiranOwner(c, db, pemilik)
+	if !ok {
+		return
+	}
+
+	if !limitRequestBody(c, config.Upload.MaxLampiranSize*int64(config.Upload.MaxLampiran)) {
+		return
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form upload tidak valid"})
+		return
+	}
+	files := append(form.File["files"], form.File["file"]...)
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tidak ada file yang diupload"})
+		return
+	}
+	names := form.Value["nama"]
+
+	// Pemeriksaan awal agar file tidak disimpan sia-sia; batas yang mengikat
+	// diperiksa lagi di insertLampiran
+	var existing int64
+	if err := db.Model(&model.Lampiran{}).Where("pemilik = ? AND pemilik_id = ?", pemilik, pemilikID).
+		Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil lampiran"})
+		return
+	}
+	if int(existing)+len(files) > config.Upload.MaxLampiran {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Maksimal %d lampiran", config.Upload.MaxLampiran)})
+		return
+	}
+
+	lampiran := make([]model.Lampiran, 0, len(files))
+	extensions := make([]string, 0, len(files))
+	for i, file := range files {
+		detected, ok := checkUpload(c, file, config.Upload.LampiranAllowedMIME, config.Upload.MaxLampiranSize)
+		if !ok {
+			return
+		}
+		nama := file.Filename
+		if i < len(names) && strings.TrimSpace(names[i]) != "" {
+			nama = strings.TrimSpace(names[i])
+		}
+		extensions = append(extensions, detected.Extension())
+		nama = truncate(nama, 255)
+		lampiran = append(lampiran, model.Lampiran{
+			Pemilik:    pemilik,
+			PemilikID:  pemilikID,
+			Nama:       nama,
+			Ukuran:     file.Size,
+			MIME:       detected.String(),
+			UploadedBy: subject.UserID,
+		})
+	}
+
+	// Simpan file ke storage; jika salah satu gagal, hapus yang sudah tersimpan
+	saved := []string{}
+	cleanup := func() {
+		for _, key := range saved {
+			if err := storage.Uploads.Delete(context.Background(), key); err != nil {
+				fmt.Printf("Failed to remove lampiran %s: %v\n", key, err)
+			}
+		}
+	}
+	for i, file := range files {
+		name := utils.ReplaceExt(utils.SanitizeFilename(file.Filename), extensions[i])
+		key := fmt.Sprintf("lampiran/%s/%d/%d_%d_%s", pemilik, pemilikID, time.Now().Unix(), i, name)
+		if err := saveUpload(c, storage.Uploads, key, file, lampiran[i].MIME); err != nil {
+			cleanup()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+			return
+		}
+		saved = append(saved, key)
+		lampiran[i].FilePath = storage.DBPath(key)
+	}
+
+	if err := insertLampiran(db, pemilik, pemilikID, lampiran); err != nil {
+		cleanup()
+		if errors.Is(err, errLampiranPenuh) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Maksimal %d lampiran", config.Upload.MaxLampiran)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan lampiran"})
+		return
+	}
+	audit.Track(c, audit.Change{
+		Action:     pemilik + ".lampiran_upload",
+		EntityType: pemilik,
+		EntityID:   pemilikID,
+		After:      lampiran,
+	})
+
+	respondLampiran(c, db, pemilik, pemilikID, http.StatusCreated)
+}
+
+// deleteLampiran menghapus satu lampiran beserta file-nya di storage
+func deleteLampiran(c *gin.Context, pemilik string) {
+	db := config.DB
+
+	pemilikID, _, ok := loadLampiranOwner(c, db, pemilik)
+	if !ok {
+		return
+	}
+
+	var lampiran model.Lampiran
+	if err := db.Where("id = ? AND pemilik = ? AND pemilik_id = ?", c.Param("lampiran_id"), pemilik, pemilikID).
+		First(&lampiran).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lampiran tidak ditemukan"})
+		return
+	}
+	if err := db.Delete(&lampiran).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus lampiran"})
+		return
+	}
+	// File dihapus setelah baris lampiran; file yang tertinggal tidak lagi
+	// bisa diakses karena tidak terpetakan ke pemilik mana pun
+	if err := storage.Uploads.Delete(c.Request.Context(), storage.KeyFromDBPath(lampiran.FilePath)); err != nil {
+		fmt.Printf("Failed to remove lampiran %s: %v\n", lampiran.FilePath, err)
+	}
+	audit.Track(c, audit.Change{
+		Action:     pemilik + ".lampiran_delete",
+		EntityType: pemilik,
+		EntityID:   pemilikID,
+		Before:     lampiran,
+	})
+
+	respondLampiran(c, db, pemilik, pemilikID, http.StatusOK)
+}
+
+// LampiranUrutanRequest berisi seluruh id lampiran dalam urutan baru
+type LampiranUrutanRequest struct {
+	Urutan []uint `json:"urutan" binding:"required"`
+}
+
+// reorderLampiran mengubah urutan lampiran. Request harus memuat semua id
+// lampiran milik pengumuman atau tugas tersebut tepat satu kali.
+func reorderLampiran(c *gin.Context, pemilik string) {
+	db := config.DB
+
+	pemilikID, _, ok := loadLampiranOwner(c, db, pemilik)
+	if !ok {
+		return
+	}
+	var req LampiranUrutanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format data tidak valid"})
+		return
+	}
+
+	var ids []uint
+	if err := db.Model(&model.Lampiran{}).Where("pemilik = ? AND pemilik_id = ?", pemilik, pemilikID).
+		Pluck("id", &ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil lampiran"})
+		return
+	}
+	known := map[uint]bool{}
+	for _, id := range ids {
+		known[id] = true
+	}
+	if len(req.Urutan) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Urutan harus memuat semua lampiran"})
+		return
+	}
+	for _, id := range req.Urutan {
+		if !known[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Lampiran " + strconv.FormatUint(uint64(id), 10) + " tidak ditemukan atau disebut lebih dari sekali"})
+			return
+		}
+		delete(known, id)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.Urutan {
+			if err := tx.Model(&model.Lampiran{}).Where("id = ?", id).Update("urutan", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan urutan lampiran"})
+		return
+	}
+	audit.Track(c, audit.Change{
+		Action:     pemilik + ".lampiran_reorder",
+		EntityType: pemilik,
+		EntityID:   pemilikID,
+		After:      req.Urutan,
+	})
+
+	respondLampiran(c, db, pemilik, pemilikID, http.StatusOK)
+}
+
+// UploadPengumumanLampiran menambahkan lampiran pengumuman (penulis atau koordinator)
+func UploadPengumumanLampiran(c *gin.Context) { uploadLampiran(c, model.LampiranPengumuman) }
+
+// DeletePengumumanLampiran menghapus lampiran pengumuman
+func DeletePengumumanLampiran(c *gin.Context) { deleteLampiran(c, model.LampiranPengumuman) }
+
+// ReorderPengumumanLampiran mengubah urutan lampiran pengumuman
+func ReorderPengumumanLampiran(c *gin.Context) { reorderLampiran(c, model.LampiranPengumuman) }
+
+// UploadTugasLampiran menambahkan lampiran tugas (pembuat tugas atau koordinator)
+func UploadTugasLampiran(c *gin.Context) { uploadLampiran(c, model.LampiranTugas) }
+
+// DeleteTugasLampiran menghapus lampiran tugas
+func DeleteTugasLampiran(c *gin.Context) { deleteLampiran(c, model.LampiranTugas) }
+
+// ReorderTugasLampiran mengubah urutan lampiran tugas
+func ReorderTugasLampiran(c *gin.Context) { reorderLampiran(c, model.LampiranTugas) }
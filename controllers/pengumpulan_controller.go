@@ -1,19 +1,109 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/storage"
+	"github.com/rudychandra/lagi/utils"
 )
 
-// GetSubmitanTugas retrieves assignments based on user's group
+// formatTugas formats a tugas, its attachments and the submission of the
+// given kelompok to match what the Flutter app expects
+func formatTugas(tugas model.Tugas, kelompokID uint, lampiran []model.Lampiran) map[string]interface{} {
+    // Check if there's a submission for this tugas by this kelompok
+    var submission map[string]interface{} = nil
+    if len(tugas.PengumpulanTugas) > 0 {
+        pengumpulan := tugas.PengumpulanTugas[0]
+        submission = map[string]interface{}{
+            "id": pengumpulan.ID,
+            "kelompok_id": pengumpulan.KelompokID,
+            "tugas_id": pengumpulan.TugasID,
+            "waktu_submit": pengumpulan.WaktuSubmit.Format(time.RFC3339),
+            "file_path": pengumpulan.FilePath,
+            "status": pengumpulan.Status,
+        }
+    }
+
+    // Process file path for Laravel storage if needed
+    filePath := tugas.File
+    if strings.HasPrefix(filePath, upstreams.LaravelTugasDir+"/") {
+        // This is a Laravel storage path, keep as is
+    } else if !strings.HasPrefix(filePath, "http") && filePath != "" {
+        // This is a local path, make it accessible via our file proxy
+        filePath = "uploads/" + filePath
+    }
+
+    // Replace ternary operator with if-else statements
+    var kategoriPA map[string]interface{}
+    if tugas.KategoriPA.ID > 0 {
+        kategoriPA = map[string]interface{}{
+            "id": tugas.KategoriPA.ID,
+            "kategori_pa": tugas.KategoriPA.KategoriPA,
+        }
+    } else {
+        kategoriPA = nil
+    }
+
+    var tahunMasuk map[string]interface{}
+    if tugas.TahunMasuk.ID > 0 {
+        tahunMasuk = map[string]interface{}{
+            "id": tugas.TahunMasuk.ID,
+            "tahun_masuk": tugas.TahunMasuk.TahunMasuk,
+            "status": tugas.TahunMasuk.Status,
+        }
+    } else {
+        tahunMasuk = nil
+    }
+
+    var pengumpulanTugas []map[string]interface{}
+    if submission != nil {
+        pengumpulanTugas = []map[string]interface{}{submission}
+    } else {
+        pengumpulanTugas = []map[string]interface{}{}
+    }
+
+    if lampiran == nil {
+        lampiran = []model.Lampiran{}
+    }
+
+    return map[string]interface{}{
+        "id": tugas.ID,
+        "judul_tugas": tugas.JudulTugas,
+        "deskripsi_tugas": tugas.DeskripsiTugas,
+        "tanggal_pengumpulan": tugas.TanggalPengumpulan.Format(time.RFC3339),
+        "file": filePath,
+        "user_id": tugas.UserID,
+        "status": tugas.Status,
+        "kategori_tugas": tugas.KategoriTugas,
+        "kpa_id": tugas.KPAID,
+        "prodi_id": tugas.ProdiID,
+        "TM_id": tugas.TMID,
+        "kelompok_id": kelompokID,
+        "prodi": map[string]interface{}{
+            "id": tugas.Prodi.ID,
+            "nama_prodi": tugas.Prodi.NamaProdi,
+        },
+        "kategori_pa": kategoriPA,
+        "tahun_masuk": tahunMasuk,
+        "pengumpulan_tugas": pengumpulanTugas,
+        "lampiran": lampiran,
+    }
+}
+
+// GetSubmitanTugas retrieves assignments for one of the user's groups.
+// Query kelompok_id selects the group, all=true returns tasks of every group;
+// otherwise the active group of the current tahun masuk is used.
 func GetSubmitanTugas(c *gin.Context) {
     // Get user_id from context
     userID, exists := c.Get("user_id")
@@ -22,8 +112,6 @@ func GetSubmitanTugas(c *gin.Context) {
         return
     }
 
-    fmt.Printf("User ID: %v\n", userID)
-
     // Get database connection with nil check
     db, err := config.GetDB()
     if err != nil {
@@ -31,12 +119,19 @@ func GetSubmitanTugas(c *gin.Context) {
         return
     }
 
-    // Find kelompok mahasiswa based on user_id WITH PRELOADED KELOMPOK
-    var km model.KelompokMahasiswa
-    if err := db.Debug().
-        Where("user_id = ?", userID).
-        Preload("Kelompok").
-        First(&km).Error; err != nil {
+    // Determine which kelompok to show tasks for
+    var kelompokList []model.Kelompok
+    if wantsAllKelompok(c) {
+        kelompokList, err = memberKelompok(db, userID)
+        if err == nil && len(kelompokList) == 0 {
+            err = errBelumBerkelompok
+        }
+    } else {
+        var kelompok model.Kelompok
+        kelompok, err = resolveKelompok(c, db, userID)
+        kelompokList = []model.Kelompok{kelompok}
+    }
+    if errors.Is(err, errBelumBerkelompok) {
         c.JSON(http.StatusOK, gin.H{
             "status": "success",
             "data": []interface{}{}, // Return empty array, not null
@@ -44,33 +139,44 @@ func GetSubmitanTugas(c *gin.Context) {
         })
         return
     }
+    if err != nil {
+        respondKelompokContextError(c, err)
+        return
+    }
 
-    fmt.Printf("Kelompok Mahasiswa: %+v\n", km)
-    fmt.Printf("Kelompok: %+v\n", km.Kelompok)
-
-    // Use the preloaded Kelompok
-    kelompok := km.Kelompok
+    response := []map[string]interface{}{}
+    for _, kelompok := range kelompokList {
+        // Query for tugas of the kelompok's prodi, tahun masuk and kategori PA
+        var tugasList []model.Tugas
+        if err := db.
+            Where("prodi_id = ? AND TM_id = ?", kelompok.ProdiID, kelompok.TMID).
+            Where("KPA_id = ? OR KPA_id IS NULL OR KPA_id = 0", kelompok.KPAID).
+            Preload("Prodi").
+            Preload("KategoriPA").
+            Preload("TahunMasuk").
+            Preload("PengumpulanTugas", "kelompok_id = ?", kelompok.ID).
+            Find(&tugasList).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
 
-    // Log the query parameters for debugging
-    fmt.Printf("Querying tugas with prodi_id = %d and TM_id = %d\n", kelompok.ProdiID, kelompok.TMID)
+        tugasIDs := make([]uint, 0, len(tugasList))
+        for _, tugas := range tugasList {
+            tugasIDs = append(tugasIDs, tugas.ID)
+        }
+        lampiran, err := loadLampiran(db, model.LampiranTugas, tugasIDs)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
 
-    // Query for tugas using the values from the user's kelompok
-    var tugasList []model.Tugas
-    if err := db.Debug().
-        Where("prodi_id = ? AND TM_id = ?", kelompok.ProdiID, kelompok.TMID).
-        Preload("Prodi").
-        Preload("KategoriPA").
-        Preload("TahunMasuk").
-        Preload("PengumpulanTugas", "kelompok_id = ?", km.KelompokID).
-        Find(&tugasList).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-        return
+        for _, tugas := range tugasList {
+            response = append(response, formatTugas(tugas, kelompok.ID, lampiran[tugas.ID]))
+        }
     }
 
-    fmt.Printf("Found %d tugas\n", len(tugasList))
-
     // If no tugas found, return empty array
-    if len(tugasList) == 0 {
+    if len(response) == 0 {
         c.JSON(http.StatusOK, gin.H{
             "status": "success",
             "data": []interface{}{}, // Return empty array, not null
@@ -79,84 +185,6 @@ func GetSubmitanTugas(c *gin.Context) {
         return
     }
 
-    // Format the response to match what the Flutter app expects
-    var response []map[string]interface{}
-    for _, tugas := range tugasList {
-        // Check if there's a submission for this tugas by this kelompok
-        var submission map[string]interface{} = nil
-        if len(tugas.PengumpulanTugas) > 0 {
-            pengumpulan := tugas.PengumpulanTugas[0]
-            submission = map[string]interface{}{
-                "id": pengumpulan.ID,
-                "kelompok_id": pengumpulan.KelompokID,
-                "tugas_id": pengumpulan.TugasID,
-                "waktu_submit": pengumpulan.WaktuSubmit.Format(time.RFC3339),
-                "file_path": pengumpulan.FilePath,
-                "status": pengumpulan.Status,
-            }
-        }
-
-        // Process file path for Laravel storage if needed
-        filePath := tugas.File
-        if strings.HasPrefix(filePath, "tugas_files/") {
-            // This is a Laravel storage path, keep as is
-        } else if !strings.HasPrefix(filePath, "http") && filePath != "" {
-            // This is a local path, make it accessible via our file proxy
-            filePath = "uploads/" + filePath
-        }
-
-        // Replace ternary operator with if-else statements
-        var kategoriPA map[string]interface{}
-        if tugas.KategoriPA.ID > 0 {
-            kategoriPA = map[string]interface{}{
-                "id": tugas.KategoriPA.ID,
-                "kategori_pa": tugas.KategoriPA.KategoriPA,
-            }
-        } else {
-            kategoriPA = nil
-        }
-
-        var tahunMasuk map[string]interface{}
-        if tugas.TahunMasuk.ID > 0 {
-            tahunMasuk = map[string]interface{}{
-                "id": tugas.TahunMasuk.ID,
-                "tahun_masuk": tugas.TahunMasuk.TahunMasuk,
-                "status": tugas.TahunMasuk.Status,
-            }
-        } else {
-            tahunMasuk = nil
-        }
-
-        var pengumpulanTugas []map[string]interface{}
-        if submission != nil {
-            pengumpulanTugas = []map[string]interface{}{submission}
-        } else {
-            pengumpulanTugas = []map[string]interface{}{}
-        }
-
-        item := map[string]interface{}{
-            "id": tugas.ID,
-            "judul_tugas": tugas.JudulTugas,
-            "deskripsi_tugas": tugas.DeskripsiTugas,
-            "tanggal_pengumpulan": tugas.TanggalPengumpulan.Format(time.RFC3339),
-            "file": filePath,
-            "user_id": tugas.UserID,
-            "status": tugas.Status,
-            "kategori_tugas": tugas.KategoriTugas,
-            "kpa_id": tugas.KPAID,
-            "prodi_id": tugas.ProdiID,
-            "TM_id": tugas.TMID,
-            "prodi": map[string]interface{}{
-                "id": tugas.Prodi.ID,
-                "nama_prodi": tugas.Prodi.NamaProdi,
-            },
-            "kategori_pa": kategoriPA,
-            "tahun_masuk": tahunMasuk,
-            "pengumpulan_tugas": pengumpulanTugas,
-        }
-        response = append(response, item)
-    }
-
     // Return the results
     c.JSON(http.StatusOK, gin.H{
         "message": "Submitan tugas ditemukan",
@@ -164,7 +192,8 @@ func GetSubmitanTugas(c *gin.Context) {
     })
 }
 
-// GetSubmitanTugasByID retrieves a specific assignment by ID
+// GetSubmitanTugasByID retrieves a specific assignment by ID together with
+// the submission of the user's kelompok for that assignment
 func GetSubmitanTugasByID(c *gin.Context) {
     // Get task ID from URL parameter
     tugasID := c.Param("id")
@@ -183,13 +212,6 @@ func GetSubmitanTugasByID(c *gin.Context) {
         return
     }
 
-    // Find kelompok mahasiswa based on user_id
-    var km model.KelompokMahasiswa
-    if err := db.Where("user_id = ?", userID).Preload("Kelompok").First(&km).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan untuk user"})
-        return
-    }
-
     // Get the specific tugas
     var tugas model.Tugas
     if err := db.
@@ -197,90 +219,38 @@ func GetSubmitanTugasByID(c *gin.Context) {
         Preload("Prodi").
         Preload("KategoriPA").
         Preload("TahunMasuk").
-        Preload("PengumpulanTugas", "kelompok_id = ?", km.KelompokID).
         First(&tugas).Error; err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Tugas tidak ditemukan"})
         return
     }
 
-    // Check if there's a submission for this tugas by this kelompok
-    var submission map[string]interface{} = nil
-    if len(tugas.PengumpulanTugas) > 0 {
-        pengumpulan := tugas.PengumpulanTugas[0]
-        submission = map[string]interface{}{
-            "id": pengumpulan.ID,
-            "kelompok_id": pengumpulan.KelompokID,
-            "tugas_id": pengumpulan.TugasID,
-            "waktu_submit": pengumpulan.WaktuSubmit.Format(time.RFC3339),
-            "file_path": pengumpulan.FilePath,
-            "status": pengumpulan.Status,
-        }
-    }
-
-    // Process file path for Laravel storage if needed
-    filePath := tugas.File
-    if strings.HasPrefix(filePath, "tugas_files/") {
-        // This is a Laravel storage path, keep as is
-    } else if !strings.HasPrefix(filePath, "http") && filePath != "" {
-        // This is a local path, make it accessible via our file proxy
-        filePath = "uploads/" + filePath
-    }
-
-    // Replace ternary operators with if-else statements
-    var kategoriPA map[string]interface{}
-    if tugas.KategoriPA.ID > 0 {
-        kategoriPA = map[string]interface{}{
-            "id": tugas.KategoriPA.ID,
-            "kategori_pa": tugas.KategoriPA.KategoriPA,
-        }
-    } else {
-        kategoriPA = nil
+    // Find the user's kelompok this tugas belongs to
+    kelompok, err := resolveKelompokForTugas(c, db, userID, tugas)
+    if errors.Is(err, errBelumBerkelompok) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan untuk user"})
+        return
     }
-
-    var tahunMasuk map[string]interface{}
-    if tugas.TahunMasuk.ID > 0 {
-        tahunMasuk = map[string]interface{}{
-            "id": tugas.TahunMasuk.ID,
-            "tahun_masuk": tugas.TahunMasuk.TahunMasuk,
-            "status": tugas.TahunMasuk.Status,
-        }
-    } else {
-        tahunMasuk = nil
+    if err != nil {
+        respondKelompokContextError(c, err)
+        return
     }
 
-    var pengumpulanTugas []map[string]interface{}
-    if submission != nil {
-        pengumpulanTugas = []map[string]interface{}{submission}
-    } else {
-        pengumpulanTugas = []map[string]interface{}{}
+    if err := db.Where("tugas_id = ? AND kelompok_id = ?", tugas.ID, kelompok.ID).
+        Find(&tugas.PengumpulanTugas).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
     }
 
-    // Format the response
-    response := map[string]interface{}{
-        "id": tugas.ID,
-        "judul_tugas": tugas.JudulTugas,
-        "deskripsi_tugas": tugas.DeskripsiTugas,
-        "tanggal_pengumpulan": tugas.TanggalPengumpulan.Format(time.RFC3339),
-        "file": filePath,
-        "user_id": tugas.UserID,
-        "status": tugas.Status,
-        "kategori_tugas": tugas.KategoriTugas,
-        "kpa_id": tugas.KPAID,
-        "prodi_id": tugas.ProdiID,
-        "TM_id": tugas.TMID,
-        "prodi": map[string]interface{}{
-            "id": tugas.Prodi.ID,
-            "nama_prodi": tugas.Prodi.NamaProdi,
-        },
-        "kategori_pa": kategoriPA,
-        "tahun_masuk": tahunMasuk,
-        "pengumpulan_tugas": pengumpulanTugas,
+    lampiran, err := loadLampiran(db, model.LampiranTugas, []uint{tugas.ID})
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
     }
 
     // Return the result
     c.JSON(http.StatusOK, gin.H{
         "message": "Detail tugas ditemukan",
-        "data": response,
+        "data": formatTugas(tugas, kelompok.ID, lampiran[tugas.ID]),
     })
 }
 
@@ -308,10 +278,15 @@ func UpdateUploadFileTugas(c *gin.Context) {
         return
     }
 
-    // Find kelompok mahasiswa based on user_id
-    var km model.KelompokMahasiswa
-    if err := db.Where("user_id = ?", userID).First(&km).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan untuk user"})
+    var tugas model.Tugas
+    if err := db.First(&tugas, tugasID).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Tugas tidak ditemukan"})
+        return
+    }
+
+    // Resolve upload limits for this tugas (falls back to config defaults)
+    allowedMIME, maxSize := tugasUploadLimits(db, uint(tugasID))
+    if !limitRequestBody(c, maxSize) {
         return
     }
 
@@ -322,24 +297,40 @@ func UpdateUploadFileTugas(c *gin.Context) {
         return
     }
 
-    // Validate file size
-    if file.Size > 100*1024*1024 { // 100MB limit
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Ukuran file maksimal 100MB"})
+    // Find the kelompok submitting; kelompok_id may come from the form,
+    // which is only read after the body limit above is in place
+    kelompok, err := resolveKelompokForTugas(c, db, userID, tugas)
+    if errors.Is(err, errBelumBerkelompok) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan untuk user"})
+        return
+    }
+    if err != nil {
+        respondKelompokContextError(c, err)
+        return
+    }
+
+    // Validate size and content, then scan the file
+    detected, ok := checkUpload(c, file, allowedMIME, maxSize)
+    if !ok {
         return
     }
 
     // Check if submission already exists
     var pengumpulan model.PengumpulanTugas
-    result := db.Where("kelompok_id = ? AND tugas_id = ?", km.KelompokID, tugasID).First(&pengumpulan)
+    result := db.Where("kelompok_id = ? AND tugas_id = ?", kelompok.ID, tugasID).First(&pengumpulan)
     
-    // Generate unique filename
+    // Generate unique filename, using the extension of the detected content type
     timestamp := time.Now().Unix()
-    ext := filepath.Ext(file.Filename)
-    filename := fmt.Sprintf("tugas_%d_%d_%d%s", km.KelompokID, tugasID, timestamp, ext)
-    filePath := filepath.Join("uploads", "tugas", filename)
+    ext := detected.Extension()
+    if ext == "" {
+        ext = filepath.Ext(utils.SanitizeFilename(file.Filename))
+    }
+    filename := fmt.Sprintf("tugas_%d_%d_%d%s", kelompok.ID, tugasID, timestamp, ext)
+    fileKey := path.Join("tugas", filename)
+    filePath := storage.DBPath(fileKey)
 
-    // Save file to disk
-    if err := c.SaveUploadedFile(file, filePath); err != nil {
+    // Save file to upload storage
+    if err := saveUpload(c, storage.Uploads, fileKey, file, detected.String()); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan file"})
         return
     }
@@ -347,7 +338,7 @@ func UpdateUploadFileTugas(c *gin.Context) {
     if result.Error != nil {
         // Create new submission if it doesn't exist
         newPengumpulan := model.PengumpulanTugas{
-            KelompokID:  km.KelompokID,
+            KelompokID:  kelompok.ID,
             TugasID:     uint(tugasID),
             WaktuSubmit: time.Now(),
             FilePath:    filePath,
@@ -358,6 +349,7 @@ func UpdateUploadFileTugas(c *gin.Context) {
             c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan pengumpulan tugas"})
             return
         }
+        audit.Track(c, audit.Change{Action: "pengumpulan.create", EntityType: "pengumpulan_tugas", EntityID: newPengumpulan.ID, After: newPengumpulan})
 
         c.JSON(http.StatusOK, gin.H{
             "message": "File tugas berhasil dikumpulkan",
@@ -365,6 +357,7 @@ func UpdateUploadFileTugas(c *gin.Context) {
         })
     } else {
         // Update existing submission
+        before := pengumpulan
         pengumpulan.FilePath = filePath
         pengumpulan.WaktuSubmit = time.Now()
         pengumpulan.Status = "Resubmitted"
@@ -373,6 +366,7 @@ func UpdateUploadFileTugas(c *gin.Context) {
             c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui pengumpulan tugas"})
             return
         }
+        audit.Track(c, audit.Change{Action: "pengumpulan.update", EntityType: "pengumpulan_tugas", EntityID: pengumpulan.ID, Before: before, After: pengumpulan})
 
         c.JSON(http.StatusOK, gin.H{
             "message": "File tugas berhasil diperbarui",
@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/announcement"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"github.com/rudychandra/lagi/utils"
+	"gorm.io/gorm"
+)
+
+// pengumumanResponse adalah pengumuman beserta meta dan targetnya. Field
+// pengumuman tetap di level atas agar client lama tidak berubah.
+// DeskripsiHTML adalah deskripsi yang sudah dirender dan aman ditampilkan.
+type pengumumanResponse struct {
+	model.Pengumuman
+	DeskripsiHTML string                   `json:"deskripsi_html"`
+	Meta          model.PengumumanMeta     `json:"meta"`
+	Target        []model.PengumumanTarget `json:"target"`
+	Lampiran      []model.Lampiran         `json:"lampiran"`
+	// Baca adalah status baca user yang sedang login
+	Baca *model.PengumumanBaca `json:"baca,omitempty"`
+}
+
+// defaultPengumumanMeta adalah meta untuk pengumuman yang belum punya baris
+// pengumuman_meta, misalnya yang dibuat dari Laravel
+func defaultPengumumanMeta(pengumumanID uint) model.PengumumanMeta {
+	return model.PengumumanMeta{PengumumanID: pengumumanID, Format: model.FormatTeks, Prioritas: model.PrioritasNormal}
+}
+
+func newPengumumanResponse(p model.Pengumuman, meta *model.PengumumanMeta, targets []model.PengumumanTarget) pengumumanResponse {
+	resp := pengumumanResponse{Pengumuman: p, Meta: defaultPengumumanMeta(p.ID), Target: targets}
+	if meta != nil {
+		resp.Meta = *meta
+	}
+	if resp.Target == nil {
+		resp.Target = []model.PengumumanTarget{}
+	}
+	resp.Lampiran = []model.Lampiran{}
+	resp.DeskripsiHTML = utils.RenderPlainText(p.Deskripsi)
+	if resp.Meta.Format == model.FormatMarkdown {
+		if rendered, err := utils.RenderMarkdown(p.Deskripsi); err == nil {
+			resp.DeskripsiHTML = rendered
+		}
+	}
+	return resp
+}
+
+// pengumumanOrder mengurutkan pengumuman yang disematkan lebih dulu, lalu
+// prioritas tertinggi, lalu yang terbaru. Query harus menggabungkan
+// pengumuman_meta dengan alias pm.
+const pengumumanOrder = "COALESCE(pm.pinned, 0) DESC, " +
+	"CASE pm.prioritas WHEN 'darurat' THEN 3 WHEN 'tinggi' THEN 2 WHEN 'rendah' THEN 0 ELSE 1 END DESC, " +
+	"pengumuman.tanggal_penulisan DESC, pengumuman.id DESC"
+
+// sqlCond adalah potongan klausa WHERE beserta argumennya
+type sqlCond struct {
+	sql  string
+	args []interface{}
+}
+
+var (
+	condTrue  = sqlCond{sql: "1 = 1"}
+	condFalse = sqlCond{sql: "1 = 0"}
+)
+
+func joinConds(op string, conds []sqlCond, empty sqlCond) sqlCond {
+	if len(conds) == 0 {
+		return empty
+	}
+	parts := make([]string, 0, len(conds))
+	args := []interface{}{}
+	for _, c := range conds {
+		parts = append(parts, "("+c.sql+")")
+		args = append(args, c.args...)
+	}
+	return sqlCond{sql: strings.Join(parts, " "+op+" "), args: args}
+}
+
+func orConds(conds ...sqlCond) sqlCond  { return joinConds("OR", conds, condFalse) }
+func andConds(conds ...sqlCond) sqlCond { return joinConds("AND", conds, condTrue) }
+
+// inIDs membatasi kolom ke daftar id; nil berarti semua
+func inIDs(column string, ids []uint) sqlCond {
+	if ids == nil {
+		return condTrue
+	}
+	if len(ids) == 0 {
+		return condFalse
+	}
+	return sqlCond{sql: column + " IN ?", args: []interface{}{ids}}
+}
+
+// scopeCond mencocokkan kolom prodi dan kategori PA dengan salah satu
+// cakupan permission
+func scopeCond(scopes []policy.CohortScope, prodiCol, kpaCol string) sqlCond {
+	conds := make([]sqlCond, 0, len(scopes))
+	for _, s := range scopes {
+		conds = append(conds, andConds(inIDs(prodiCol, s.ProdiIDs), inIDs(kpaCol, s.KPAIDs)))
+	}
+	return orConds(conds...)
+}
+
+// Subquery target pengumuman; alias pt dipakai kondisi di dalamnya
+const (
+	targetExists    = "EXISTS (SELECT 1 FROM pengumuman_target pt WHERE pt.pengumuman_id = pengumuman.id AND (%s))"
+	targetNotExists = "NOT EXISTS (SELECT 1 FROM pengumuman_target pt WHERE pt.pengumuman_id = pengumuman.id)"
+)
+
+func existsTarget(cond sqlCond) sqlCond {
+	return sqlCond{sql: fmt.Sprintf(targetExists, cond.sql), args: cond.args}
+}
+
+// manageCond adalah kondisi SQL pengumuman yang boleh diubah, dihapus atau
+// dipulihkan user: penulisnya sendiri, atau koordinator yang cakupan
+// PengumumanManage-nya meliputi seluruh sasaran pengumuman. Sasaran diambil
+// dari target pengumuman; pengumuman tanpa target memakai kolom prodi dan
+// kategori PA-nya. Target role tidak punya cohort sehingga hanya bisa
+// dikelola pemegang permission global.
+func manageCond(db *gorm.DB, subject *policy.Subject) (sqlCond, error) {
+	author := sqlCond{sql: "pengumuman.user_id = ?", args: []interface{}{subject.UserID}}
+	if subject.CanGlobal(policy.PengumumanManage) {
+		return condTrue, nil
+	}
+	scopes, err := subject.CohortScopes(db, policy.PengumumanManage)
+	if err != nil || len(scopes) == 0 {
+		return author, err
+	}
+
+	covered := orConds(
+		andConds(sqlCond{sql: "pt.tipe = ?", args: []interface{}{model.TargetCohort}},
+			scopeCond(scopes, "pt.prodi_id", "pt.KPA_id")),
+		andConds(sqlCond{sql: "pt.tipe = ?", args: []interface{}{model.TargetKelompok}},
+			kelompokInCond("pt.kelompok_id", scopeCond(scopes, "k.prodi_id", "k.KPA_id"))),
+	)
+	notCovered := sqlCond{sql: "NOT (" + covered.sql + ")", args: covered.args}
+	targeted := andConds(existsTarget(condTrue),
+		sqlCond{sql: "NOT " + existsTarget(notCovered).sql, args: notCovered.args})
+	untargeted := andConds(sqlCond{sql: targetNotExists},
+		scopeCond(scopes, "pengumuman.prodi_id", "pengumuman.KPA_id"))
+	return orConds(author, targeted, untargeted), nil
+}
+
+// kelompokInCond membatasi kolom kelompok_id ke kelompok yang memenuhi
+// kondisi pada alias k
+func kelompokInCond(column string, cond sqlCond) sqlCond {
+	return sqlCond{sql: column + " IN (SELECT k.id FROM kelompok k WHERE " + cond.sql + ")", args: cond.args}
+}
+
+// canManagePengumuman memeriksa apakah user boleh mengelola satu pengumuman
+// (lihat manageCond)
+func canManagePengumuman(db *gorm.DB, subject *policy.Subject, p model.Pengumuman) (bool, error) {
+	if p.UserID == subject.UserID {
+		return true, nil
+	}
+	cond, err := manageCond(db, subject)
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = db.Model(&model.Pengumuman{}).Where("pengumuman.id = ?", p.ID).
+		Where(cond.sql, cond.args...).Count(&count).Error
+	return count > 0, err
+}
+
+// pengumumanAudience adalah keanggotaan user yang menentukan pengumuman
+// mana yang terlihat olehnya
+type pengumumanAudience struct {
+	db       *gorm.DB
+	subject  *policy.Subject
+	kelompok []model.Kelompok
+	// visible adalah kondisi SQL hasil visibleCond, dihitung sekali per request
+	visible *sqlCond
+}
+
+// loadAudience mengumpulkan kelompok dan role dosen user yang sedang login
+func loadAudience(c *gin.Context, db *gorm.DB) (*pengumumanAudience, error) {
+	subject, err := policy.Load(c)
+	if err != nil {
+		return nil, err
+	}
+	kelompok, err := memberKelompok(db, subject.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &pengumumanAudience{db: db, subject: subject, kelompok: kelompok}, nil
+}
+
+// cohortCond mencocokkan kolom cohort dengan user. Mahasiswa cocok jika
+// salah satu kelompoknya berada di cohort tersebut; dosen cocok jika cohort
+// termasuk cakupan KelompokView-nya. Nilai 0 pada kolom berarti semua.
+func (a *pengumumanAudience) cohortCond(scopes []policy.CohortScope, prodiCol, kpaCol, tmCol string) sqlCond {
+	if a.subject.CanGlobal(policy.KelompokView) {
+		return condTrue
+	}
+	conds := []sqlCond{{sql: prodiCol + " = 0 AND " + kpaCol + " = 0 AND " + tmCol + " = 0"}}
+	for _, k := range a.kelompok {
+		conds = append(conds, sqlCond{
+			sql:  prodiCol + " IN (0, ?) AND " + kpaCol + " IN (0, ?) AND " + tmCol + " IN (0, ?)",
+			args: []interface{}{k.ProdiID, k.KPAID, k.TMID},
+		})
+	}
+	for _, s := range scopes {
+		conds = append(conds, andConds(
+			orConds(sqlCond{sql: prodiCol + " = 0"}, inIDs(prodiCol, s.ProdiIDs)),
+			orConds(sqlCond{sql: kpaCol + " = 0"}, inIDs(kpaCol, s.KPAIDs)),
+		))
+	}
+	return orConds(conds...)
+}
+
+// visibleCond adalah kondisi SQL pengumuman yang ditujukan untuk user,
+// dipakai untuk daftar, pencarian dan pemeriksaan satu pengumuman agar
+// aturannya sama di semua tempat. Penulis dan admin selalu bisa melihat.
+// Pengumuman tanpa target (misalnya dibuat dari Laravel) memakai kolom
+// prodi, kategori PA dan tahun masuknya sebagai target, kecuali untuk dosen
+// yang tetap melihat semua pengumuman tanpa target seperti sebelumnya.
+func (a *pengumumanAudience) visibleCond() (sqlCond, error) {
+	if a.visible != nil {
+		return *a.visible, nil
+	}
+	if a.subject.Admin {
+		a.visible = &condTrue
+		return condTrue, nil
+	}
+	scopes, err := a.subject.CohortScopes(a.db, policy.KelompokView)
+	if err != nil {
+		return sqlCond{}, err
+	}
+
+	untargeted := sqlCond{sql: targetNotExists}
+	if !strings.EqualFold(a.subject.Role, policy.RoleDosen) {
+		untargeted = andConds(untargeted,
+			a.cohortCond(scopes, "pengumuman.prodi_id", "pengumuman.KPA_id", "pengumuman.TM_id"))
+	}
+
+	// Target kelompok: kelompok user sendiri atau kelompok dalam cakupan KelompokView
+	kelompok := condTrue
+	if !a.subject.CanGlobal(policy.KelompokView) {
+		ids := make([]uint, 0, len(a.kelompok))
+		for _, k := range a.kelompok {
+			ids = append(ids, k.ID)
+		}
+		kelompok = inIDs("pt.kelompok_id", ids)
+		if len(scopes) > 0 {
+			kelompok = orConds(kelompok, kelompokInCond("pt.kelompok_id", scopeCond(scopes, "k.prodi_id", "k.KPA_id")))
+		}
+	}
+
+	// Target role: role token, atau awal nama role dosen (misalnya target
+	// "pembimbing" cocok dengan "Pembimbing 1")
+	roles := []sqlCond{{sql: "LOWER(TRIM(pt.role)) = ?", args: []interface{}{strings.ToLower(a.subject.Role)}}}
+	for _, dr := range a.subject.DosenRoles {
+		name := strings.ToLower(strings.TrimSpace(dr.NamaRole))
+		if name == "" {
+			continue
+		}
+		roles = append(roles, sqlCond{sql: "TRIM(pt.role) <> '' AND ? LIKE CONCAT(LOWER(TRIM(pt.role)), '%')", args: []interface{}{name}})
+	}
+
+	targeted := existsTarget(orConds(
+		andConds(sqlCond{sql: "pt.tipe = ?", args: []interface{}{model.TargetCohort}},
+			a.cohortCond(scopes, "pt.prodi_id", "pt.KPA_id", "pt.TM_id")),
+		andConds(sqlCond{sql: "pt.tipe = ?", args: []interface{}{model.TargetKelompok}}, kelompok),
+		andConds(sqlCond{sql: "pt.tipe = ?", args: []interface{}{model.TargetRole}}, orConds(roles...)),
+	))
+
+	cond := orConds(
+		sqlCond{sql: "pengumuman.user_id = ?", args: []interface{}{a.subject.UserID}},
+		untargeted,
+		targeted,
+	)
+	a.visible = &cond
+	return cond, nil
+}
+
+// filterVisible membatasi query pengumuman ke yang ditujukan untuk user
+func (a *pengumumanAudience) filterVisible(query *gorm.DB) (*gorm.DB, error) {
+	cond, err := a.visibleCond()
+	if err != nil {
+		return nil, err
+	}
+	return query.Where(cond.sql, cond.args...), nil
+}
+
+// canOpen memeriksa apakah user boleh membuka satu pengumuman. Pengumuman
+// terhapus hanya untuk yang boleh memulihkannya; penulis dan admin juga bisa
+// membuka pengumuman yang belum terbit atau sudah kadaluarsa.
+func (a *pengumumanAudience) canOpen(p model.Pengumuman, meta *model.PengumumanMeta) (bool, error) {
+	if meta != nil && meta.DeletedAt != nil {
+		return canManagePengumuman(a.db, a.subject, p)
+	}
+	if p.UserID == a.subject.UserID || a.subject.Admin {
+		return true, nil
+	}
+	query, err := a.filterVisible(publishedPengumuman(a.db, time.Now()).Where("pengumuman.id = ?", p.ID))
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = query.Count(&count).Error
+	return count > 0, err
+}
+
+// publishedPengumuman membatasi query ke pengumuman aktif yang sudah terbit
+// dan belum kadaluarsa atau dihapus. Jadwal juga diperiksa di sini agar pengumuman tidak
+// terlihat terlalu awal atau terlalu lama saat scheduler terlambat.
+func publishedPengumuman(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Model(&model.Pengumuman{}).
+		Joins("LEFT JOIN pengumuman_meta pm ON pm.pengumuman_id = pengumuman.id").
+		Where("pengumuman.status = ?", announcement.StatusAktif).
+		Where("pm.publish_at IS NULL OR pm.publish_at <= ?", now).
+		Where("pm.expire_at IS NULL OR pm.expire_at > ?", now).
+		Where("pm.deleted_at IS NULL")
+}
+
+// loadPengumumanExtras mengambil jadwal dan target untuk daftar pengumuman
+func loadPengumumanExtras(db *gorm.DB, ids []uint) (map[uint]*model.PengumumanMeta, map[uint][]model.PengumumanTarget, error) {
+	metas := map[uint]*model.PengumumanMeta{}
+	targets := map[uint][]model.PengumumanTarget{}
+	if len(ids) == 0 {
+		return metas, targets, nil
+	}
+
+	var metaRows []model.PengumumanMeta
+	if err := db.Where("pengumuman_id IN ?", ids).Find(&metaRows).Error; err != nil {
+		return nil, nil, err
+	}
+	for i := range metaRows {
+		metas[metaRows[i].PengumumanID] = &metaRows[i]
+	}
+
+	var targetRows []model.PengumumanTarget
+	if err := db.Where("pengumuman_id IN ?", ids).Order("id").Find(&targetRows).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, t := range targetRows {
+		targets[t.PengumumanID] = append(targets[t.PengumumanID], t)
+	}
+	return metas, targets, nil
+}
+
+// parsePengumumanTargets membaca field form "targets" berisi JSON array
+// target dan memvalidasinya
+func parsePengumumanTargets(raw string) ([]model.PengumumanTarget, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var targets []model.PengumumanTarget
+	if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+		return nil, fmt.Errorf("format targets tidak valid: %v", err)
+	}
+	for i, t := range targets {
+		targets[i].ID = 0
+		targets[i].PengumumanID = 0
+		switch t.Tipe {
+		case model.TargetCohort:
+			if t.ProdiID == 0 && t.KPAID == 0 && t.TMID == 0 {
+				return nil, fmt.Errorf("target cohort ke-%d harus berisi prodi_id, kpa_id atau tm_id", i+1)
+			}
+		case model.TargetKelompok:
+			if t.KelompokID == 0 {
+				return nil, fmt.Errorf("target kelompok ke-%d harus berisi kelompok_id", i+1)
+			}
+		case model.TargetRole:
+			targets[i].Role = strings.TrimSpace(t.Role)
+			if targets[i].Role == "" {
+				return nil, fmt.Errorf("target role ke-%d harus berisi role", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("tipe target ke-%d harus cohort, kelompok atau role", i+1)
+		}
+	}
+	return targets, nil
+}
+
+// parsePengumumanFormat memvalidasi format isi; kosong berarti teks biasa
+func parsePengumumanFormat(raw string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(raw)); f {
+	case "":
+		return model.FormatTeks, nil
+	case model.FormatTeks, model.FormatMarkdown:
+		return f, nil
+	}
+	return "", fmt.Errorf("format harus %s atau %s", model.FormatTeks, model.FormatMarkdown)
+}
+
+// parsePrioritas memvalidasi tingkat prioritas; kosong berarti normal
+func parsePrioritas(raw string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(raw)); p {
+	case "":
+		return model.PrioritasNormal, nil
+	case model.PrioritasRendah, model.PrioritasNormal, model.PrioritasTinggi, model.PrioritasDarurat:
+		return p, nil
+	}
+	return "", fmt.Errorf("prioritas harus rendah, normal, tinggi atau darurat")
+}
+
+// parseJadwalTime membaca waktu RFC3339 dari form; kosong berarti tidak diatur
+func parseJadwalTime(raw, field string) (*time.Time, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s harus dalam format RFC3339, contoh 2025-01-31T08:00:00+07:00", field)
+	}
+	return &t, nil
+}
@@ -0,0 +1,604 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/announcement"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordPengumumanRead mencatat bacaan pertama user; bacaan berikutnya tidak
+// mengubah waktu baca
+func recordPengumumanRead(db *gorm.DB, pengumumanID, userID uint) (*model.PengumumanBaca, error) {
+	baca := model.PengumumanBaca{PengumumanID: pengumumanID, UserID: userID, ReadAt: time.Now()}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&baca).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("pengumuman_id = ? AND user_id = ?", pengumumanID, userID).First(&baca).Error; err != nil {
+		return nil, err
+	}
+	return &baca, nil
+}
+
+// loadPengumumanReads mengambil status baca satu user untuk daftar pengumuman
+func loadPengumumanReads(db *gorm.DB, userID uint, ids []uint) (map[uint]*model.PengumumanBaca, error) {
+	reads := map[uint]*model.PengumumanBaca{}
+	if len(ids) == 0 {
+		return reads, nil
+	}
+	var rows []model.PengumumanBaca
+	if err := db.Where("user_id = ? AND pengumuman_id IN ?", userID, ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		reads[rows[i].PengumumanID] = &rows[i]
+	}
+	return reads, nil
+}
+
+// penerimaKelompok adalah penerima pengumuman yang tergabung di satu
+// kelompok aktif
+type penerimaKelompok struct {
+	Kelompok model.Kelompok
+	Anggota  []uint
+}
+
+// penerimaPengumuman adalah seluruh penerima pengumuman. Penerima yang tidak
+// tergabung di kelompok aktif, misalnya dosen atau mahasiswa yang belum
+// berkelompok, dikumpulkan di Lainnya.
+type penerimaPengumuman struct {
+	Kelompok []penerimaKelompok
+	Lainnya  []uint
+	Users    map[uint]model.User
+}
+
+// penerimaBatch adalah jumlah kandidat penerima yang diperiksa per query
+const penerimaBatch = 200
+
+// visibleAmong mengembalikan kandidat yang termasuk sasaran pengumuman.
+// Kondisi setiap kandidat sama dengan visibleCond yang dipakai daftar
+// pengumuman, sehingga penerima statistik selalu sama dengan yang bisa
+// melihat pengumuman tersebut.
+func visibleAmong(db *gorm.DB, pengumumanID uint, candidates []*pengumumanAudience) ([]uint, error) {
+	visible := []uint{}
+	for start := 0; start < len(candidates); start += penerimaBatch {
+		end := start + penerimaBatch
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		parts := make([]string, 0, end-start)
+		args := []interface{}{}
+		for _, a := range candidates[start:end] {
+			cond, err := a.visibleCond()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, "SELECT ? AS user_id FROM pengumuman WHERE pengumuman.id = ? AND ("+cond.sql+")")
+			args = append(args, a.subject.UserID, pengumumanID)
+			args = append(args, cond.args...)
+		}
+		var ids []uint
+		if err := db.Raw(strings.Join(parts, " UNION ALL "), args...).Scan(&ids).Error; err != nil {
+			return nil, err
+		}
+		visible = append(visible, ids...)
+	}
+	return visible, nil
+}
+
+// cohortKelompokCond mencocokkan kelompok dengan cohort sasaran. Nilai 0
+// berarti semua; semua=true jika cohort tidak dibatasi sama sekali.
+func cohortKelompokCond(prodiID, kpaID, tmID uint) (cond sqlCond, semua bool) {
+	conds := []sqlCond{}
+	if prodiID != 0 {
+		conds = append(conds, sqlCond{sql: "prodi_id = ?", args: []interface{}{prodiID}})
+	}
+	if kpaID != 0 {
+		conds = append(conds, sqlCond{sql: "KPA_id = ?", args: []interface{}{kpaID}})
+	}
+	if tmID != 0 {
+		conds = append(conds, sqlCond{sql: "TM_id = ?", args: []interface{}{tmID}})
+	}
+	return andConds(conds...), len(conds) == 0
+}
+
+// kelompokSasaran mengembalikan kondisi kelompok yang anggotanya mungkin
+// menjadi sasaran pengumuman. Mahasiswa hanya melihat pengumuman lewat
+// kelompoknya, kecuali cohort sasaran tidak dibatasi atau target role
+// mahasiswa; untuk itu semua=true dan seluruh mahasiswa menjadi kandidat.
+func kelompokSasaran(p model.Pengumuman, targets []model.PengumumanTarget) (cond sqlCond, semua bool) {
+	if len(targets) == 0 {
+		return cohortKelompokCond(p.ProdiID, p.KPAID, p.TMID)
+	}
+	conds := []sqlCond{}
+	for _, t := range targets {
+		switch t.Tipe {
+		case model.TargetCohort:
+			c, all := cohortKelompokCond(t.ProdiID, t.KPAID, t.TMID)
+			if all {
+				return condTrue, true
+			}
+			conds = append(conds, c)
+		case model.TargetKelompok:
+			conds = append(conds, sqlCond{sql: "id = ?", args: []interface{}{t.KelompokID}})
+		case model.TargetRole:
+			if strings.EqualFold(strings.TrimSpace(t.Role), policy.RoleMahasiswa) {
+				return condTrue, true
+			}
+		}
+	}
+	return orConds(conds...), false
+}
+
+// loadPenerima menentukan penerima pengumuman dengan aturan audience yang
+// sama seperti daftar pengumuman. Kandidatnya adalah user non-mahasiswa,
+// pemegang dosen_roles, dan mahasiswa dari kelompok yang cocok dengan target
+// pengumuman (semua mahasiswa jika targetnya tidak dibatasi). Penulis dan
+// admin tidak dihitung karena mereka melihat semua pengumuman.
+func loadPenerima(db *gorm.DB, p model.Pengumuman) (penerimaPengumuman, error) {
+	result := penerimaPengumuman{Kelompok: []penerimaKelompok{}, Lainnya: []uint{}, Users: map[uint]model.User{}}
+
+	var targets []model.PengumumanTarget
+	if err := db.Where("pengumuman_id = ?", p.ID).Find(&targets).Error; err != nil {
+		return result, err
+	}
+	cond, semua := kelompokSasaran(p, targets)
+
+	var users []model.User
+	var members []model.KelompokMahasiswa
+	if semua {
+		if err := db.Select("id", "username", "role").Find(&users).Error; err != nil {
+			return result, err
+		}
+		if err := db.Select("id", "user_id", "kelompok_id").Order("id").Find(&members).Error; err != nil {
+			return result, err
+		}
+	} else {
+		var mahasiswa []uint
+		if err := db.Model(&model.KelompokMahasiswa{}).Distinct("user_id").
+			Where("kelompok_id IN (SELECT id FROM kelompok WHERE "+cond.sql+")", cond.args...).
+			Pluck("user_id", &mahasiswa).Error; err != nil {
+			return result, err
+		}
+		// Semua kelompok kandidat tetap dimuat karena visibleCond memakai
+		// seluruh kelompok mahasiswa tersebut
+		if len(mahasiswa) > 0 {
+			if err := db.Select("id", "user_id", "kelompok_id").Where("user_id IN ?", mahasiswa).
+				Order("id").Find(&members).Error; err != nil {
+				return result, err
+			}
+		}
+		query := db.Select("id", "username", "role").Where("role <> ?", policy.RoleMahasiswa)
+		if len(mahasiswa) > 0 {
+			query = query.Or("id IN ?", mahasiswa)
+		}
+		if err := query.Find(&users).Error; err != nil {
+			return result, err
+		}
+	}
+	var dosenRoles []model.DosenRole
+	if err := db.Find(&dosenRoles).Error; err != nil {
+		return result, err
+	}
+	kelompokIDs := map[uint]bool{}
+	for _, m := range members {
+		kelompokIDs[m.KelompokID] = true
+	}
+	var kelompok []model.Kelompok
+	if len(kelompokIDs) > 0 {
+		ids := make([]uint, 0, len(kelompokIDs))
+		for id := range kelompokIDs {
+			ids = append(ids, id)
+		}
+		if err := db.Where("id IN ?", ids).Find(&kelompok).Error; err != nil {
+			return result, err
+		}
+	}
+
+	kelompokByID := map[uint]model.Kelompok{}
+	for _, k := range kelompok {
+		kelompokByID[k.ID] = k
+	}
+	role := map[uint]string{}
+	for _, u := range users {
+		role[u.ID] = u.Role
+		result.Users[u.ID] = u
+	}
+	rolesOf := map[uint][]model.DosenRole{}
+	for _, dr := range dosenRoles {
+		rolesOf[dr.UserID] = append(rolesOf[dr.UserID], dr)
+		if _, ok := role[dr.UserID]; !ok {
+			role[dr.UserID] = policy.RoleDosen
+		}
+	}
+	kelompokOf := map[uint][]model.Kelompok{}
+	for _, m := range members {
+		if k, ok := kelompokByID[m.KelompokID]; ok {
+			kelompokOf[m.UserID] = append(kelompokOf[m.UserID], k)
+		}
+		if _, ok := role[m.UserID]; !ok {
+			role[m.UserID] = policy.RoleMahasiswa
+		}
+	}
+
+	ids := make([]uint, 0, len(role))
+	for id, r := range role {
+		if id != 0 && id != p.UserID && !config.App.IsAdmin(r) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	candidates := make([]*pengumumanAudience, 0, len(ids))
+	for _, id := range ids {
+		candidates = append(candidates, &pengumumanAudience{
+			db:       db,
+			subject:  policy.NewSubject(id, role[id], rolesOf[id]),
+			kelompok: kelompokOf[id],
+		})
+	}
+	penerima, err := visibleAmong(db, p.ID, candidates)
+	if err != nil {
+		return result, err
+	}
+	sort.Slice(penerima, func(i, j int) bool { return penerima[i] < penerima[j] })
+
+	// Penerima dikelompokkan menurut kelompok aktifnya
+	anggota := map[uint][]uint{}
+	for _, id := range penerima {
+		grouped := false
+		for _, k := range kelompokOf[id] {
+			if isKelompokAktif(k) {
+				anggota[k.ID] = append(anggota[k.ID], id)
+				grouped = true
+			}
+		}
+		if !grouped {
+			result.Lainnya = append(result.Lainnya, id)
+		}
+	}
+	for id, list := range anggota {
+		result.Kelompok = append(result.Kelompok, penerimaKelompok{Kelompok: kelompokByID[id], Anggota: list})
+	}
+	sort.Slice(result.Kelompok, func(i, j int) bool { return result.Kelompok[i].Kelompok.ID < result.Kelompok[j].Kelompok.ID })
+	return result, nil
+}
+
+// loadPenerimaReads mengambil status baca seluruh penerima pengumuman
+func loadPenerimaReads(db *gorm.DB, pengumumanID uint) (map[uint]model.PengumumanBaca, error) {
+	var rows []model.PengumumanBaca
+	if err := db.Where("pengumuman_id = ?", pengumumanID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	reads := map[uint]model.PengumumanBaca{}
+	for _, r := range rows {
+		reads[r.UserID] = r
+	}
+	return reads, nil
+}
+
+// sudahDitindaklanjuti: untuk pengumuman wajib konfirmasi, penerima dianggap
+// selesai setelah konfirmasi; selain itu cukup membaca
+func sudahDitindaklanjuti(meta model.PengumumanMeta, baca model.PengumumanBaca, ok bool) bool {
+	if !ok {
+		return false
+	}
+	return !meta.WajibKonfirmasi || baca.AcknowledgedAt != nil
+}
+
+func rasio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// KonfirmasiPengumuman mencatat konfirmasi penerima untuk pengumuman yang
+// wajib dikonfirmasi. Admin yang sedang impersonasi tidak boleh
+// mengonfirmasi atas nama user.
+func KonfirmasiPengumuman(c *gin.Context) {
+	db := config.DB
+
+	if _, impersonated := c.Get("impersonator_id"); impersonated {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Konfirmasi tidak dapat dilakukan saat impersonasi"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID pengumuman tidak valid"})
+		return
+	}
+	var pengumuman model.Pengumuman
+	if err := db.First(&pengumuman, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pengumuman tidak ditemukan"})
+		return
+	}
+	audience, err := loadAudience(c, db)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	metas, _, err := loadPengumumanExtras(db, []uint{pengumuman.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil pengumuman"})
+		return
+	}
+	visible, err := audience.canOpen(pengumuman, metas[pengumuman.ID])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil pengumuman"})
+		return
+	}
+	meta := metas[pengumuman.ID]
+	if !visible || (meta != nil && meta.DeletedAt != nil) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pengumuman tidak ditemukan"})
+		return
+	}
+	if meta == nil || !meta.WajibKonfirmasi {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pengumuman ini tidak memerlukan konfirmasi"})
+		return
+	}
+
+	baca, err := recordPengumumanRead(db, pengumuman.ID, audience.subject.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan konfirmasi"})
+		return
+	}
+	if baca.AcknowledgedAt == nil {
+		now := time.Now()
+		if err := db.Model(baca).Update("acknowledged_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan konfirmasi"})
+			return
+		}
+		baca.AcknowledgedAt = &now
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": baca})
+}
+
+// fcmMulticastBatch adalah jumlah token maksimum per panggilan
+// SendEachForMulticast
+const fcmMulticastBatch = 500
+
+// GetPengumumanStatistik menampilkan rasio baca dan konfirmasi serta daftar
+// penerima yang belum membaca per kelompok. Penerima tanpa kelompok aktif
+// (dosen, mahasiswa yang belum berkelompok) ditampilkan di "lainnya". Hanya
+// untuk penulis atau koordinator.
+func GetPengumumanStatistik(c *gin.Context) {
+	db := config.DB
+
+	pengumuman, meta, _, ok := loadManagedPengumuman(c, db)
+	if !ok {
+		return
+	}
+	penerima, err := loadPenerima(db, *pengumuman)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil penerima pengumuman"})
+		return
+	}
+	reads, err := loadPenerimaReads(db, pengumuman.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil status baca"})
+		return
+	}
+
+	user := func(id uint) gin.H {
+		return gin.H{"user_id": id, "username": penerima.Users[id].Username}
+	}
+	counted, dibaca, dikonfirmasi := map[uint]bool{}, 0, 0
+	// ringkasan menghitung status baca sekelompok penerima dan menambahkan
+	// penerima yang belum dihitung ke total
+	ringkasan := func(anggota []uint) gin.H {
+		gDibaca, gDikonfirmasi := 0, 0
+		belumMembaca, belumKonfirmasi := []gin.H{}, []gin.H{}
+		for _, id := range anggota {
+			baca, ok := reads[id]
+			if ok {
+				gDibaca++
+			} else {
+				belumMembaca = append(belumMembaca, user(id))
+			}
+			if ok && baca.AcknowledgedAt != nil {
+				gDikonfirmasi++
+			} else if meta.WajibKonfirmasi {
+				belumKonfirmasi = append(belumKonfirmasi, user(id))
+			}
+
+			if counted[id] {
+				continue
+			}
+			counted[id] = true
+			if ok {
+				dibaca++
+				if baca.AcknowledgedAt != nil {
+					dikonfirmasi++
+				}
+			}
+		}
+
+		item := gin.H{
+			"anggota":       len(anggota),
+			"dibaca":        gDibaca,
+			"belum_membaca": belumMembaca,
+		}
+		if meta.WajibKonfirmasi {
+			item["dikonfirmasi"] = gDikonfirmasi
+			item["belum_konfirmasi"] = belumKonfirmasi
+		}
+		return item
+	}
+
+	perKelompok := make([]gin.H, 0, len(penerima.Kelompok))
+	for _, k := range penerima.Kelompok {
+		item := ringkasan(k.Anggota)
+		item["kelompok_id"] = k.Kelompok.ID
+		item["nomor_kelompok"] = k.Kelompok.NomorKelompok
+		perKelompok = append(perKelompok, item)
+	}
+	lainnya := ringkasan(penerima.Lainnya)
+
+	data := gin.H{
+		"pengumuman_id":    pengumuman.ID,
+		"wajib_konfirmasi": meta.WajibKonfirmasi,
+		"penerima":         len(counted),
+		"dibaca":           dibaca,
+		"rasio_baca":       rasio(dibaca, len(counted)),
+		"kelompok":         perKelompok,
+		"lainnya":          lainnya,
+	}
+	if meta.WajibKonfirmasi {
+		data["dikonfirmasi"] = dikonfirmasi
+		data["rasio_konfirmasi"] = rasio(dikonfirmasi, len(counted))
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": data})
+}
+
+// IngatkanPengumuman mengirim ulang notifikasi FCM ke penerima yang belum
+// membaca (atau belum mengonfirmasi, untuk pengumuman wajib konfirmasi).
+// Query kelompok_id membatasi pengingat ke anggota satu kelompok.
+func IngatkanPengumuman(c *gin.Context) {
+	db := config.DB
+
+	pengumuman, meta, _, ok := loadManagedPengumuman(c, db)
+	if !ok {
+		return
+	}
+	if meta.DeletedAt != nil || pengumuman.Status != announcement.StatusAktif {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pengumuman belum terbit, sudah kadaluarsa atau dihapus"})
+		return
+	}
+	var kelompokID uint64
+	if raw := c.Query("kelompok_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "kelompok_id tidak valid"})
+			return
+		}
+		kelompokID = id
+	}
+
+	penerima, err := loadPenerima(db, *pengumuman)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil penerima pengumuman"})
+		return
+	}
+	reads, err := loadPenerimaReads(db, pengumuman.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil status baca"})
+		return
+	}
+
+	groups := [][]uint{}
+	for _, k := range penerima.Kelompok {
+		if kelompokID == 0 || uint64(k.Kelompok.ID) == kelompokID {
+			groups = append(groups, k.Anggota)
+		}
+	}
+	if kelompokID == 0 {
+		groups = append(groups, penerima.Lainnya)
+	}
+	recipients := []uint{}
+	seen := map[uint]bool{}
+	for _, anggota := range groups {
+		for _, id := range anggota {
+			baca, ok := reads[id]
+			if seen[id] || sudahDitindaklanjuti(*meta, baca, ok) {
+				continue
+			}
+			seen[id] = true
+			recipients = append(recipients, id)
+		}
+	}
+
+	var tokens []model.Device_Token
+	if len(recipients) > 0 {
+		if err := db.Where("user_id IN ? AND token_device <> ''", recipients).Find(&tokens).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil device token"})
+			return
+		}
+	}
+
+	count, failed := 0, 0
+	if len(tokens) > 0 {
+		if config.FirebaseApp == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Firebase belum dikonfigurasi"})
+			return
+		}
+		ctx := c.Request.Context()
+		client, err := config.FirebaseApp.Messaging(ctx)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Firebase init failed"})
+			return
+		}
+
+		body := "Anda belum membaca pengumuman ini"
+		if meta.WajibKonfirmasi {
+			body = "Pengumuman ini memerlukan konfirmasi Anda"
+		}
+		// FCM menerima paling banyak 500 token per multicast
+		for start := 0; start < len(tokens); start += fcmMulticastBatch {
+			end := start + fcmMulticastBatch
+			if end > len(tokens) {
+				end = len(tokens)
+			}
+			batch := tokens[start:end]
+			msg := &messaging.MulticastMessage{
+				Tokens: make([]string, 0, len(batch)),
+				Notification: &messaging.Notification{
+					Title: pengumuman.Judul,
+					Body:  body,
+				},
+				Data: map[string]string{
+					"screen":        "pengumuman",
+					"pengumuman_id": strconv.FormatUint(uint64(pengumuman.ID), 10),
+				},
+			}
+			for _, token := range batch {
+				msg.Tokens = append(msg.Tokens, token.TokenDevice)
+			}
+			resp, err := client.SendEachForMulticast(ctx, msg)
+			if err != nil {
+				failed += len(batch)
+				log.Printf("Gagal mengirim pengingat pengumuman %d: %v\n", pengumuman.ID, err)
+				continue
+			}
+			count += resp.SuccessCount
+			failed += resp.FailureCount
+			for i, r := range resp.Responses {
+				if !r.Success {
+					log.Printf("Gagal mengirim pengingat pengumuman %d ke user %d: %v\n", pengumuman.ID, batch[i].UserID, r.Error)
+				}
+			}
+		}
+	}
+
+	// Satu user bisa punya beberapa device, jadi yang dihitung adalah usernya
+	punyaToken := map[int]bool{}
+	for _, t := range tokens {
+		punyaToken[t.UserID] = true
+	}
+	result := gin.H{
+		"penerima":    len(recipients),
+		"tanpa_token": len(recipients) - len(punyaToken),
+		"terkirim":    count,
+		"gagal":       failed,
+	}
+	audit.Track(c, audit.Change{
+		Action:     "pengumuman.remind",
+		EntityType: "pengumuman",
+		EntityID:   pengumuman.ID,
+		After:      result,
+	})
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+}
@@ -3,48 +3,146 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/announcement"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"github.com/rudychandra/lagi/storage"
+	"github.com/rudychandra/lagi/utils"
+	"gorm.io/gorm"
 )
 
-// GetPengumuman retrieves all active announcements
+// GetPengumuman retrieves the published announcements targeted at the caller,
+// pinned and high priority ones first. Targeting is applied in SQL so only
+// the requested page is loaded.
+// Optional filters: prodi_id, kpa_id, tm_id; pagination: limit (max 100)
+// and offset, in which case total, limit and offset are returned as well.
+// Without either parameter every announcement is returned. milik_saya=true
+// returns the caller's own announcements including scheduled and expired
+// ones; terhapus=true returns deleted announcements the caller may restore.
 func GetPengumuman(c *gin.Context) {
 	db := config.DB
 	
-	var pengumumans []model.Pengumuman
-	
-	// Get query parameters for filtering
-	prodiID := c.DefaultQuery("prodi_id", "")
-	kpaID := c.DefaultQuery("kpa_id", "")
+	audience, err := loadAudience(c, db)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
 	
-	// Base query
-	query := db.Where("status = ?", "aktif")
+	// Base query, limited to announcements the caller may see or manage
+	mine, _ := strconv.ParseBool(c.Query("milik_saya"))
+	deleted, _ := strconv.ParseBool(c.Query("terhapus"))
+	var query *gorm.DB
+	switch {
+	case deleted:
+		cond, err := manageCond(db, audience.subject)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		query = db.Model(&model.Pengumuman{}).
+			Joins("JOIN pengumuman_meta pm ON pm.pengumuman_id = pengumuman.id").
+			Where("pm.deleted_at IS NOT NULL").
+			Where(cond.sql, cond.args...)
+	case mine:
+		query = db.Model(&model.Pengumuman{}).
+			Joins("LEFT JOIN pengumuman_meta pm ON pm.pengumuman_id = pengumuman.id").
+			Where("pengumuman.user_id = ? AND pm.deleted_at IS NULL", audience.subject.UserID)
+	default:
+		query, err = audience.filterVisible(publishedPengumuman(db, time.Now()))
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+	}
 	
 	// Apply filters if provided
-	if prodiID != "" {
-		query = query.Where("prodi_id = ?", prodiID)
+	for param, column := range map[string]string{
+		"prodi_id": "pengumuman.prodi_id",
+		"kpa_id":   "pengumuman.KPA_id",
+		"tm_id":    "pengumuman.TM_id",
+	} {
+		if v := c.Query(param); v != "" {
+			query = query.Where(column+" = ?", v)
+		}
 	}
+	// Without limit or offset the full list is returned as before, so
+	// existing clients keep receiving every announcement
+	_, hasLimit := c.GetQuery("limit")
+	_, hasOffset := c.GetQuery("offset")
+	paginated := hasLimit || hasOffset
+	limit, offset := parseLimitOffset(c)
 	
-	if kpaID != "" {
-		query = query.Where("KPA_id = ?", kpaID)
+	var total int64
+	if paginated {
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+			return
+		}
+		query = query.Limit(limit).Offset(offset)
+	}
+	var pengumumans []model.Pengumuman
+	if err := query.Select("pengumuman.*").Order(pengumumanOrder).Find(&pengumumans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+		return
 	}
 	
-	// Execute query with order by newest first
-	if err := query.Order("tanggal_penulisan DESC").Find(&pengumumans).Error; err != nil {
+	ids := make([]uint, 0, len(pengumumans))
+	for _, p := range pengumumans {
+		ids = append(ids, p.ID)
+	}
+	metas, targets, err := loadPengumumanExtras(db, ids)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
 		return
 	}
 	
+	reads, err := loadPengumumanReads(db, audience.subject.UserID, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+		return
+	}
+	lampiran, err := loadLampiran(db, model.LampiranPengumuman, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+		return
+	}
+	
+	data := make([]pengumumanResponse, 0, len(pengumumans))
+	for _, p := range pengumumans {
+		resp := newPengumumanResponse(p, metas[p.ID], targets[p.ID])
+		resp.Baca = reads[p.ID]
+		if lampiran[p.ID] != nil {
+			resp.Lampiran = lampiran[p.ID]
+		}
+		data = append(data, resp)
+	}
+	
+	if !paginated {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": data,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
-		"data": pengumumans,
+		"data": data,
+		"total": total,
+		"limit": limit,
+		"offset": offset,
 	})
 }
 
-// GetPengumumanByID retrieves a specific announcement by ID
+// GetPengumumanByID retrieves a specific announcement by ID. Announcements
+// that are not published yet or not targeted at the caller are reported as
+// not found, except to their author. Deleted announcements are only visible
+// to those who may restore them.
 func GetPengumumanByID(c *gin.Context) {
 	db := config.DB
 	
@@ -56,13 +154,60 @@ func GetPengumumanByID(c *gin.Context) {
 		return
 	}
 	
+	audience, err := loadAudience(c, db)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	metas, targets, err := loadPengumumanExtras(db, []uint{pengumuman.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+		return
+	}
+	
+	visible, err := audience.canOpen(pengumuman, metas[pengumuman.ID])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	
+	lampiran, err := loadLampiran(db, model.LampiranPengumuman, []uint{pengumuman.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+		return
+	}
+	resp := newPengumumanResponse(pengumuman, metas[pengumuman.ID], targets[pengumuman.ID])
+	if lampiran[pengumuman.ID] != nil {
+		resp.Lampiran = lampiran[pengumuman.ID]
+	}
+	
+	// Record the first read by a recipient. Admin yang sedang impersonasi
+	// tidak mencatat baca atas nama user tersebut.
+	_, impersonated := c.Get("impersonator_id")
+	if !impersonated && pengumuman.UserID != audience.subject.UserID && resp.Meta.DeletedAt == nil {
+		baca, err := recordPengumumanRead(db, pengumuman.ID, audience.subject.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+			return
+		}
+		resp.Baca = baca
+	}
+	
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
-		"data": pengumuman,
+		"data": resp,
 	})
 }
 
-// CreatePengumuman creates a new announcement
+// CreatePengumuman creates a new announcement. Besides the cohort fields it
+// accepts "targets" (JSON array of cohort, kelompok or role targets) and
+// "publish_at"/"expire_at" (RFC3339), "format" (teks or markdown),
+// "prioritas", "pinned" and "wajib_konfirmasi". An announcement with a future publish_at stays
+// non-aktif until the scheduler publishes it.
 func CreatePengumuman(c *gin.Context) {
 	db := config.DB
 	
@@ -73,6 +218,10 @@ func CreatePengumuman(c *gin.Context) {
 		return
 	}
 	
+	if !limitRequestBody(c, config.Upload.MaxPengumumanSize) {
+		return
+	}
+	
 	// Parse form data
 	judul := c.PostForm("judul")
 	deskripsi := c.PostForm("deskripsi")
@@ -80,12 +229,64 @@ func CreatePengumuman(c *gin.Context) {
 	prodiID := c.PostForm("prodi_id")
 	tmID := c.PostForm("tm_id")
 	
-	// Validate required fields
-	if judul == "" || deskripsi == "" || kpaID == "" || prodiID == "" || tmID == "" {
+	targets, err := parsePengumumanTargets(c.PostForm("targets"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	// Validate required fields; cohort fields are optional when targets are given
+	if judul == "" || deskripsi == "" || (len(targets) == 0 && (kpaID == "" || prodiID == "" || tmID == "")) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "All fields are required"})
 		return
 	}
 	
+	format, err := parsePengumumanFormat(c.PostForm("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prioritas, err := parsePrioritas(c.PostForm("prioritas"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pinned, _ := strconv.ParseBool(c.PostForm("pinned"))
+	wajibKonfirmasi, _ := strconv.ParseBool(c.PostForm("wajib_konfirmasi"))
+	
+	now := time.Now()
+	publishAt, err := parseJadwalTime(c.PostForm("publish_at"), "publish_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expireAt, err := parseJadwalTime(c.PostForm("expire_at"), "expire_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if expireAt != nil && (!expireAt.After(now) || (publishAt != nil && !expireAt.After(*publishAt))) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expire_at harus setelah publish_at dan waktu sekarang"})
+		return
+	}
+	scheduled := publishAt != nil && publishAt.After(now)
+	
+	// Target kelompok harus merujuk kelompok yang ada
+	for _, t := range targets {
+		if t.Tipe != model.TargetKelompok {
+			continue
+		}
+		var count int64
+		if err := db.Model(&model.Kelompok{}).Where("id = ?", t.KelompokID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Kelompok " + strconv.FormatUint(uint64(t.KelompokID), 10) + " tidak ditemukan"})
+			return
+		}
+	}
+	
 	// Convert string IDs to uint
 	kpaIDInt, _ := strconv.ParseUint(kpaID, 10, 64)
 	prodiIDInt, _ := strconv.ParseUint(prodiID, 10, 64)
@@ -95,58 +296,338 @@ func CreatePengumuman(c *gin.Context) {
 	pengumuman := model.Pengumuman{
 		Judul:            judul,
 		Deskripsi:        deskripsi,
-		TanggalPenulisan: time.Now(),
-		Status:           "aktif",
+		TanggalPenulisan: now,
+		Status:           announcement.StatusAktif,
 		UserID:           userID.(uint),
 		KPAID:            uint(kpaIDInt),
 		ProdiID:          uint(prodiIDInt),
 		TMID:             uint(tmIDInt),
 	}
+	if scheduled {
+		pengumuman.Status = announcement.StatusNonAktif
+	}
 	
 	// Handle file upload if present
 	file, err := c.FormFile("file")
 	if err == nil {
-		filename := "uploads/pengumuman/" + strconv.FormatUint(uint64(time.Now().Unix()), 10) + "_" + file.Filename
-		if err := c.SaveUploadedFile(file, filename); err != nil {
+		detected, ok := checkUpload(c, file, config.Upload.PengumumanAllowedMIME, config.Upload.MaxPengumumanSize)
+		if !ok {
+			return
+		}
+		name := utils.ReplaceExt(utils.SanitizeFilename(file.Filename), detected.Extension())
+		fileKey := "pengumuman/" + strconv.FormatUint(uint64(time.Now().Unix()), 10) + "_" + name
+		if err := saveUpload(c, storage.Uploads, fileKey, file, detected.String()); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 			return
 		}
-		pengumuman.File = filename
+		pengumuman.File = storage.DBPath(fileKey)
 	}
 	
-	// Save to database
-	if err := db.Create(&pengumuman).Error; err != nil {
+	// Save the announcement together with its schedule and targets
+	meta := model.PengumumanMeta{
+		PublishAt:       publishAt,
+		ExpireAt:        expireAt,
+		Format:          format,
+		Prioritas:       prioritas,
+		Pinned:          pinned,
+		WajibKonfirmasi: wajibKonfirmasi,
+	}
+	if publishAt != nil && !scheduled {
+		meta.PublishedAt = &now
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&pengumuman).Error; err != nil {
+			return err
+		}
+		meta.PengumumanID = pengumuman.ID
+		if err := tx.Create(&meta).Error; err != nil {
+			return err
+		}
+		for i := range targets {
+			targets[i].PengumumanID = pengumuman.ID
+		}
+		if len(targets) > 0 {
+			return tx.Create(&targets).Error
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
 		return
 	}
+	audit.Track(c, audit.Change{
+		Action:     "pengumuman.create",
+		EntityType: "pengumuman",
+		EntityID:   pengumuman.ID,
+		After:      newPengumumanResponse(pengumuman, &meta, targets),
+	})
 	
 	c.JSON(http.StatusCreated, gin.H{
 		"status": "success",
-		"data": pengumuman,
+		"data": newPengumumanResponse(pengumuman, &meta, targets),
 	})
 }
 
-// DeletePengumuman deletes an announcement
-func DeletePengumuman(c *gin.Context) {
+// PengumumanUpdateRequest berisi field yang boleh diubah; field kosong
+// (nil) dibiarkan seperti semula
+type PengumumanUpdateRequest struct {
+	Judul           *string `json:"judul"`
+	Deskripsi       *string `json:"deskripsi"`
+	Format          *string `json:"format"`
+	Prioritas       *string `json:"prioritas"`
+	Pinned          *bool   `json:"pinned"`
+	WajibKonfirmasi *bool   `json:"wajib_konfirmasi"`
+}
+
+// loadManagedPengumuman loads the announcement with its meta and checks that
+// the caller is its author or a coordinator covering its cohort
+func loadManagedPengumuman(c *gin.Context, db *gorm.DB) (*model.Pengumuman, *model.PengumumanMeta, *policy.Subject, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement ID"})
+		return nil, nil, nil, false
+	}
+	var pengumuman model.Pengumuman
+	if err := db.First(&pengumuman, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return nil, nil, nil, false
+	}
+	
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return nil, nil, nil, false
+	}
+	allowed, err := canManagePengumuman(db, subject, pengumuman)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return nil, nil, nil, false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya penulis atau koordinator yang dapat mengelola pengumuman ini"})
+		return nil, nil, nil, false
+	}
+	
+	meta := defaultPengumumanMeta(pengumuman.ID)
+	if err := db.Where("pengumuman_id = ?", pengumuman.ID).Limit(1).Find(&meta).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+		return nil, nil, nil, false
+	}
+	return &pengumuman, &meta, subject, true
+}
+
+// UpdatePengumuman updates an announcement. The previous title and body are
+// kept in pengumuman_revisi whenever they change.
+func UpdatePengumuman(c *gin.Context) {
 	db := config.DB
 	
-	id := c.Param("id")
-	var pengumuman model	.Pengumuman
+	pengumuman, meta, subject, ok := loadManagedPengumuman(c, db)
+	if !ok {
+		return
+	}
+	if meta.DeletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pengumuman sudah dihapus, pulihkan terlebih dahulu"})
+		return
+	}
 	
-	// Check if announcement exists
-	if err := db.First(&pengumuman, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+	var req PengumumanUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format data tidak valid"})
+		return
+	}
+	
+	before := newPengumumanResponse(*pengumuman, meta, nil)
+	updated, updatedMeta := *pengumuman, *meta
+	if req.Judul != nil {
+		updated.Judul = strings.TrimSpace(*req.Judul)
+	}
+	if req.Deskripsi != nil {
+		updated.Deskripsi = *req.Deskripsi
+	}
+	if updated.Judul == "" || strings.TrimSpace(updated.Deskripsi) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Judul dan deskripsi tidak boleh kosong"})
+		return
+	}
+	if req.Format != nil {
+		format, err := parsePengumumanFormat(*req.Format)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updatedMeta.Format = format
+	}
+	if req.Prioritas != nil {
+		prioritas, err := parsePrioritas(*req.Prioritas)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updatedMeta.Prioritas = prioritas
+	}
+	if req.Pinned != nil {
+		updatedMeta.Pinned = *req.Pinned
+	}
+	if req.WajibKonfirmasi != nil {
+		updatedMeta.WajibKonfirmasi = *req.WajibKonfirmasi
+	}
+	
+	contentChanged := updated.Judul != pengumuman.Judul ||
+		updated.Deskripsi != pengumuman.Deskripsi ||
+		updatedMeta.Format != meta.Format
+	
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if contentChanged {
+			revisi := model.PengumumanRevisi{
+				PengumumanID: pengumuman.ID,
+				Judul:        pengumuman.Judul,
+				Deskripsi:    pengumuman.Deskripsi,
+				Format:       meta.Format,
+				EditedBy:     subject.UserID,
+			}
+			if err := tx.Create(&revisi).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&updated).Updates(map[string]interface{}{
+				"judul":      updated.Judul,
+				"deskripsi":  updated.Deskripsi,
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&updatedMeta).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update announcement"})
+		return
+	}
+	
+	var targets []model.PengumumanTarget
+	if err := db.Where("pengumuman_id = ?", pengumuman.ID).Order("id").Find(&targets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+		return
+	}
+	after := newPengumumanResponse(updated, &updatedMeta, targets)
+	before.Target = after.Target
+	audit.Track(c, audit.Change{
+		Action:     "pengumuman.update",
+		EntityType: "pengumuman",
+		EntityID:   pengumuman.ID,
+		Before:     before,
+		After:      after,
+	})
+	
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": after,
+	})
+}
+
+// GetPengumumanRiwayat returns the edit history of an announcement, newest first
+func GetPengumumanRiwayat(c *gin.Context) {
+	db := config.DB
+	
+	pengumuman, _, _, ok := loadManagedPengumuman(c, db)
+	if !ok {
+		return
+	}
+	
+	var revisi []model.PengumumanRevisi
+	if err := db.Where("pengumuman_id = ?", pengumuman.ID).Order("id DESC").Find(&revisi).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement history"})
 		return
 	}
 	
-	// Delete the announcement
-	if err := db.Delete(&pengumuman).Error; err != nil {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": revisi,
+	})
+}
+
+// DeletePengumuman soft-deletes an announcement. It is set to non-aktif so
+// it disappears from every client and can be restored later.
+func DeletePengumuman(c *gin.Context) {
+	db := config.DB
+	
+	pengumuman, meta, subject, ok := loadManagedPengumuman(c, db)
+	if !ok {
+		return
+	}
+	if meta.DeletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pengumuman sudah dihapus"})
+		return
+	}
+	
+	now := time.Now()
+	before := *meta
+	meta.DeletedAt = &now
+	meta.DeletedBy = &subject.UserID
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(meta).Error; err != nil {
+			return err
+		}
+		return tx.Model(pengumuman).Updates(map[string]interface{}{
+			"status":     announcement.StatusNonAktif,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete announcement"})
 		return
 	}
+	audit.Track(c, audit.Change{Action: "pengumuman.delete", EntityType: "pengumuman", EntityID: pengumuman.ID, Before: before, After: *meta})
 	
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
 		"message": "Announcement deleted successfully",
 	})
-}
\ No newline at end of file
+}
+
+// RestorePengumuman restores a soft-deleted announcement. Its status follows
+// the schedule: aktif unless publish_at is still ahead or expire_at has passed.
+func RestorePengumuman(c *gin.Context) {
+	db := config.DB
+	
+	pengumuman, meta, _, ok := loadManagedPengumuman(c, db)
+	if !ok {
+		return
+	}
+	if meta.DeletedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pengumuman tidak dalam keadaan terhapus"})
+		return
+	}
+	
+	now := time.Now()
+	before := *meta
+	status := announcement.StatusAktif
+	if (meta.PublishAt != nil && meta.PublishAt.After(now)) || (meta.ExpireAt != nil && !meta.ExpireAt.After(now)) {
+		status = announcement.StatusNonAktif
+	}
+	meta.DeletedAt = nil
+	meta.DeletedBy = nil
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(meta).Error; err != nil {
+			return err
+		}
+		return tx.Model(pengumuman).Updates(map[string]interface{}{
+			"status":     status,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore announcement"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "pengumuman.restore", EntityType: "pengumuman", EntityID: pengumuman.ID, Before: before, After: *meta})
+	
+	var targets []model.PengumumanTarget
+	if err := db.Where("pengumuman_id = ?", pengumuman.ID).Order("id").Find(&targets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": newPengumumanResponse(*pengumuman, meta, targets),
+	})
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"gorm.io/gorm"
+)
+
+// provisionUser membuat atau memperbarui baris users dari data login CIS,
+// sehingga username, email dan role tersedia untuk join di database lokal.
+// ID user sama dengan user_id dari CIS. Password tidak disimpan karena
+// autentikasi tetap dilakukan oleh CIS.
+func provisionUser(db *gorm.DB, userID uint, username, email, role string) (model.User, error) {
+	// Email kosong disimpan sebagai NULL agar tidak bentrok dengan index unique
+	var emailValue *string
+	if email = strings.TrimSpace(email); email != "" {
+		emailValue = &email
+	}
+
+	var user model.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Unscoped agar user yang pernah dihapus dipulihkan, bukan dibuat ulang
+		err := tx.Unscoped().Where("id = ?", userID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user = model.User{
+				ID:       userID,
+				Username: username,
+				Email:    emailValue,
+				Role:     role,
+			}
+			return tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&user).Updates(map[string]interface{}{
+			"username":   username,
+			"email":      emailValue,
+			"role":       role,
+			"deleted_at": nil,
+		}).Error
+	})
+	return user, err
+}
+
+// GetMe mengembalikan profil lokal user yang sedang login, kelompok tempat
+// user terdaftar dan role dosen yang dimiliki
+func GetMe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	role, _ := c.Get("user_role")
+
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profil belum tersedia, silakan login kembali"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil profil"})
+		return
+	}
+
+	var memberships []model.KelompokMahasiswa
+	if err := db.Preload("Kelompok").Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kelompok"})
+		return
+	}
+	kelompok := make([]model.Kelompok, 0, len(memberships))
+	for _, m := range memberships {
+		kelompok = append(kelompok, m.Kelompok)
+	}
+
+	dosenRoles := []model.DosenRole{}
+	if err := db.Where("user_id = ?", userID).Find(&dosenRoles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil role dosen"})
+		return
+	}
+
+	data := gin.H{
+		"user":        user,
+		"token_role":  role,
+		"kelompok":    kelompok,
+		"dosen_roles": dosenRoles,
+		"permissions": policy.NewSubject(user.ID, c.GetString("user_role"), dosenRoles).Permissions(),
+	}
+	if adminID, ok := c.Get("impersonator_id"); ok {
+		data["impersonated_by"] = adminID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   data,
+	})
+}
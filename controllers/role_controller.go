@@ -2,6 +2,7 @@ package controllers
 
 import (
     "github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
    "github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/model"
     "net/http"
@@ -20,6 +21,7 @@ func CreateRole(c *gin.Context) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
+    audit.Track(c, audit.Change{Action: "role.create", EntityType: "role", EntityID: role.ID, After: role})
 
     c.JSON(http.StatusCreated, role)
 }
@@ -65,13 +67,18 @@ func UpdateRole(c *gin.Context) {
         return
     }
 
+    before := role
     if err := c.ShouldBindJSON(&role); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
     role.ID = uint(id) // Pastikan ID tidak berubah
-    config.DB.Save(&role)
+    if err := config.DB.Save(&role).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    audit.Track(c, audit.Change{Action: "role.update", EntityType: "role", EntityID: role.ID, Before: before, After: role})
     c.JSON(http.StatusOK, role)
 }
 
@@ -91,6 +98,10 @@ func DeleteRole(c *gin.Context) {
         return
     }
 
-    config.DB.Delete(&role)
+    if err := config.DB.Delete(&role).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    audit.Track(c, audit.Change{Action: "role.delete", EntityType: "role", EntityID: role.ID, Before: role})
     c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
 }
\ No newline at end of file
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"github.com/rudychandra/lagi/search"
+	"gorm.io/gorm"
+)
+
+// snippetWidth adalah panjang cuplikan isi dokumen dalam karakter
+const snippetWidth = 200
+
+// searchHit adalah satu dokumen hasil MATCH ... AGAINST beserta skornya
+type searchHit struct {
+	model.SearchDokumen
+	Skor float64 `gorm:"column:skor"`
+}
+
+// searchAccess membatasi hasil pencarian sesuai halaman asalnya: pengumuman
+// mengikuti target, tugas mengikuti cohort kelompok dan bimbingan mengikuti
+// kelompok (anggota, cakupan bimbingan:view_all atau kelompok yang
+// dibimbing). Semua batasan dipasang di WHERE agar total dan paginasi
+// dihitung oleh database.
+type searchAccess struct {
+	db       *gorm.DB
+	audience *pengumumanAudience
+	// bimbinganKelompok nil berarti semua kelompok
+	bimbinganKelompok []uint
+}
+
+func loadSearchAccess(c *gin.Context, db *gorm.DB) (*searchAccess, error) {
+	audience, err := loadAudience(c, db)
+	if err != nil {
+		return nil, err
+	}
+	access := &searchAccess{db: db, audience: audience}
+	if audience.subject.CanGlobal(policy.BimbinganViewAll) {
+		return access, nil
+	}
+
+	access.bimbinganKelompok = []uint{}
+	for _, k := range audience.kelompok {
+		access.bimbinganKelompok = append(access.bimbinganKelompok, k.ID)
+	}
+	ids, err := bimbinganKelompokIDs(db, audience.subject)
+	if err != nil {
+		return nil, err
+	}
+	access.bimbinganKelompok = append(access.bimbinganKelompok, ids...)
+	return access, nil
+}
+
+// tugasCond: dosen (file:read_all) melihat semua tugas, mahasiswa melihat
+// tugas cohort kelompoknya, koordinator melihat tugas dalam cakupan
+// KelompokView-nya
+func (a *searchAccess) tugasCond() (sqlCond, error) {
+	subject := a.audience.subject
+	if subject.CanGlobal(policy.FileReadAll) || subject.CanGlobal(policy.KelompokView) {
+		return condTrue, nil
+	}
+	conds := []sqlCond{}
+	for _, k := range a.audience.kelompok {
+		conds = append(conds, sqlCond{
+			sql:  "search_dokumen.prodi_id = ? AND search_dokumen.TM_id = ? AND search_dokumen.KPA_id IN (0, ?)",
+			args: []interface{}{k.ProdiID, k.TMID, k.KPAID},
+		})
+	}
+	scopes, err := subject.CohortScopes(a.db, policy.KelompokView)
+	if err != nil {
+		return sqlCond{}, err
+	}
+	if len(scopes) > 0 {
+		conds = append(conds, scopeCond(scopes, "search_dokumen.prodi_id", "search_dokumen.KPA_id"))
+	}
+	return orConds(conds...), nil
+}
+
+// filterPengumuman membatasi dokumen pengumuman ke yang bisa dibuka user
+// (lihat canOpen): milik sendiri atau, untuk yang lain, sudah terbit dan
+// ditujukan untuknya. Pengumuman terhapus tidak ditampilkan di pencarian.
+func (a *searchAccess) filterPengumuman(query *gorm.DB) (*gorm.DB, error) {
+	subject := a.audience.subject
+	own := a.db.Model(&model.Pengumuman{}).Select("pengumuman.id").
+		Joins("LEFT JOIN pengumuman_meta pm ON pm.pengumuman_id = pengumuman.id").
+		Where("pm.deleted_at IS NULL")
+	if !subject.Admin {
+		own = own.Where("pengumuman.user_id = ?", subject.UserID)
+	}
+	visible, err := a.audience.filterVisible(publishedPengumuman(a.db, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	visible = visible.Select("pengumuman.id")
+	return query.Where("search_dokumen.tipe <> ? OR search_dokumen.ref_id IN (?) OR search_dokumen.ref_id IN (?)",
+		model.DokumenPengumuman, own, visible), nil
+}
+
+// filter memasang semua batasan hak akses pada query search_dokumen
+func (a *searchAccess) filter(query *gorm.DB) (*gorm.DB, error) {
+	query, err := a.filterPengumuman(query)
+	if err != nil {
+		return nil, err
+	}
+	tugas, err := a.tugasCond()
+	if err != nil {
+		return nil, err
+	}
+	query = query.Where("search_dokumen.tipe <> ? OR ("+tugas.sql+")", append([]interface{}{model.DokumenTugas}, tugas.args...)...)
+	// Bimbingan dibatasi ke daftar kelompok yang boleh dilihat
+	if a.bimbinganKelompok != nil {
+		query = query.Where("search_dokumen.tipe <> ? OR search_dokumen.kelompok_id IN ?", model.DokumenBimbingan, a.bimbinganKelompok)
+	}
+	return query, nil
+}
+
+// parseSearchDate membaca tanggal YYYY-MM-DD dari query
+func parseSearchDate(c *gin.Context, name string) (time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " harus berformat YYYY-MM-DD"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Search mencari pengumuman, tugas dan catatan bimbingan dengan indeks
+// FULLTEXT, diurutkan berdasarkan relevansi. Hasil dibatasi pada dokumen yang
+// boleh dilihat user.
+//
+// Query: q (wajib), tipe (pengumuman,tugas,bimbingan), dari dan sampai
+// (YYYY-MM-DD), prodi_id, kpa_id, tm_id, limit (maks 100) dan offset.
+func Search(c *gin.Context) {
+	db := config.DB
+
+	terms := search.Terms(c.Query("q"))
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter q wajib diisi"})
+		return
+	}
+
+	tipe := []string{}
+	if raw := c.Query("tipe"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			switch t = strings.TrimSpace(strings.ToLower(t)); t {
+			case model.DokumenPengumuman, model.DokumenTugas, model.DokumenBimbingan:
+				tipe = append(tipe, t)
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "tipe harus pengumuman, tugas atau bimbingan"})
+				return
+			}
+		}
+	}
+	dari, ok := parseSearchDate(c, "dari")
+	if !ok {
+		return
+	}
+	sampai, ok := parseSearchDate(c, "sampai")
+	if !ok {
+		return
+	}
+	limit, offset := parseLimitOffset(c)
+
+	access, err := loadSearchAccess(c, db)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+
+	against := search.BooleanQuery(terms)
+	query := db.Model(&model.SearchDokumen{}).
+		Where("MATCH(judul, isi) AGAINST (? IN BOOLEAN MODE)", against)
+	if len(tipe) > 0 {
+		query = query.Where("tipe IN ?", tipe)
+	}
+	if !dari.IsZero() {
+		query = query.Where("tanggal >= ?", dari)
+	}
+	if !sampai.IsZero() {
+		query = query.Where("tanggal < ?", sampai.AddDate(0, 0, 1))
+	}
+	for param, column := range map[string]string{
+		"prodi_id": "prodi_id",
+		"kpa_id":   "KPA_id",
+		"tm_id":    "TM_id",
+	} {
+		if v := c.Query(param); v != "" {
+			query = query.Where("search_dokumen."+column+" = ?", v)
+		}
+	}
+	if query, err = access.filter(query); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal melakukan pencarian"})
+		return
+	}
+	var hits []searchHit
+	if err := query.Select("search_dokumen.*, MATCH(judul, isi) AGAINST (? IN BOOLEAN MODE) AS skor", against).
+		Order("skor DESC, tanggal DESC").Limit(limit).Offset(offset).
+		Scan(&hits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal melakukan pencarian"})
+		return
+	}
+
+	data := make([]gin.H, 0, len(hits))
+	for _, h := range hits {
+		item := gin.H{
+			"tipe":            h.Tipe,
+			"id":              h.RefID,
+			"judul":           h.Judul,
+			"judul_highlight": search.Highlight(h.Judul, terms),
+			"snippet":         search.Snippet(h.Isi, terms, snippetWidth),
+			"skor":            h.Skor,
+			"tanggal":         h.Tanggal,
+			"prodi_id":        h.ProdiID,
+			"kpa_id":          h.KPAID,
+			"tm_id":           h.TMID,
+		}
+		if h.Tipe == model.DokumenBimbingan {
+			item["kelompok_id"] = h.KelompokID
+		}
+		data = append(data, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   data,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
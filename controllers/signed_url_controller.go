@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/utils"
+)
+
+// SignedFileURLRequest adalah permintaan pembuatan signed URL
+type SignedFileURLRequest struct {
+	// Source: "mobile" untuk file upload, "web" untuk file storage Laravel
+	Source     string `json:"source" binding:"required,oneof=mobile web"`
+	Path       string `json:"path" binding:"required"`
+	Download   bool   `json:"download"`
+	TTLSeconds int    `json:"ttl_seconds"`
+	// SingleUse membatasi URL ke satu sesi unduhan (lihat utils.UseFileURL)
+	SingleUse bool `json:"single_use"`
+	BindIP    bool `json:"bind_ip"`
+}
+
+// CreateSignedFileURL membuat URL file berumur pendek yang ditandatangani HMAC
+// untuk user yang sedang login
+func CreateSignedFileURL(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	role, _ := c.Get("user_role")
+
+	var req SignedFileURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, ok := cleanFilePath(c, req.Path)
+	if !ok {
+		return
+	}
+
+	// Tentukan route yang akan dibuka oleh signed URL
+	action := "view"
+	if req.Download {
+		action = "download"
+	}
+	routePrefix := "/files/"
+	if req.Source == "mobile" {
+		key = mobileFileKey(key)
+		routePrefix = "/mobile-files/"
+	}
+
+	// Hanya file yang boleh diakses user yang bisa dibuatkan signed URL
+	if !checkFileAccess(c, key) {
+		return
+	}
+
+	// TTLSeconds dibatasi sebelum dikali agar tidak overflow menjadi negatif
+	ttl := config.SignedURL.DefaultTTL
+	if req.TTLSeconds > 0 {
+		ttl = config.SignedURL.MaxTTL
+		if int64(req.TTLSeconds) < int64(config.SignedURL.MaxTTL/time.Second) {
+			ttl = time.Duration(req.TTLSeconds) * time.Second
+		}
+	}
+	if ttl > config.SignedURL.MaxTTL {
+		ttl = config.SignedURL.MaxTTL
+	}
+
+	claims := utils.FileURLClaims{
+		Path:      path.Join(routePrefix, action, key),
+		UserID:    userID.(uint),
+		Role:      fmt.Sprint(role),
+		ExpiresAt: time.Now().Add(ttl),
+		SingleUse: req.SingleUse,
+		SessionID: c.GetString("session_id"),
+	}
+	if req.BindIP {
+		claims.ClientIP = c.ClientIP()
+	}
+	if id, ok := c.Get("impersonator_id"); ok {
+		claims.ImpersonatorID, _ = id.(uint)
+	}
+
+	query, err := utils.SignFileURL(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat signed URL"})
+		return
+	}
+
+	relative := (&url.URL{Path: claims.Path, RawQuery: query.Encode()}).String()
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"url":        fmt.Sprintf("%s://%s%s", scheme, c.Request.Host, relative),
+			"path":       relative,
+			"expires_at": claims.ExpiresAt.Format(time.RFC3339),
+			"single_use": req.SingleUse,
+			"ip_bound":   req.BindIP,
+		},
+	})
+}
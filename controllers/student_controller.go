@@ -54,8 +54,8 @@ func GetStudentData(c *gin.Context) {
 	}
 
 	// Construct URL for external API
-	externalAPIURL := "https://cis-dev.del.ac.id/api/library-api/mahasiswa"
-	req, err := http.NewRequest("GET", externalAPIURL, nil)
+	externalAPIURL := upstreams.CISStudent.URL("mahasiswa")
+	req, err := http.NewRequestWithContext(c.Request.Context(), "GET", externalAPIURL, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat request ke API eksternal"})
 		return
@@ -70,8 +70,7 @@ func GetStudentData(c *gin.Context) {
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	// Send request to external API
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := cisStudentClient.Do(req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal terhubung ke API eksternal"})
 		return
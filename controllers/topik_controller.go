@@ -0,0 +1,613 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"gorm.io/gorm"
+)
+
+const (
+	topikDibuka  = "dibuka"
+	topikDitutup = "ditutup"
+
+	usulanDiajukan = "diajukan"
+	usulanDiterima = "diterima"
+	usulanDitolak  = "ditolak"
+)
+
+// TopikDosenRequest adalah body untuk menawarkan atau mengubah topik dosen
+type TopikDosenRequest struct {
+	ProdiID   uint   `json:"prodi_id" binding:"required"`
+	KPAID     uint   `json:"kpa_id" binding:"required"`
+	TMID      uint   `json:"tm_id" binding:"required"`
+	Judul     string `json:"judul" binding:"required"`
+	Deskripsi string `json:"deskripsi"`
+	KataKunci string `json:"kata_kunci"`
+	Kuota     int    `json:"kuota" binding:"required,min=1"`
+	Status    string `json:"status"`
+}
+
+// TopikUsulanRequest adalah body untuk mengajukan topik kelompok
+type TopikUsulanRequest struct {
+	KelompokID uint   `json:"kelompok_id"`
+	DosenID    uint   `json:"dosen_id"`
+	Judul      string `json:"judul" binding:"required"`
+	Abstrak    string `json:"abstrak" binding:"required"`
+	KataKunci  string `json:"kata_kunci"`
+}
+
+// ReviewUsulanRequest adalah body untuk menerima atau menolak usulan topik
+type ReviewUsulanRequest struct {
+	Status  string `json:"status" binding:"required"`
+	Catatan string `json:"catatan"`
+}
+
+// TopikPilihanRequest adalah urutan topik pilihan kelompok, pilihan utama
+// di urutan pertama
+type TopikPilihanRequest struct {
+	KelompokID uint   `json:"kelompok_id"`
+	TopikIDs   []uint `json:"topik_ids" binding:"required"`
+}
+
+// normalizeKataKunci merapikan daftar kata kunci yang dipisah koma
+func normalizeKataKunci(raw string) string {
+	parts := config.SplitList(raw)
+	for i, p := range parts {
+		parts[i] = strings.ToLower(p)
+	}
+	return strings.Join(parts, ",")
+}
+
+// cohortFilter menambahkan filter prodi_id, kpa_id dan tm_id dari query
+func cohortFilter(c *gin.Context, query *gorm.DB) *gorm.DB {
+	for param, column := range map[string]string{
+		"prodi_id": "prodi_id",
+		"kpa_id":   "KPA_id",
+		"tm_id":    "TM_id",
+	} {
+		if v := c.Query(param); v != "" {
+			query = query.Where(column+" = ?", v)
+		}
+	}
+	return query
+}
+
+// CreateTopikDosen menawarkan topik PA untuk satu angkatan
+func CreateTopikDosen(c *gin.Context) {
+	var req TopikDosenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	topik := model.TopikDosen{
+		UserID:    c.GetUint("user_id"),
+		ProdiID:   req.ProdiID,
+		KPAID:     req.KPAID,
+		TMID:      req.TMID,
+		Judul:     strings.TrimSpace(req.Judul),
+		Deskripsi: req.Deskripsi,
+		KataKunci: normalizeKataKunci(req.KataKunci),
+		Kuota:     req.Kuota,
+		Status:    topikDibuka,
+	}
+	if err := db.Create(&topik).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan topik"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "topik.create", EntityType: "topik_dosen", EntityID: topik.ID, After: topik})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Topik berhasil ditawarkan",
+		"data":    topik,
+	})
+}
+
+// GetTopikDosen menampilkan topik yang ditawarkan beserta sisa kuotanya.
+// Filter: prodi_id, kpa_id, tm_id, status, dosen_id, q (judul atau kata kunci).
+func GetTopikDosen(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	query := cohortFilter(c, db.Model(&model.TopikDosen{}))
+	if v := c.Query("status"); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	if v := c.Query("dosen_id"); v != "" {
+		query = query.Where("user_id = ?", v)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(judul) LIKE ? OR kata_kunci LIKE ?", like, like)
+	}
+
+	var topik []model.TopikDosen
+	if err := query.Order("id DESC").Find(&topik).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data topik"})
+		return
+	}
+
+	ids := make([]uint, 0, len(topik))
+	for _, t := range topik {
+		ids = append(ids, t.ID)
+	}
+	terpakai, err := topikTerpakai(db, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung kuota topik"})
+		return
+	}
+
+	data := make([]gin.H, 0, len(topik))
+	for _, t := range topik {
+		sisa := t.Kuota - terpakai[t.ID]
+		if sisa < 0 {
+			sisa = 0
+		}
+		data = append(data, gin.H{"topik": t, "terpakai": terpakai[t.ID], "sisa_kuota": sisa})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   data,
+	})
+}
+
+// topikTerpakai menghitung jumlah kelompok yang sudah ditetapkan ke tiap topik
+func topikTerpakai(db *gorm.DB, ids []uint) (map[uint]int, error) {
+	out := map[uint]int{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var rows []struct {
+		TopikID uint
+		Jumlah  int
+	}
+	if err := db.Model(&model.TopikPenetapan{}).
+		Select("topik_id, COUNT(*) AS jumlah").
+		Where("topik_id IN ?", ids).
+		Group("topik_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.TopikID] = r.Jumlah
+	}
+	return out, nil
+}
+
+// UpdateTopikDosen mengubah topik milik dosen yang sedang login
+func UpdateTopikDosen(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid topik ID"})
+		return
+	}
+	var topik model.TopikDosen
+	if err := db.First(&topik, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Topik tidak ditemukan"})
+		return
+	}
+	if topik.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Topik ini milik dosen lain"})
+		return
+	}
+
+	var req TopikDosenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	if status == "" {
+		status = topik.Status
+	}
+	if status != topikDibuka && status != topikDitutup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status harus dibuka atau ditutup"})
+		return
+	}
+
+	before := topik
+	topik.ProdiID = req.ProdiID
+	topik.KPAID = req.KPAID
+	topik.TMID = req.TMID
+	topik.Judul = strings.TrimSpace(req.Judul)
+	topik.Deskripsi = req.Deskripsi
+	topik.KataKunci = normalizeKataKunci(req.KataKunci)
+	topik.Kuota = req.Kuota
+	topik.Status = status
+	if err := db.Save(&topik).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui topik"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "topik.update", EntityType: "topik_dosen", EntityID: topik.ID, Before: before, After: topik})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Topik diperbarui",
+		"data":    topik,
+	})
+}
+
+// CreateTopikUsulan mengajukan topik dari kelompok mahasiswa
+func CreateTopikUsulan(c *gin.Context) {
+	var req TopikUsulanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	kelompok, err := resolveKelompokID(db, userID, req.KelompokID)
+	if errors.Is(err, errBelumBerkelompok) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mahasiswa belum tergabung dalam kelompok"})
+		return
+	}
+	if err != nil {
+		respondKelompokContextError(c, err)
+		return
+	}
+
+	// Usulan hanya boleh ditujukan ke dosen, agar mahasiswa tidak bisa
+	// menunjuk dirinya sendiri lalu menerima usulannya
+	if req.DosenID != 0 {
+		dosen, err := isDosen(db, req.DosenID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa dosen"})
+			return
+		}
+		if !dosen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dosen_id bukan dosen"})
+			return
+		}
+	}
+
+	usulan := model.TopikUsulan{
+		KelompokID: kelompok.ID,
+		UserID:     userID,
+		DosenID:    req.DosenID,
+		Judul:      strings.TrimSpace(req.Judul),
+		Abstrak:    req.Abstrak,
+		KataKunci:  normalizeKataKunci(req.KataKunci),
+		Status:     usulanDiajukan,
+	}
+	if err := db.Create(&usulan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan usulan topik"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "topik.usulan_create", EntityType: "topik_usulan", EntityID: usulan.ID, After: usulan})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Usulan topik berhasil diajukan",
+		"data":    usulan,
+	})
+}
+
+// GetTopikUsulan menampilkan usulan topik. Mahasiswa melihat usulan
+// kelompoknya; dosen melihat usulan yang ditujukan kepadanya dan usulan di
+// prodi dan kategori PA yang menjadi cakupannya.
+func GetTopikUsulan(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	userID := c.GetUint("user_id")
+
+	query := db.Model(&model.TopikUsulan{}).Order("id DESC")
+	if v := c.Query("status"); v != "" {
+		query = query.Where("status = ?", v)
+	}
+
+	var usulan []model.TopikUsulan
+	if !subject.Can(policy.KelompokView) {
+		kelompok, err := memberKelompok(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kelompok"})
+			return
+		}
+		ids := []uint{}
+		for _, k := range kelompok {
+			ids = append(ids, k.ID)
+		}
+		if err := query.Where("kelompok_id IN ? OR dosen_id = ?", ids, userID).Find(&usulan).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil usulan topik"})
+			return
+		}
+	} else {
+		var all []model.TopikUsulan
+		if err := query.Find(&all).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil usulan topik"})
+			return
+		}
+		visible, err := usulanInScope(db, subject, all)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		usulan = visible
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   usulan,
+	})
+}
+
+// usulanInScope menyaring usulan yang boleh dilihat dosen: ditujukan
+// kepadanya atau kelompoknya termasuk cakupan KelompokView
+func usulanInScope(db *gorm.DB, subject *policy.Subject, usulan []model.TopikUsulan) ([]model.TopikUsulan, error) {
+	ids := []uint{}
+	for _, u := range usulan {
+		ids = append(ids, u.KelompokID)
+	}
+	var kelompok []model.Kelompok
+	if len(ids) > 0 {
+		if err := db.Where("id IN ?", ids).Find(&kelompok).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := map[uint]model.Kelompok{}
+	for _, k := range kelompok {
+		byID[k.ID] = k
+	}
+
+	inScope := kelompokScopeFilter(db, subject, policy.KelompokView)
+	out := []model.TopikUsulan{}
+	for _, u := range usulan {
+		allowed := u.DosenID == subject.UserID
+		if !allowed {
+			k, ok := byID[u.KelompokID]
+			if !ok {
+				continue
+			}
+			var err error
+			if allowed, err = inScope(k); err != nil {
+				return nil, err
+			}
+		}
+		if allowed {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+// isDosen memeriksa apakah user terdaftar dengan role Dosen
+func isDosen(db *gorm.DB, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&model.User{}).
+		Where("id = ? AND role = ?", userID, policy.RoleDosen).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// isAnggotaKelompok memeriksa apakah user adalah anggota kelompok
+func isAnggotaKelompok(db *gorm.DB, kelompokID, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&model.KelompokMahasiswa{}).
+		Where("kelompok_id = ? AND user_id = ?", kelompokID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ReviewTopikUsulan menerima atau menolak usulan topik. Boleh dilakukan oleh
+// dosen yang dituju usulan atau pengelola kelompok di prodi tersebut, tetapi
+// tidak oleh anggota kelompok pengusul.
+func ReviewTopikUsulan(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid usulan ID"})
+		return
+	}
+	var usulan model.TopikUsulan
+	if err := db.First(&usulan, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usulan topik tidak ditemukan"})
+		return
+	}
+
+	// Anggota kelompok pengusul tidak boleh menilai usulannya sendiri
+	userID := c.GetUint("user_id")
+	anggota, err := isAnggotaKelompok(db, usulan.KelompokID, userID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if anggota {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anggota kelompok tidak dapat menilai usulannya sendiri"})
+		return
+	}
+	if usulan.DosenID == userID {
+		dosen, err := isDosen(db, userID)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		if !dosen {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Usulan topik hanya bisa dinilai oleh dosen"})
+			return
+		}
+	} else {
+		subject, err := policy.Load(c)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		allowed, err := kelompokInScope(db, subject, policy.KelompokManage, usulan.KelompokID)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		if !allowed {
+			policy.Deny(c, policy.KelompokManage)
+			return
+		}
+	}
+
+	var req ReviewUsulanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	if status != usulanDiterima && status != usulanDitolak {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status harus diterima atau ditolak"})
+		return
+	}
+
+	before := usulan
+	usulan.Status = status
+	usulan.Catatan = req.Catatan
+	usulan.ReviewedBy = userID
+	if err := db.Save(&usulan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui usulan topik"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "topik.usulan_review", EntityType: "topik_usulan", EntityID: usulan.ID, Before: before, After: usulan})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Usulan topik " + status,
+		"data":    usulan,
+	})
+}
+
+// SetTopikPilihan menyimpan urutan topik pilihan kelompok. Pilihan lama
+// diganti seluruhnya. Topik harus dibuka dan ditawarkan untuk angkatan
+// kelompok tersebut.
+func SetTopikPilihan(c *gin.Context) {
+	var req TopikPilihanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	kelompok, err := resolveKelompokID(db, c.GetUint("user_id"), req.KelompokID)
+	if errors.Is(err, errBelumBerkelompok) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mahasiswa belum tergabung dalam kelompok"})
+		return
+	}
+	if err != nil {
+		respondKelompokContextError(c, err)
+		return
+	}
+
+	seen := map[uint]bool{}
+	for _, id := range req.TopikIDs {
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Topik " + strconv.FormatUint(uint64(id), 10) + " dipilih lebih dari sekali"})
+			return
+		}
+		seen[id] = true
+	}
+	if len(req.TopikIDs) > 0 {
+		var count int64
+		if err := db.Model(&model.TopikDosen{}).
+			Where("id IN ? AND prodi_id = ? AND KPA_id = ? AND TM_id = ? AND status = ?",
+				req.TopikIDs, kelompok.ProdiID, kelompok.KPAID, kelompok.TMID, topikDibuka).
+			Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa topik"})
+			return
+		}
+		if int(count) != len(req.TopikIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Sebagian topik tidak dibuka untuk angkatan kelompok ini"})
+			return
+		}
+	}
+
+	pilihan := make([]model.TopikPilihan, 0, len(req.TopikIDs))
+	for i, id := range req.TopikIDs {
+		pilihan = append(pilihan, model.TopikPilihan{KelompokID: kelompok.ID, TopikID: id, Peringkat: i + 1})
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kelompok_id = ?", kelompok.ID).Delete(&model.TopikPilihan{}).Error; err != nil {
+			return err
+		}
+		if len(pilihan) == 0 {
+			return nil
+		}
+		return tx.Create(&pilihan).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan pilihan topik"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "topik.pilihan_set", EntityType: "kelompok", EntityID: kelompok.ID, After: gin.H{"topik_ids": req.TopikIDs}})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Pilihan topik disimpan",
+		"data":    pilihan,
+	})
+}
+
+// GetTopikPilihan menampilkan urutan topik pilihan kelompok mahasiswa
+func GetTopikPilihan(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	kelompok, err := resolveKelompok(c, db, c.GetUint("user_id"))
+	if errors.Is(err, errBelumBerkelompok) {
+		c.JSON(http.StatusOK, gin.H{"status": "success", "data": []interface{}{}})
+		return
+	}
+	if err != nil {
+		respondKelompokContextError(c, err)
+		return
+	}
+
+	var pilihan []model.TopikPilihan
+	if err := db.Where("kelompok_id = ?", kelompok.ID).Order("peringkat").Find(&pilihan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil pilihan topik"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"kelompok_id": kelompok.ID, "pilihan": pilihan},
+	})
+}
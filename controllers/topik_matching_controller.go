@@ -0,0 +1,628 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"gorm.io/gorm"
+)
+
+const (
+	penetapanMatching    = "matching"
+	penetapanUsulan      = "usulan"
+	penetapanKoordinator = "koordinator"
+)
+
+// MatchingRequest adalah body untuk menjalankan penetapan topik satu angkatan
+type MatchingRequest struct {
+	ProdiID uint `json:"prodi_id" binding:"required"`
+	KPAID   uint `json:"kpa_id" binding:"required"`
+	TMID    uint `json:"tm_id" binding:"required"`
+	// DryRun hanya menghitung hasil tanpa menyimpan
+	DryRun bool `json:"dry_run"`
+}
+
+// PenetapanRequest adalah body untuk mengubah hasil penetapan secara manual
+type PenetapanRequest struct {
+	TopikID uint `json:"topik_id"`
+	DosenID uint `json:"dosen_id"`
+}
+
+// kapasitasDosen menghitung sisa kapasitas bimbingan dosen dalam satu
+// angkatan sesuai config.Kelompok.MaxBimbinganDosen
+type kapasitasDosen struct {
+	max     int
+	dipakai map[uint]int
+}
+
+func newKapasitasDosen(max int) *kapasitasDosen {
+	return &kapasitasDosen{max: max, dipakai: map[uint]int{}}
+}
+
+func (k *kapasitasDosen) tersedia(dosenID uint) bool {
+	return k.max <= 0 || k.dipakai[dosenID] < k.max
+}
+
+func (k *kapasitasDosen) pakai(dosenID uint) {
+	k.dipakai[dosenID]++
+}
+
+// matchTopik menetapkan topik per putaran peringkat. Putaran pertama hanya
+// melihat pilihan utama setiap kelompok, putaran kedua pilihan kedua dari
+// kelompok yang belum mendapat topik, dan seterusnya. Jadi kelompok yang
+// mendapat pilihan ke-n tidak pernah mengambil tempat kelompok lain yang
+// memilih topik itu di peringkat lebih tinggi. Jika dalam satu putaran
+// peminat topik melebihi sisa kuota topik atau kapasitas dosen, kelompok
+// dengan id lebih kecil didahulukan; waktu menyimpan pilihan tidak
+// berpengaruh. Kelompok tanpa pilihan atau yang semua pilihannya penuh
+// dikembalikan di unassigned.
+func matchTopik(kelompokIDs []uint, pilihan map[uint][]model.TopikPilihan, kuota map[uint]int, dosenOf map[uint]uint, kapasitas *kapasitasDosen) (assigned []model.TopikPenetapan, unassigned []uint) {
+	order := append([]uint(nil), kelompokIDs...)
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	placed := map[uint]bool{}
+	for round := 0; ; round++ {
+		active := false
+		for _, kelompokID := range order {
+			choices := pilihan[kelompokID]
+			if placed[kelompokID] || round >= len(choices) {
+				continue
+			}
+			active = true
+			p := choices[round]
+			dosenID := dosenOf[p.TopikID]
+			if kuota[p.TopikID] <= 0 || !kapasitas.tersedia(dosenID) {
+				continue
+			}
+			kuota[p.TopikID]--
+			kapasitas.pakai(dosenID)
+			topikID := p.TopikID
+			assigned = append(assigned, model.TopikPenetapan{
+				KelompokID: kelompokID,
+				TopikID:    &topikID,
+				DosenID:    dosenID,
+				Peringkat:  p.Peringkat,
+				Sumber:     penetapanMatching,
+			})
+			placed[kelompokID] = true
+		}
+		if !active {
+			break
+		}
+	}
+	for _, kelompokID := range order {
+		if !placed[kelompokID] {
+			unassigned = append(unassigned, kelompokID)
+		}
+	}
+	return assigned, unassigned
+}
+
+// writePembimbing menulis dosen hasil penetapan ke tabel pembimbing. Baris
+// yang sebelumnya dibuat penetapan yang sama diperbarui. Jika kelompok
+// sudah memiliki baris pembimbing dari Laravel untuk dosen yang sama, baris
+// itu hanya dirujuk (PembimbingDibuat false); baris pembimbing dosen lain
+// tidak ditimpa dan penetapan ditolak agar kelompok tidak mendapat
+// pembimbing ganda.
+func writePembimbing(tx *gorm.DB, penetapan *model.TopikPenetapan) error {
+	now := time.Now()
+	if penetapan.PembimbingID != 0 && penetapan.PembimbingDibuat {
+		result := tx.Model(&model.Pembimbing{}).
+			Where("id = ? AND kelompok_id = ?", penetapan.PembimbingID, penetapan.KelompokID).
+			Updates(map[string]interface{}{"user_id": penetapan.DosenID, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+	}
+	penetapan.PembimbingID = 0
+	penetapan.PembimbingDibuat = false
+
+	var existing []model.Pembimbing
+	if err := tx.Where("kelompok_id = ?", penetapan.KelompokID).Order("id").Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, p := range existing {
+		if p.UserID == penetapan.DosenID {
+			penetapan.PembimbingID = p.ID
+			return nil
+		}
+	}
+	if len(existing) > 0 {
+		return ruleErrorf("Kelompok %d sudah memiliki pembimbing lain", penetapan.KelompokID)
+	}
+
+	pembimbing := model.Pembimbing{
+		UserID:     penetapan.DosenID,
+		KelompokID: penetapan.KelompokID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := tx.Create(&pembimbing).Error; err != nil {
+		return err
+	}
+	penetapan.PembimbingID = pembimbing.ID
+	penetapan.PembimbingDibuat = true
+	return nil
+}
+
+// RunTopikMatching menetapkan topik dan pembimbing untuk semua kelompok
+// aktif dalam satu angkatan. Urutannya:
+//  1. penetapan manual koordinator dipertahankan dan memakai kuota topik
+//  2. kelompok yang sudah memiliki pembimbing dari Laravel dilewati
+//  3. kelompok dengan usulan topik yang diterima dan memiliki dosen tujuan
+//     dibimbing dosen tersebut selama kapasitas dosen masih ada
+//  4. kelompok lain mendapat topik dosen sesuai pilihan (matchTopik)
+//
+// Kapasitas dosen (KELOMPOK_MAX_BIMBINGAN_DOSEN) dihitung dari semua
+// langkah di atas.
+//
+// Hasil penetapan otomatis sebelumnya diganti seluruhnya.
+func RunTopikMatching(c *gin.Context) {
+	var req MatchingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	allowed, err := subject.CanForCohort(db, policy.TopikMatch, req.ProdiID, req.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if !allowed {
+		policy.Deny(c, policy.TopikMatch)
+		return
+	}
+
+	var kelompok []model.Kelompok
+	if err := db.Where("prodi_id = ? AND KPA_id = ? AND TM_id = ? AND status = ?",
+		req.ProdiID, req.KPAID, req.TMID, config.Kelompok.StatusAktif).
+		Order("id").Find(&kelompok).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kelompok"})
+		return
+	}
+	if len(kelompok) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tidak ada kelompok aktif di angkatan ini"})
+		return
+	}
+	kelompokIDs := make([]uint, 0, len(kelompok))
+	for _, k := range kelompok {
+		kelompokIDs = append(kelompokIDs, k.ID)
+	}
+
+	var existing []model.TopikPenetapan
+	var topik []model.TopikDosen
+	var usulan []model.TopikUsulan
+	var pilihanRows []model.TopikPilihan
+	if err := db.Where("kelompok_id IN ?", kelompokIDs).Find(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data penetapan"})
+		return
+	}
+	if err := db.Where("prodi_id = ? AND KPA_id = ? AND TM_id = ? AND status = ?",
+		req.ProdiID, req.KPAID, req.TMID, topikDibuka).Find(&topik).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data topik"})
+		return
+	}
+	if err := db.Where("kelompok_id IN ? AND status = ? AND dosen_id <> 0", kelompokIDs, usulanDiterima).
+		Order("updated_at DESC").Find(&usulan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil usulan topik"})
+		return
+	}
+	if err := db.Where("kelompok_id IN ?", kelompokIDs).Order("kelompok_id, peringkat").Find(&pilihanRows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil pilihan topik"})
+		return
+	}
+
+	var pembimbingRows []model.Pembimbing
+	if err := db.Where("kelompok_id IN ?", kelompokIDs).Find(&pembimbingRows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data pembimbing"})
+		return
+	}
+
+	kuota := map[uint]int{}
+	dosenOf := map[uint]uint{}
+	for _, t := range topik {
+		kuota[t.ID] = t.Kuota
+		dosenOf[t.ID] = t.UserID
+	}
+
+	kapasitas := newKapasitasDosen(config.Kelompok.MaxBimbinganDosen)
+	done := map[uint]bool{}
+	previous := map[uint]model.TopikPenetapan{}
+	owned := map[uint]bool{}
+	for _, p := range existing {
+		if p.PembimbingID != 0 && p.PembimbingDibuat {
+			owned[p.PembimbingID] = true
+		}
+		if p.Sumber == penetapanKoordinator {
+			done[p.KelompokID] = true
+			kapasitas.pakai(p.DosenID)
+			if p.TopikID != nil {
+				kuota[*p.TopikID]--
+			}
+			continue
+		}
+		previous[p.KelompokID] = p
+	}
+
+	// Kelompok yang sudah punya pembimbing dari Laravel tidak ikut matching;
+	// dosennya tetap dihitung ke kapasitas bimbingan
+	var sudahAdaPembimbing []uint
+	for _, p := range pembimbingRows {
+		if owned[p.ID] || done[p.KelompokID] {
+			continue
+		}
+		done[p.KelompokID] = true
+		kapasitas.pakai(p.UserID)
+		sudahAdaPembimbing = append(sudahAdaPembimbing, p.KelompokID)
+	}
+
+	// Usulan terbaru per kelompok; kelompok diproses berurutan id seperti
+	// matchTopik. Usulan yang dosennya sudah penuh tidak ditetapkan dan
+	// kelompoknya ikut matching pilihan topik.
+	usulanOf := map[uint]model.TopikUsulan{}
+	for _, u := range usulan {
+		if _, ok := usulanOf[u.KelompokID]; !ok {
+			usulanOf[u.KelompokID] = u
+		}
+	}
+	var results []model.TopikPenetapan
+	var usulanPenuh []uint
+	for _, id := range kelompokIDs {
+		u, ok := usulanOf[id]
+		if !ok || done[id] {
+			continue
+		}
+		if !kapasitas.tersedia(u.DosenID) {
+			usulanPenuh = append(usulanPenuh, id)
+			continue
+		}
+		kapasitas.pakai(u.DosenID)
+		usulanID := u.ID
+		results = append(results, model.TopikPenetapan{
+			KelompokID: id,
+			UsulanID:   &usulanID,
+			DosenID:    u.DosenID,
+			Sumber:     penetapanUsulan,
+		})
+		done[id] = true
+	}
+
+	pilihan := map[uint][]model.TopikPilihan{}
+	for _, p := range pilihanRows {
+		pilihan[p.KelompokID] = append(pilihan[p.KelompokID], p)
+	}
+	order := []uint{}
+	var tanpaPilihan []uint
+	for _, id := range kelompokIDs {
+		if done[id] {
+			continue
+		}
+		if len(pilihan[id]) == 0 {
+			tanpaPilihan = append(tanpaPilihan, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	matched, penuh := matchTopik(order, pilihan, kuota, dosenOf, kapasitas)
+	results = append(results, matched...)
+
+	createdBy := c.GetUint("user_id")
+	for i := range results {
+		results[i].CreatedBy = createdBy
+		// Baris pembimbing dari penetapan sebelumnya dipakai ulang
+		results[i].PembimbingID = previous[results[i].KelompokID].PembimbingID
+		results[i].PembimbingDibuat = previous[results[i].KelompokID].PembimbingDibuat
+	}
+
+	if !req.DryRun {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			assigned := map[uint]bool{}
+			for _, r := range results {
+				assigned[r.KelompokID] = true
+			}
+			for _, p := range previous {
+				if !assigned[p.KelompokID] && p.PembimbingID != 0 && p.PembimbingDibuat {
+					if err := tx.Delete(&model.Pembimbing{}, p.PembimbingID).Error; err != nil {
+						return err
+					}
+				}
+				if err := tx.Delete(&p).Error; err != nil {
+					return err
+				}
+			}
+			for i := range results {
+				if err := writePembimbing(tx, &results[i]); err != nil {
+					return err
+				}
+			}
+			if len(results) == 0 {
+				return nil
+			}
+			return tx.Create(&results).Error
+		})
+		if err != nil {
+			respondKelompokError(c, err, "Gagal menyimpan hasil penetapan")
+			return
+		}
+		audit.Track(c, audit.Change{
+			Action:     "topik.matching",
+			EntityType: "prodi",
+			EntityID:   req.ProdiID,
+			Detail: fmt.Sprintf("kpa=%d tm=%d ditetapkan=%d tanpa_pilihan=%v pilihan_penuh=%v usulan_penuh=%v sudah_ada_pembimbing=%v",
+				req.KPAID, req.TMID, len(results), tanpaPilihan, penuh, usulanPenuh, sudahAdaPembimbing),
+		})
+	}
+
+	peringkat := map[string]int{}
+	for _, r := range results {
+		key := r.Sumber
+		if r.Sumber == penetapanMatching {
+			key = "pilihan_" + strconv.Itoa(r.Peringkat)
+		}
+		peringkat[key]++
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": fmt.Sprintf("%d kelompok ditetapkan", len(results)),
+		"data": gin.H{
+			"dry_run":              req.DryRun,
+			"penetapan":            results,
+			"tanpa_pilihan":        tanpaPilihan,
+			"pilihan_penuh":        penuh,
+			"usulan_penuh":         usulanPenuh,
+			"sudah_ada_pembimbing": sudahAdaPembimbing,
+			"penetapan_manual":     len(existing) - len(previous),
+			"ringkasan_peringkat":  peringkat,
+		},
+	})
+}
+
+// GetTopikPenetapan menampilkan hasil penetapan topik dan pembimbing.
+// Filter: prodi_id, kpa_id, tm_id.
+func GetTopikPenetapan(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+
+	var kelompok []model.Kelompok
+	if err := cohortFilter(c, db.Model(&model.Kelompok{})).Find(&kelompok).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kelompok"})
+		return
+	}
+	inScope := kelompokScopeFilter(db, subject, policy.KelompokView)
+	ids := []uint{}
+	for _, k := range kelompok {
+		allowed, err := inScope(k)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+			return
+		}
+		if allowed {
+			ids = append(ids, k.ID)
+		}
+	}
+
+	penetapan := []model.TopikPenetapan{}
+	if len(ids) > 0 {
+		if err := db.Where("kelompok_id IN ?", ids).Order("kelompok_id").Find(&penetapan).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil hasil penetapan"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   penetapan,
+	})
+}
+
+// SetTopikPenetapan mengganti hasil penetapan satu kelompok secara manual.
+// Penetapan manual tidak diubah oleh matching berikutnya. Kuota topik tidak
+// membatasi koordinator.
+func SetTopikPenetapan(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	kelompokID, err := strconv.ParseUint(c.Param("kelompok_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid kelompok ID"})
+		return
+	}
+	var kelompok model.Kelompok
+	if err := db.First(&kelompok, kelompokID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan"})
+		return
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	allowed, err := subject.CanForCohort(db, policy.TopikMatch, kelompok.ProdiID, kelompok.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if !allowed {
+		policy.Deny(c, policy.TopikMatch)
+		return
+	}
+
+	var req PenetapanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	penetapan := model.TopikPenetapan{
+		KelompokID: kelompok.ID,
+		DosenID:    req.DosenID,
+		Sumber:     penetapanKoordinator,
+		CreatedBy:  c.GetUint("user_id"),
+	}
+	if req.TopikID != 0 {
+		var topik model.TopikDosen
+		if err := db.First(&topik, req.TopikID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topik tidak ditemukan"})
+			return
+		}
+		if topik.ProdiID != kelompok.ProdiID || topik.KPAID != kelompok.KPAID || topik.TMID != kelompok.TMID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Topik tidak ditawarkan untuk angkatan kelompok ini"})
+			return
+		}
+		penetapan.TopikID = &topik.ID
+		if penetapan.DosenID == 0 {
+			penetapan.DosenID = topik.UserID
+		}
+		var pilihan model.TopikPilihan
+		if err := db.Where("kelompok_id = ? AND topik_id = ?", kelompok.ID, topik.ID).Limit(1).Find(&pilihan).Error; err == nil {
+			penetapan.Peringkat = pilihan.Peringkat
+		}
+	}
+	if penetapan.DosenID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dosen_id atau topik_id wajib diisi"})
+		return
+	}
+	var dosen model.User
+	if err := db.Where("id = ?", penetapan.DosenID).Limit(1).Find(&dosen).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa dosen"})
+		return
+	}
+	if dosen.ID != 0 && dosen.Role != policy.RoleDosen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User tersebut bukan dosen"})
+		return
+	}
+
+	var before model.TopikPenetapan
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kelompok_id = ?", kelompok.ID).Limit(1).Find(&before).Error; err != nil {
+			return err
+		}
+		penetapan.PembimbingID = before.PembimbingID
+		penetapan.PembimbingDibuat = before.PembimbingDibuat
+		if err := writePembimbing(tx, &penetapan); err != nil {
+			return err
+		}
+		if before.ID != 0 {
+			penetapan.ID = before.ID
+			penetapan.CreatedAt = before.CreatedAt
+			return tx.Save(&penetapan).Error
+		}
+		return tx.Create(&penetapan).Error
+	})
+	if err != nil {
+		respondKelompokError(c, err, "Gagal menyimpan penetapan")
+		return
+	}
+
+	var auditBefore interface{}
+	if before.ID != 0 {
+		auditBefore = before
+	}
+	audit.Track(c, audit.Change{Action: "topik.penetapan_override", EntityType: "kelompok", EntityID: kelompok.ID, Before: auditBefore, After: penetapan})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Penetapan pembimbing diperbarui",
+		"data":    penetapan,
+	})
+}
+
+// errPenetapanTidakAda dipakai DeleteTopikPenetapan saat kelompok belum
+// memiliki penetapan
+var errPenetapanTidakAda = errors.New("penetapan tidak ditemukan")
+
+// DeleteTopikPenetapan menghapus penetapan satu kelompok beserta baris
+// pembimbing yang dibuatnya, misalnya untuk melepas penetapan manual agar
+// kelompok ikut matching berikutnya
+func DeleteTopikPenetapan(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+	kelompokID, err := strconv.ParseUint(c.Param("kelompok_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid kelompok ID"})
+		return
+	}
+	var kelompok model.Kelompok
+	if err := db.First(&kelompok, kelompokID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok tidak ditemukan"})
+		return
+	}
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	allowed, err := subject.CanForCohort(db, policy.TopikMatch, kelompok.ProdiID, kelompok.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if !allowed {
+		policy.Deny(c, policy.TopikMatch)
+		return
+	}
+
+	var penetapan model.TopikPenetapan
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kelompok_id = ?", kelompok.ID).First(&penetapan).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errPenetapanTidakAda
+			}
+			return err
+		}
+		if penetapan.PembimbingID != 0 && penetapan.PembimbingDibuat {
+			if err := tx.Delete(&model.Pembimbing{}, penetapan.PembimbingID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&penetapan).Error
+	})
+	if errors.Is(err, errPenetapanTidakAda) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kelompok belum memiliki penetapan"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus penetapan"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "topik.penetapan_delete", EntityType: "kelompok", EntityID: kelompok.ID, Before: penetapan})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Penetapan dihapus",
+	})
+}
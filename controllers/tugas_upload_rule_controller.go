@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"github.com/rudychandra/lagi/policy"
+	"github.com/rudychandra/lagi/utils"
+	"gorm.io/gorm"
+)
+
+// tugasUploadLimits mengembalikan allow-list MIME dan ukuran maksimal untuk sebuah tugas.
+// Nilai yang tidak diatur pada tugas_upload_rules diambil dari konfigurasi upload.
+func tugasUploadLimits(db *gorm.DB, tugasID uint) ([]string, int64) {
+	allowed := config.Upload.TugasAllowedMIME
+	maxSize := config.Upload.MaxTugasSize
+
+	var rule model.TugasUploadRule
+	if err := db.Where("tugas_id = ?", tugasID).First(&rule).Error; err == nil {
+		if list := config.SplitList(rule.AllowedMIME); len(list) > 0 {
+			allowed = list
+		}
+		if rule.MaxSize > 0 {
+			maxSize = rule.MaxSize
+		}
+	}
+	return allowed, maxSize
+}
+
+// GetTugasUploadRule menampilkan aturan upload yang berlaku untuk sebuah tugas
+func GetTugasUploadRule(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	tugasID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tugas ID"})
+		return
+	}
+
+	allowed, maxSize := tugasUploadLimits(db, uint(tugasID))
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"tugas_id":     tugasID,
+			"allowed_mime": allowed,
+			"max_size":     maxSize,
+		},
+	})
+}
+
+// SetTugasUploadRule membuat atau memperbarui aturan upload sebuah tugas.
+// Permission tugas:manage_upload_rule diperiksa di route.
+func SetTugasUploadRule(c *gin.Context) {
+	db, err := config.GetDB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	tugasID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tugas ID"})
+		return
+	}
+
+	var request struct {
+		AllowedMIME []string `json:"allowed_mime"`
+		MaxSizeMB   int64    `json:"max_size_mb"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.MaxSizeMB < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_size_mb tidak boleh negatif"})
+		return
+	}
+	allowedMIME, err := utils.NormalizeMIMEList(request.AllowedMIME)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tugas model.Tugas
+	if err := db.First(&tugas, tugasID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tugas tidak ditemukan"})
+		return
+	}
+
+	// Koordinator hanya boleh mengatur tugas di prodi dan jenis PA-nya
+	subject, err := policy.Load(c)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	allowed, err := subject.CanForCohort(db, policy.TugasManageUploadRule, tugas.ProdiID, tugas.KPAID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return
+	}
+	if !allowed {
+		policy.Deny(c, policy.TugasManageUploadRule)
+		return
+	}
+
+	var rule model.TugasUploadRule
+	if err := db.Where("tugas_id = ?", tugasID).First(&rule).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil aturan upload"})
+		return
+	}
+	before := rule
+	rule.TugasID = uint(tugasID)
+	rule.AllowedMIME = strings.Join(allowedMIME, ",")
+	rule.MaxSize = request.MaxSizeMB * 1024 * 1024
+
+	if err := db.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan aturan upload"})
+		return
+	}
+	audit.Track(c, audit.Change{Action: "tugas.upload_rule", EntityType: "tugas", EntityID: tugasID, Before: before, After: rule})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Aturan upload diperbarui",
+		"data":    rule,
+	})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/storage"
+	"github.com/rudychandra/lagi/utils"
+)
+
+// checkUpload memvalidasi ukuran dan isi file, lalu memindainya dengan scanner aktif.
+// File yang terdeteksi berbahaya dikarantina. Jika validasi gagal, response error
+// sudah ditulis dan fungsi mengembalikan ok=false.
+func checkUpload(c *gin.Context, file *multipart.FileHeader, allowed []string, maxSize int64) (*mimetype.MIME, bool) {
+	detected, err := utils.ValidateUpload(file, allowed, maxSize)
+	switch {
+	case errors.Is(err, utils.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Ukuran file maksimal %dMB", maxSize/(1024*1024)),
+		})
+		return nil, false
+	case errors.Is(err, utils.ErrMIMENotAllowed):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":    "Tipe file tidak diizinkan",
+			"detected": detected.String(),
+			"allowed":  allowed,
+		})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File tidak dapat dibaca"})
+		return nil, false
+	}
+
+	result, err := utils.ScanUpload(c.Request.Context(), file)
+	if err != nil {
+		if !config.Upload.ScanFailOpen {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Pemindaian file tidak tersedia, coba lagi nanti"})
+			return nil, false
+		}
+	} else if !result.Clean {
+		if _, qErr := utils.QuarantineUpload(c.Request.Context(), file, result.Signature); qErr != nil {
+			fmt.Printf("Failed to quarantine upload: %v\n", qErr)
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     "File terdeteksi berbahaya dan telah dikarantina",
+			"signature": result.Signature,
+		})
+		return nil, false
+	}
+
+	return detected, true
+}
+
+// limitRequestBody membatasi ukuran body request lalu mem-parse form upload.
+// Body yang melebihi batas dijawab 413 (http.MaxBytesError), form yang rusak
+// 400. Mengembalikan false jika response error sudah ditulis.
+func limitRequestBody(c *gin.Context, maxSize int64) bool {
+	if maxSize > 0 {
+		// Tambahan 1MB untuk field form dan boundary multipart
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1024*1024)
+	}
+
+	_, err := c.MultipartForm()
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = c.Request.ParseForm()
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Ukuran upload maksimal %dMB", maxSize/(1024*1024)),
+		})
+		return false
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form upload tidak valid"})
+		return false
+	}
+	return true
+}
+
+// saveUpload menyimpan file upload ke storage dengan key yang diberikan
+func saveUpload(c *gin.Context, store storage.Storage, key string, file *multipart.FileHeader, contentType string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return store.Put(c.Request.Context(), key, src, file.Size, contentType)
+}
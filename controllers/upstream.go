@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/upstream"
+)
+
+var (
+	// upstreams adalah konfigurasi layanan eksternal yang dipakai controller
+	upstreams config.UpstreamsConfig
+
+	cisAuthClient    = http.DefaultClient
+	cisStudentClient = http.DefaultClient
+	laravelClient    = http.DefaultClient
+)
+
+// ConfigureUpstreams menyiapkan URL dan HTTP client layanan eksternal (CIS,
+// Laravel) untuk controller. Dipanggil sekali saat aplikasi dijalankan.
+func ConfigureUpstreams(cfg config.UpstreamsConfig) error {
+	authClient, err := upstream.NewClient(cfg.CISAuth)
+	if err != nil {
+		return err
+	}
+	studentClient, err := upstream.NewClient(cfg.CISStudent)
+	if err != nil {
+		return err
+	}
+
+	webClient, err := upstream.NewClient(cfg.Laravel)
+	if err != nil {
+		return err
+	}
+
+	upstreams = cfg
+	cisAuthClient = authClient
+	cisStudentClient = studentClient
+	laravelClient = webClient
+	return nil
+}
@@ -0,0 +1,377 @@
+// Package grouping membagi mahasiswa ke dalam kelompok dengan ukuran yang
+// rata, nilai (IPK atau skor keahlian) yang seimbang antar kelompok, dan
+// menghormati pasangan yang harus sekelompok atau harus dipisah. Package ini
+// tidak menyentuh database; penyimpanan dilakukan oleh controller.
+package grouping
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxSwapRounds membatasi putaran perbaikan dengan menukar anggota
+const maxSwapRounds = 50
+
+// Student adalah mahasiswa yang akan dibagi beserta nilai penyeimbangnya
+type Student struct {
+	UserID uint    `json:"user_id"`
+	Nilai  float64 `json:"nilai"`
+}
+
+// Pair adalah dua mahasiswa yang harus sekelompok atau harus dipisah
+type Pair [2]uint
+
+// Input adalah data pembagian kelompok
+type Input struct {
+	Students []Student `json:"mahasiswa"`
+	// Size adalah ukuran kelompok yang diinginkan; jumlah kelompok adalah
+	// jumlah mahasiswa dibagi Size, dibulatkan ke atas
+	Size     int    `json:"ukuran"`
+	Together []Pair `json:"bersama"`
+	Apart    []Pair `json:"pisah"`
+}
+
+// GroupCount mengembalikan jumlah kelompok yang akan dibentuk
+func (in Input) GroupCount() int {
+	if in.Size < 1 || len(in.Students) == 0 {
+		return 0
+	}
+	return (len(in.Students) + in.Size - 1) / in.Size
+}
+
+type cluster struct {
+	members []uint
+	total   float64
+}
+
+// clusters menggabungkan mahasiswa yang harus sekelompok
+func clusters(in Input) ([]cluster, error) {
+	nilai := map[uint]float64{}
+	parent := map[uint]uint{}
+	for _, s := range in.Students {
+		if _, dup := nilai[s.UserID]; dup {
+			return nil, fmt.Errorf("mahasiswa %d tercantum lebih dari sekali", s.UserID)
+		}
+		nilai[s.UserID] = s.Nilai
+		parent[s.UserID] = s.UserID
+	}
+
+	var find func(uint) uint
+	find = func(id uint) uint {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for _, p := range in.Together {
+		for _, id := range p {
+			if _, ok := parent[id]; !ok {
+				return nil, fmt.Errorf("mahasiswa %d pada pasangan bersama tidak ada di daftar", id)
+			}
+		}
+		parent[find(p[0])] = find(p[1])
+	}
+
+	byRoot := map[uint]*cluster{}
+	var roots []uint
+	for _, s := range in.Students {
+		root := find(s.UserID)
+		cl, ok := byRoot[root]
+		if !ok {
+			cl = &cluster{}
+			byRoot[root] = cl
+			roots = append(roots, root)
+		}
+		cl.members = append(cl.members, s.UserID)
+		cl.total += s.Nilai
+	}
+
+	for _, p := range in.Apart {
+		for _, id := range p {
+			if _, ok := parent[id]; !ok {
+				return nil, fmt.Errorf("mahasiswa %d pada pasangan pisah tidak ada di daftar", id)
+			}
+		}
+		if p[0] == p[1] || find(p[0]) == find(p[1]) {
+			return nil, fmt.Errorf("mahasiswa %d dan %d harus sekelompok sekaligus dipisah", p[0], p[1])
+		}
+	}
+
+	out := make([]cluster, 0, len(roots))
+	for _, root := range roots {
+		cl := byRoot[root]
+		if len(cl.members) > in.Size {
+			return nil, fmt.Errorf("%d mahasiswa yang harus sekelompok melebihi ukuran kelompok %d", len(cl.members), in.Size)
+		}
+		out = append(out, *cl)
+	}
+	return out, nil
+}
+
+// apartSet menyimpan pasangan pisah untuk pencarian cepat
+type apartSet map[[2]uint]bool
+
+func newApartSet(pairs []Pair) apartSet {
+	set := apartSet{}
+	for _, p := range pairs {
+		set[[2]uint{p[0], p[1]}] = true
+		set[[2]uint{p[1], p[0]}] = true
+	}
+	return set
+}
+
+func (a apartSet) conflicts(members, others []uint) bool {
+	for _, m := range members {
+		for _, o := range others {
+			if a[[2]uint{m, o}] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type group struct {
+	members []uint
+	total   float64
+}
+
+// Form membagi mahasiswa menjadi kelompok. Kelompok pertama-tama diisi
+// secara greedy: kelompok mahasiswa yang harus bersama ditempatkan lebih
+// dulu, masing-masing ke kelompok dengan total nilai terendah yang masih
+// muat. Setelah itu anggota yang tidak terikat pasangan bersama ditukar
+// selama selisih rata-rata nilai antar kelompok mengecil.
+func Form(in Input) ([][]uint, error) {
+	if len(in.Students) == 0 {
+		return nil, fmt.Errorf("daftar mahasiswa kosong")
+	}
+	if in.Size < 1 {
+		return nil, fmt.Errorf("ukuran kelompok minimal 1")
+	}
+	cls, err := clusters(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// Yang besar dan bernilai tinggi ditempatkan lebih dulu agar sisa ruang
+	// bisa dipakai untuk menyeimbangkan
+	sort.SliceStable(cls, func(i, j int) bool {
+		if len(cls[i].members) != len(cls[j].members) {
+			return len(cls[i].members) > len(cls[j].members)
+		}
+		return cls[i].total > cls[j].total
+	})
+
+	n := len(in.Students)
+	count := in.GroupCount()
+	groups := make([]group, count)
+	// Target ukuran dibuat serata mungkin; jika kelompok bersama tidak muat,
+	// ukuran boleh naik sampai Size
+	target := make([]int, count)
+	for i := range target {
+		target[i] = n / count
+		if i < n%count {
+			target[i]++
+		}
+	}
+	apart := newApartSet(in.Apart)
+
+	for _, cl := range cls {
+		best := -1
+		for _, limit := range []func(int) int{
+			func(i int) int { return target[i] },
+			func(int) int { return in.Size },
+		} {
+			for i := range groups {
+				g := groups[i]
+				if len(g.members)+len(cl.members) > limit(i) || apart.conflicts(cl.members, g.members) {
+					continue
+				}
+				if best < 0 || g.total < groups[best].total ||
+					(g.total == groups[best].total && len(g.members) < len(groups[best].members)) {
+					best = i
+				}
+			}
+			if best >= 0 {
+				break
+			}
+		}
+		if best < 0 {
+			return nil, fmt.Errorf("mahasiswa %v tidak dapat ditempatkan tanpa melanggar batasan", cl.members)
+		}
+		groups[best].members = append(groups[best].members, cl.members...)
+		groups[best].total += cl.total
+	}
+
+	improve(groups, in, apart)
+
+	out := make([][]uint, 0, len(groups))
+	for _, g := range groups {
+		if len(g.members) > 0 {
+			out = append(out, g.members)
+		}
+	}
+	return out, nil
+}
+
+// spread adalah jumlah kuadrat selisih rata-rata nilai tiap kelompok
+// terhadap rata-rata keseluruhan
+func spread(groups []group) float64 {
+	var total float64
+	var n int
+	for _, g := range groups {
+		total += g.total
+		n += len(g.members)
+	}
+	if n == 0 {
+		return 0
+	}
+	mean := total / float64(n)
+	var s float64
+	for _, g := range groups {
+		if len(g.members) == 0 {
+			continue
+		}
+		d := g.total/float64(len(g.members)) - mean
+		s += d * d
+	}
+	return s
+}
+
+// improve menukar dua anggota bebas dari kelompok berbeda jika pertukaran
+// membuat nilai antar kelompok lebih seimbang
+func improve(groups []group, in Input, apart apartSet) {
+	nilai := map[uint]float64{}
+	for _, s := range in.Students {
+		nilai[s.UserID] = s.Nilai
+	}
+	bound := map[uint]bool{}
+	for _, p := range in.Together {
+		bound[p[0]], bound[p[1]] = true, true
+	}
+	without := func(members []uint, skip int) []uint {
+		out := make([]uint, 0, len(members)-1)
+		out = append(out, members[:skip]...)
+		return append(out, members[skip+1:]...)
+	}
+
+	current := spread(groups)
+	for round := 0; round < maxSwapRounds; round++ {
+		improved := false
+		for a := range groups {
+			for b := a + 1; b < len(groups); b++ {
+				for i := 0; i < len(groups[a].members); i++ {
+					x := groups[a].members[i]
+					if bound[x] {
+						continue
+					}
+					for j := 0; j < len(groups[b].members); j++ {
+						y := groups[b].members[j]
+						if bound[y] || nilai[x] == nilai[y] {
+							continue
+						}
+						if apart.conflicts([]uint{x}, without(groups[b].members, j)) ||
+							apart.conflicts([]uint{y}, without(groups[a].members, i)) {
+							continue
+						}
+						delta := nilai[y] - nilai[x]
+						groups[a].total += delta
+						groups[b].total -= delta
+						if s := spread(groups); s < current-1e-9 {
+							groups[a].members[i], groups[b].members[j] = y, x
+							current = s
+							improved = true
+							x = y
+							continue
+						}
+						groups[a].total -= delta
+						groups[b].total += delta
+					}
+				}
+			}
+		}
+		if !improved {
+			return
+		}
+	}
+}
+
+// Validate memeriksa pembagian kelompok terhadap daftar mahasiswa dan
+// batasan input. Mengembalikan daftar pelanggaran; kosong berarti valid.
+func Validate(in Input, groups [][]uint, maxSize int) []string {
+	var violations []string
+	known := map[uint]bool{}
+	for _, s := range in.Students {
+		known[s.UserID] = true
+	}
+
+	groupOf := map[uint]int{}
+	for i, g := range groups {
+		if len(g) == 0 {
+			violations = append(violations, fmt.Sprintf("kelompok %d kosong", i+1))
+		}
+		if len(g) > maxSize {
+			violations = append(violations, fmt.Sprintf("kelompok %d berisi %d mahasiswa, maksimal %d", i+1, len(g), maxSize))
+		}
+		for _, id := range g {
+			if !known[id] {
+				violations = append(violations, fmt.Sprintf("mahasiswa %d tidak ada di daftar", id))
+				continue
+			}
+			if prev, dup := groupOf[id]; dup {
+				violations = append(violations, fmt.Sprintf("mahasiswa %d ada di kelompok %d dan %d", id, prev+1, i+1))
+				continue
+			}
+			groupOf[id] = i
+		}
+	}
+	for _, s := range in.Students {
+		if _, ok := groupOf[s.UserID]; !ok {
+			violations = append(violations, fmt.Sprintf("mahasiswa %d belum mendapat kelompok", s.UserID))
+		}
+	}
+
+	for _, p := range in.Together {
+		a, okA := groupOf[p[0]]
+		b, okB := groupOf[p[1]]
+		if okA && okB && a != b {
+			violations = append(violations, fmt.Sprintf("mahasiswa %d dan %d harus sekelompok", p[0], p[1]))
+		}
+	}
+	for _, p := range in.Apart {
+		a, okA := groupOf[p[0]]
+		b, okB := groupOf[p[1]]
+		if okA && okB && a == b {
+			violations = append(violations, fmt.Sprintf("mahasiswa %d dan %d harus dipisah", p[0], p[1]))
+		}
+	}
+	return violations
+}
+
+// Summary adalah ringkasan nilai satu kelompok untuk pratinjau
+type Summary struct {
+	Jumlah     int     `json:"jumlah"`
+	TotalNilai float64 `json:"total_nilai"`
+	RataRata   float64 `json:"rata_rata"`
+}
+
+// Summarize menghitung jumlah anggota dan rata-rata nilai tiap kelompok
+func Summarize(in Input, groups [][]uint) []Summary {
+	nilai := map[uint]float64{}
+	for _, s := range in.Students {
+		nilai[s.UserID] = s.Nilai
+	}
+	out := make([]Summary, len(groups))
+	for i, g := range groups {
+		var total float64
+		for _, id := range g {
+			total += nilai[id]
+		}
+		out[i] = Summary{Jumlah: len(g), TotalNilai: total}
+		if len(g) > 0 {
+			out[i].RataRata = math.Round(total/float64(len(g))*100) / 100
+		}
+	}
+	return out
+}
@@ -0,0 +1,222 @@
+package grouping
+
+import (
+	"strings"
+	"testing"
+)
+
+// students membuat daftar mahasiswa dengan id 1..n dan nilai sesuai urutan
+func students(nilai ...float64) []Student {
+	out := make([]Student, len(nilai))
+	for i, n := range nilai {
+		out[i] = Student{UserID: uint(i + 1), Nilai: n}
+	}
+	return out
+}
+
+func TestFormSatisfiesConstraints(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     Input
+		groups int
+	}{
+		{
+			name:   "pas dibagi rata",
+			in:     Input{Students: students(3.9, 3.1, 2.5, 3.5, 2.8, 3.0), Size: 3},
+			groups: 2,
+		},
+		{
+			name:   "sisa dibagi ke kelompok awal",
+			in:     Input{Students: students(3.9, 3.1, 2.5, 3.5, 2.8, 3.0, 3.3), Size: 3},
+			groups: 3,
+		},
+		{
+			name:   "ukuran satu",
+			in:     Input{Students: students(3, 2, 1), Size: 1},
+			groups: 3,
+		},
+		{
+			name: "pasangan bersama",
+			in: Input{
+				Students: students(4, 4, 1, 1, 2, 3),
+				Size:     3,
+				Together: []Pair{{1, 2}, {2, 3}},
+			},
+			groups: 2,
+		},
+		{
+			name: "pasangan pisah",
+			in: Input{
+				Students: students(4, 3.9, 1, 1.1, 2, 2.1),
+				Size:     2,
+				Apart:    []Pair{{1, 2}, {3, 4}, {5, 6}},
+			},
+			groups: 3,
+		},
+		{
+			name: "bersama dan pisah",
+			in: Input{
+				Students: students(3.8, 3.2, 2.9, 3.4, 2.6, 3.0, 3.6, 2.7),
+				Size:     4,
+				Together: []Pair{{1, 5}, {2, 6}},
+				Apart:    []Pair{{1, 2}, {5, 7}},
+			},
+			groups: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := Form(tt.in)
+			if err != nil {
+				t.Fatalf("Form: %v", err)
+			}
+			if len(groups) != tt.groups {
+				t.Fatalf("jumlah kelompok %d, want %d", len(groups), tt.groups)
+			}
+			if v := Validate(tt.in, groups, tt.in.Size); len(v) > 0 {
+				t.Fatalf("pelanggaran: %v", v)
+			}
+			// Tanpa pasangan bersama, ukuran kelompok selisih paling banyak satu
+			if len(tt.in.Together) == 0 {
+				min, max := len(groups[0]), len(groups[0])
+				for _, g := range groups {
+					if len(g) < min {
+						min = len(g)
+					}
+					if len(g) > max {
+						max = len(g)
+					}
+				}
+				if max-min > 1 {
+					t.Fatalf("ukuran kelompok tidak rata: %v", groups)
+				}
+			}
+		})
+	}
+}
+
+func TestFormErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Input
+		want string
+	}{
+		{"daftar kosong", Input{Size: 3}, "kosong"},
+		{"ukuran nol", Input{Students: students(1, 2), Size: 0}, "ukuran kelompok minimal"},
+		{"mahasiswa ganda", Input{Students: append(students(1, 2), Student{UserID: 1}), Size: 2}, "lebih dari sekali"},
+		{"pasangan bersama tidak dikenal", Input{Students: students(1, 2), Size: 2, Together: []Pair{{1, 9}}}, "pasangan bersama"},
+		{"pasangan pisah tidak dikenal", Input{Students: students(1, 2), Size: 2, Apart: []Pair{{9, 1}}}, "pasangan pisah"},
+		{"bersama sekaligus pisah", Input{Students: students(1, 2, 3), Size: 3, Together: []Pair{{1, 2}, {2, 3}}, Apart: []Pair{{1, 3}}}, "sekaligus dipisah"},
+		{"bersama melebihi ukuran", Input{Students: students(1, 2, 3, 4), Size: 2, Together: []Pair{{1, 2}, {2, 3}}}, "melebihi ukuran"},
+		{"pisah tidak mungkin dipenuhi", Input{Students: students(1, 2, 3), Size: 3, Apart: []Pair{{1, 2}}}, "tidak dapat ditempatkan"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Form(tt.in)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %v, want berisi %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestImproveReducesSpread(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     Input
+		groups []group
+		// moved bernilai false jika pertukaran tidak boleh terjadi
+		moved bool
+	}{
+		{
+			name:   "nilai tinggi terkumpul di satu kelompok",
+			in:     Input{Students: students(4, 4, 1, 1), Size: 2},
+			groups: []group{{members: []uint{1, 2}, total: 8}, {members: []uint{3, 4}, total: 2}},
+			moved:  true,
+		},
+		{
+			name: "anggota terikat pasangan bersama tidak ditukar",
+			in: Input{
+				Students: students(4, 4, 1, 1),
+				Size:     2,
+				Together: []Pair{{1, 2}, {3, 4}},
+			},
+			groups: []group{{members: []uint{1, 2}, total: 8}, {members: []uint{3, 4}, total: 2}},
+		},
+		{
+			name: "pertukaran yang melanggar pisah dilewati",
+			in: Input{
+				Students: students(4, 4, 1, 1),
+				Size:     2,
+				Apart:    []Pair{{1, 3}, {1, 4}, {2, 3}, {2, 4}},
+			},
+			groups: []group{{members: []uint{1, 2}, total: 8}, {members: []uint{3, 4}, total: 2}},
+		},
+		{
+			name:   "sudah seimbang",
+			in:     Input{Students: students(4, 1, 4, 1), Size: 2},
+			groups: []group{{members: []uint{1, 2}, total: 5}, {members: []uint{3, 4}, total: 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := spread(tt.groups)
+			improve(tt.groups, tt.in, newApartSet(tt.in.Apart))
+			after := spread(tt.groups)
+
+			if after > before {
+				t.Fatalf("spread naik dari %v ke %v", before, after)
+			}
+			if moved := after < before; moved != tt.moved {
+				t.Fatalf("spread %v -> %v, want moved=%v", before, after, tt.moved)
+			}
+			out := make([][]uint, 0, len(tt.groups))
+			for _, g := range tt.groups {
+				out = append(out, g.members)
+			}
+			if v := Validate(tt.in, out, tt.in.Size); len(v) > 0 {
+				t.Fatalf("pelanggaran setelah improve: %v", v)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	in := Input{
+		Students: students(1, 2, 3, 4),
+		Size:     2,
+		Together: []Pair{{1, 2}},
+		Apart:    []Pair{{3, 4}},
+	}
+	tests := []struct {
+		name   string
+		groups [][]uint
+		want   []string
+	}{
+		{"pisah sekelompok", [][]uint{{1, 2}, {3, 4}}, []string{"mahasiswa 3 dan 4 harus dipisah"}},
+		{"semua batasan terpenuhi", [][]uint{{1, 2}, {3}, {4}}, nil},
+		{"kelompok kosong", [][]uint{{1, 2}, {3}, {4}, {}}, []string{"kelompok 4 kosong"}},
+		{"melebihi ukuran", [][]uint{{1, 2, 3}, {4}}, []string{"maksimal 2"}},
+		{"mahasiswa tidak dikenal", [][]uint{{1, 2}, {3}, {4, 9}}, []string{"mahasiswa 9 tidak ada"}},
+		{"mahasiswa ganda", [][]uint{{1, 2}, {3, 1}, {4}}, []string{"mahasiswa 1 ada di kelompok 1 dan 2"}},
+		{"belum mendapat kelompok", [][]uint{{1, 2}, {3}}, []string{"mahasiswa 4 belum mendapat kelompok"}},
+		{"bersama dipisah", [][]uint{{1, 3}, {2, 4}}, []string{"mahasiswa 1 dan 2 harus sekelompok"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Validate(in, tt.groups, in.Size)
+			if len(got) != len(tt.want) {
+				t.Fatalf("pelanggaran %v, want %d", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Fatalf("pelanggaran %d = %q, want berisi %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -5,17 +5,67 @@ import (
 	"log"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/announcement"
+	"github.com/rudychandra/lagi/audit"
 	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/controllers"
+	middleware "github.com/rudychandra/lagi/middlewares"
+	"github.com/rudychandra/lagi/ratelimit"
 	"github.com/rudychandra/lagi/routes"
+	"github.com/rudychandra/lagi/search"
+	"github.com/rudychandra/lagi/storage"
+	"github.com/rudychandra/lagi/utils"
 )
 
 func main() {
 	// Inisialisasi koneksi ke database dan memuat konfigurasi
 	config.Connect()
+	config.Migrate()
+	config.LoadAppConfig()
+	config.LoadJWTConfig()
+	config.LoadUploadConfig()
+	config.LoadStorageConfig()
+	config.LoadSignedURLConfig()
+	config.LoadUpstreamConfig()
+	config.LoadCISVerifyConfig()
+	config.LoadRateLimitConfig()
+	config.LoadAuditConfig()
+	config.LoadKelompokConfig()
+	config.LoadPengumumanConfig()
+	config.LoadSearchConfig()
 	config.InitFirebase()
+	utils.InitScanner(config.Upload)
+	if err := utils.InitJWTKeys(config.JWT); err != nil {
+		log.Fatal("Gagal menyiapkan kunci JWT:", err)
+	}
+
+	if err := storage.Init(config.Storage, config.Upload, config.Upstreams); err != nil {
+		log.Fatal("Gagal menyiapkan storage:", err)
+	}
+	if err := ratelimit.Init(config.RateLimit); err != nil {
+		log.Fatal("Gagal menyiapkan rate limit:", err)
+	}
+	if err := controllers.ConfigureUpstreams(config.Upstreams); err != nil {
+		log.Fatal("Gagal menyiapkan upstream controller:", err)
+	}
+	if err := middleware.ConfigureUpstreams(config.Upstreams); err != nil {
+		log.Fatal("Gagal menyiapkan upstream middleware:", err)
+	}
+	middleware.ConfigureCISVerify(config.CISVerify)
+	audit.StartRetention(config.Audit)
+	announcement.StartScheduler(config.Pengumuman)
+	search.StartIndexer(config.Search)
 
 	// Set up Gin router
+	if config.App.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
 	r := gin.Default()
+	// Tanpa daftar proxy, gin mempercayai X-Forwarded-For dari siapa pun dan
+	// rate limit per IP bisa diakali dengan mengganti header tersebut
+	if err := r.SetTrustedProxies(config.App.TrustedProxies); err != nil {
+		log.Fatal("TRUSTED_PROXIES tidak valid:", err)
+	}
 	routes.SetupRouter(r)
 
 	// Port server
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/audit"
+)
+
+// Audit menulis audit log setelah handler selesai. Dipasang global agar
+// semua request tulis tercatat; data user dibaca dari context yang diisi
+// middleware autentikasi di dalam group.
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		audit.Flush(c)
+	}
+}
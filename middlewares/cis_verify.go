@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rudychandra/lagi/config"
+)
+
+var (
+	// errTokenInvalid berarti CIS menolak token (401)
+	errTokenInvalid = errors.New("cis: token invalid")
+	// errCISUnavailable berarti CIS tidak bisa memverifikasi token saat ini (503)
+	errCISUnavailable = errors.New("cis: verification unavailable")
+)
+
+// verifyEntry adalah hasil verifikasi token yang disimpan di cache
+type verifyEntry struct {
+	res       AuthResponse
+	expiresAt time.Time
+}
+
+// verifyCache menyimpan hasil verifikasi token CIS, dengan key hash token
+// agar token aslinya tidak tersimpan di memori lebih lama dari perlu
+type verifyCache struct {
+	mu      sync.Mutex
+	entries map[string]verifyEntry
+	max     int
+}
+
+func (c *verifyCache) get(key string) (AuthResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return AuthResponse{}, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return AuthResponse{}, false
+	}
+	return e.res, true
+}
+
+func (c *verifyCache) set(key string, res AuthResponse, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.max {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		// Masih penuh: buang entry sembarang, cukup untuk cache sederhana ini
+		for k := range c.entries {
+			if len(c.entries) < c.max {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = verifyEntry{res: res, expiresAt: expiresAt}
+}
+
+// circuitBreaker menghentikan request ke CIS sementara setelah beberapa
+// kegagalan berturut-turut, agar API tidak ikut macet saat CIS lambat. Setelah
+// cooldown, satu request percobaan diizinkan untuk menguji apakah CIS pulih.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// release melepas slot percobaan tanpa menilai CIS, misalnya saat client
+// membatalkan request
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+var (
+	cisCache   = &verifyCache{entries: map[string]verifyEntry{}, max: 10000}
+	cisBreaker = &circuitBreaker{threshold: 5, cooldown: 30 * time.Second}
+	cisTTL     = 5 * time.Minute
+)
+
+// ConfigureCISVerify menerapkan pengaturan cache dan circuit breaker verifikasi CIS
+func ConfigureCISVerify(cfg config.CISVerifyConfig) {
+	cisCache = &verifyCache{entries: map[string]verifyEntry{}, max: cfg.CacheSize}
+	cisBreaker = &circuitBreaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown}
+	cisTTL = cfg.CacheTTL
+}
+
+// tokenExpiry membaca klaim exp dari token CIS tanpa memverifikasi tanda
+// tangannya; verifikasi tetap dilakukan oleh CIS
+func tokenExpiry(token string) (time.Time, bool) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}, false
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}, false
+	}
+	return exp.Time, true
+}
+
+// verifyCISToken memverifikasi token ke CIS, memakai cache jika ada
+func verifyCISToken(ctx context.Context, token string) (AuthResponse, error) {
+	exp, hasExp := tokenExpiry(token)
+	if hasExp && time.Now().After(exp) {
+		return AuthResponse{}, errTokenInvalid
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if res, ok := cisCache.get(key); ok {
+		return res, nil
+	}
+
+	if !cisBreaker.allow() {
+		return AuthResponse{}, fmt.Errorf("%w: circuit open", errCISUnavailable)
+	}
+
+	res, err := callVerifyToken(ctx, token)
+	if ctx.Err() != nil {
+		// Client sudah pergi, kegagalan ini bukan kesalahan CIS
+		cisBreaker.release()
+		return AuthResponse{}, fmt.Errorf("%w: %v", errCISUnavailable, ctx.Err())
+	}
+	switch {
+	case err == nil:
+		cisBreaker.success()
+	case errors.Is(err, errCISUnavailable):
+		cisBreaker.failure()
+		return AuthResponse{}, err
+	default:
+		// CIS menjawab dengan benar walaupun token ditolak
+		cisBreaker.success()
+		return AuthResponse{}, err
+	}
+
+	expiresAt := time.Now().Add(cisTTL)
+	if hasExp && exp.Before(expiresAt) {
+		expiresAt = exp
+	}
+	cisCache.set(key, res, expiresAt)
+	return res, nil
+}
+
+func callVerifyToken(ctx context.Context, token string) (AuthResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cisAuth.URL("verify-token"), nil)
+	if err != nil {
+		return AuthResponse{}, fmt.Errorf("%w: %v", errCISUnavailable, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := cisAuthClient.Do(req)
+	if err != nil {
+		return AuthResponse{}, fmt.Errorf("%w: %v", errCISUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return AuthResponse{}, fmt.Errorf("%w: status %d", errCISUnavailable, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return AuthResponse{}, errTokenInvalid
+	}
+
+	var authRes AuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authRes); err != nil {
+		return AuthResponse{}, fmt.Errorf("%w: invalid response: %v", errCISUnavailable, err)
+	}
+	if !authRes.Result {
+		return AuthResponse{}, errTokenInvalid
+	}
+	return authRes, nil
+}
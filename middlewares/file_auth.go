@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/utils"
+)
+
+// FileAuthMiddleware menerima signed URL (query "sig") sebagai pengganti header
+// Authorization, agar PDF viewer atau download manager bisa membuka file.
+// Request tanpa signature diteruskan ke InternalAuthMiddleware.
+func FileAuthMiddleware() gin.HandlerFunc {
+	internal := InternalAuthMiddleware()
+
+	return func(c *gin.Context) {
+		if c.Query("sig") == "" {
+			internal(c)
+			return
+		}
+
+		claims, err := utils.VerifyFileURL(c.Request.URL.Path, c.Request.URL.Query(), c.ClientIP())
+		if err != nil {
+			respondSignedURLError(c, err)
+			return
+		}
+
+		// URL dari sesi yang sudah logout atau dicabut ditolak sebelum nonce
+		// sekali pakainya dipakai
+		if claims.SessionID != "" {
+			revoked, err := sessionRevoked(claims.SessionID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa signed URL"})
+				return
+			}
+			if revoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Sesi sudah berakhir, silakan login kembali"})
+				return
+			}
+		}
+
+		if err := utils.UseFileURL(c.Request.Context(), claims, c.ClientIP(), c.GetHeader("Range") != ""); err != nil {
+			respondSignedURLError(c, err)
+			return
+		}
+
+		// Simpan data user ke context, sama seperti token internal
+		c.Set("user_id", claims.UserID)
+		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		c.Set("signed_url", true)
+		if claims.ImpersonatorID != 0 {
+			c.Set("impersonator_id", claims.ImpersonatorID)
+			c.Header("X-Impersonated-By", strconv.FormatUint(uint64(claims.ImpersonatorID), 10))
+		}
+
+		c.Next()
+	}
+}
+
+// respondSignedURLError menulis response untuk signed URL yang ditolak.
+// Error selain signature tidak valid, kadaluarsa atau sudah dipakai berasal
+// dari store nonce dan dijawab 503.
+func respondSignedURLError(c *gin.Context, err error) {
+	message := "Signed URL tidak valid"
+	switch {
+	case errors.Is(err, utils.ErrSignatureInvalid):
+	case errors.Is(err, utils.ErrSignatureExpired):
+		message = "Signed URL sudah kadaluarsa"
+	case errors.Is(err, utils.ErrSignatureUsed):
+		message = "Signed URL sudah pernah digunakan"
+	default:
+		fmt.Printf("Gagal mencatat pemakaian signed URL: %v\n", err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa signed URL"})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
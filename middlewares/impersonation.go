@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/utils"
+)
+
+// impersonationSafeWrites adalah request non-GET yang tetap boleh dipakai
+// dengan token impersonasi hanya-baca karena tidak mengubah data user
+var impersonationSafeWrites = map[string]bool{
+	"POST /auth/logout": true, // mengakhiri impersonasi
+	"POST /file-links/": true, // hanya membuat signed URL untuk membaca file
+}
+
+func impersonationWriteBlocked(c *gin.Context, claims *utils.Claims) bool {
+	if claims.ImpersonationWrite {
+		return false
+	}
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return !impersonationSafeWrites[c.Request.Method+" "+c.FullPath()]
+}
+
+// handleImpersonation menjalankan request dengan token impersonasi: response
+// ditandai header X-Impersonated-By dan request tulis ditolak kecuali
+// diizinkan saat token dibuat. Middleware Audit mencatat setiap request
+// impersonasi beserta id admin dari impersonator_id.
+func handleImpersonation(c *gin.Context, claims *utils.Claims) {
+	c.Set("impersonator_id", claims.ImpersonatorID)
+	c.Header("X-Impersonated-By", strconv.FormatUint(uint64(claims.ImpersonatorID), 10))
+
+	if impersonationWriteBlocked(c, claims) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Token impersonasi hanya boleh membaca data"})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
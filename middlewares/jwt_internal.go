@@ -3,11 +3,28 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
 	"github.com/rudychandra/lagi/utils"
 )
 
+// sessionRevoked memeriksa apakah sesi login sudah dicabut (logout, logout
+// semua perangkat, atau pemakaian ulang refresh token)
+func sessionRevoked(sessionID string) (bool, error) {
+	db, err := config.GetDB()
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = db.Model(&model.RevokedSession{}).
+		Where("session_id = ? AND expires_at > ?", sessionID, time.Now()).
+		Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
 func InternalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -34,9 +51,30 @@ func InternalAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Tolak token dari sesi yang sudah logout atau dicabut
+		if claims.SessionID != "" {
+			revoked, err := sessionRevoked(claims.SessionID)
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa status token"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token sudah dicabut, silakan login kembali"})
+				c.Abort()
+				return
+			}
+		}
+
 		// Simpan data user ke context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+
+		if claims.ImpersonatorID != 0 {
+			handleImpersonation(c, claims)
+			return
+		}
 
 		c.Next()
 	}
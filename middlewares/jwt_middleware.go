@@ -1,7 +1,8 @@
 package middleware
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -40,32 +41,21 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 		token := tokenParts[1]
 
-		// Kirim token ke API eksternal untuk verifikasi
-		apiURL := "https://cis-dev.del.ac.id/api/jwt-api/verify-token"
-		req, err := http.NewRequest("GET", apiURL, nil)
+		// Verifikasi token ke CIS (dengan cache dan circuit breaker)
+		authRes, err := verifyCISToken(c.Request.Context(), token)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat request ke server autentikasi"})
-			c.Abort()
-			return
-		}
-		req.Header.Set("Authorization", "Bearer "+token)
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil || resp.StatusCode != http.StatusOK {
+			if errors.Is(err, errCISUnavailable) {
+				// CIS sedang bermasalah, bukan token yang salah
+				fmt.Printf("Verifikasi token CIS gagal: %v\n", err)
+				c.Header("Retry-After", fmt.Sprint(int(cisBreaker.cooldown.Seconds())))
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server autentikasi sedang tidak tersedia, coba lagi nanti"})
+				c.Abort()
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid atau sudah kadaluarsa"})
 			c.Abort()
 			return
 		}
-		defer resp.Body.Close()
-
-		// Parse response dari API eksternal
-		var authRes AuthResponse
-		if err := json.NewDecoder(resp.Body).Decode(&authRes); err != nil || !authRes.Result {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Gagal memverifikasi token"})
-			c.Abort()
-			return
-		}
 
 		// Simpan user ID dalam context request
 		c.Set("user_id", authRes.User.UserID)
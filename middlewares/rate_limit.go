@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/ratelimit"
+)
+
+// RateLimit membatasi jumlah request per user untuk satu jenis aksi. Request
+// tanpa user (belum login) dihitung per IP. Jika store rate limit gagal,
+// request tetap diteruskan agar fitur utama tidak ikut mati.
+func RateLimit(name string, quota config.Quota) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ratelimit.Default == nil {
+			c.Next()
+			return
+		}
+
+		key := "quota:" + name + ":ip:" + c.ClientIP()
+		if userID, ok := c.Get("user_id"); ok {
+			key = fmt.Sprintf("quota:%s:user:%v", name, userID)
+		}
+
+		res, err := ratelimit.Default.Allow(c.Request.Context(), key, quota)
+		if err != nil {
+			fmt.Printf("Rate limit %s gagal diperiksa: %v\n", name, err)
+			c.Next()
+			return
+		}
+		if !res.Allowed {
+			ratelimit.Reject(c, res, "Kuota request terlampaui, coba lagi nanti")
+			return
+		}
+		ratelimit.SetHeaders(c, res)
+		c.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/policy"
+)
+
+// RequirePermission hanya meneruskan request jika user memiliki semua
+// permission yang diminta. Setiap permission boleh berasal dari role global
+// atau dari scope dosen_roles mana pun, jadi pemeriksaan scope terhadap data
+// tertentu tetap dilakukan di controller. Permission pertama yang tidak
+// dimiliki menghentikan request dengan 403.
+// Harus dipasang setelah middleware autentikasi.
+func RequirePermission(perms ...policy.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, p := range perms {
+			// Require sudah menulis response dan abort jika ditolak
+			if policy.Require(c, p) == nil {
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/upstream"
+)
+
+var (
+	cisAuth       config.UpstreamConfig
+	cisAuthClient = http.DefaultClient
+)
+
+// ConfigureUpstreams menyiapkan URL dan HTTP client CIS untuk verifikasi token.
+// Dipanggil sekali saat aplikasi dijalankan.
+func ConfigureUpstreams(cfg config.UpstreamsConfig) error {
+	client, err := upstream.NewClient(cfg.CISAuth)
+	if err != nil {
+		return err
+	}
+	cisAuth = cfg.CISAuth
+	cisAuthClient = client
+	return nil
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog mencatat aksi yang dilakukan user. ImpersonatorID diisi jika aksi
+// dilakukan admin dengan token impersonasi; ActorID tetap user yang ditiru.
+// Changes berisi field yang berubah dalam bentuk {"field": {"old", "new"}}.
+type AuditLog struct {
+	ID             uint            `json:"id" gorm:"column:id;primaryKey"`
+	ActorID        uint            `json:"actor_id" gorm:"column:actor_id;index"`
+	ActorRole      string          `json:"actor_role" gorm:"column:actor_role;size:50"`
+	ImpersonatorID *uint           `json:"impersonator_id,omitempty" gorm:"column:impersonator_id;index"`
+	Action         string          `json:"action" gorm:"column:action;size:100;index"`
+	EntityType     string          `json:"entity_type,omitempty" gorm:"column:entity_type;size:50;index:idx_audit_entity"`
+	EntityID       string          `json:"entity_id,omitempty" gorm:"column:entity_id;size:64;index:idx_audit_entity"`
+	Method         string          `json:"method" gorm:"column:method;size:10"`
+	Path           string          `json:"path" gorm:"column:path;size:255"`
+	StatusCode     int             `json:"status_code" gorm:"column:status_code"`
+	IPAddress      string          `json:"ip_address" gorm:"column:ip_address;size:64"`
+	UserAgent      string          `json:"user_agent" gorm:"column:user_agent;size:255"`
+	Changes        json.RawMessage `json:"changes,omitempty" gorm:"column:changes;type:text"`
+	Detail         string          `json:"detail,omitempty" gorm:"column:detail;type:text"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime;index"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// KelompokFormasi adalah usulan pembagian kelompok untuk satu angkatan.
+// Input menyimpan daftar mahasiswa beserta batasannya, Kelompok menyimpan
+// pembagian dalam bentuk [{"nomor_kelompok", "anggota"}]. Usulan baru
+// ditulis ke tabel kelompok saat di-commit.
+type KelompokFormasi struct {
+	ID          uint            `json:"id" gorm:"column:id;primaryKey"`
+	ProdiID     uint            `json:"prodi_id" gorm:"column:prodi_id;index:idx_formasi_cohort"`
+	KPAID       uint            `json:"kpa_id" gorm:"column:KPA_id;index:idx_formasi_cohort"`
+	TMID        uint            `json:"tm_id" gorm:"column:TM_id;index:idx_formasi_cohort"`
+	Status      string          `json:"status" gorm:"column:status;size:20;default:'draft'"` // draft, committed, discarded
+	Input       json.RawMessage `json:"input" gorm:"column:input;type:mediumtext"`
+	Kelompok    json.RawMessage `json:"kelompok" gorm:"column:kelompok;type:mediumtext"`
+	CreatedBy   uint            `json:"created_by" gorm:"column:created_by"`
+	CommittedBy uint            `json:"committed_by,omitempty" gorm:"column:committed_by"`
+	CommittedAt *time.Time      `json:"committed_at,omitempty" gorm:"column:committed_at"`
+	CreatedAt   time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time       `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (KelompokFormasi) TableName() string {
+	return "kelompok_formasi"
+}
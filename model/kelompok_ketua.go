@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// KelompokKetua menyimpan ketua kelompok. Disimpan di tabel milik service ini
+// karena tabel kelompok dikelola oleh Laravel.
+type KelompokKetua struct {
+	KelompokID uint      `json:"kelompok_id" gorm:"column:kelompok_id;primaryKey;autoIncrement:false"`
+	UserID     uint      `json:"user_id" gorm:"column:user_id;index"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (KelompokKetua) TableName() string {
+	return "kelompok_ketua"
+}
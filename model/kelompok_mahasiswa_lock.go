@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// KelompokMahasiswaLock adalah baris kunci per mahasiswa. Perubahan
+// keanggotaan mengunci baris ini agar pemeriksaan "satu kelompok aktif per
+// kategori PA" tidak bisa dilewati oleh dua request yang menambahkan
+// mahasiswa yang sama ke kelompok berbeda secara bersamaan. Tabel
+// kelompok_mahasiswa dan users dikelola Laravel dan tidak selalu punya baris
+// untuk dikunci, sehingga kunci disimpan di tabel milik service ini.
+type KelompokMahasiswaLock struct {
+	UserID    uint      `json:"user_id" gorm:"column:user_id;primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (KelompokMahasiswaLock) TableName() string {
+	return "kelompok_mahasiswa_lock"
+}
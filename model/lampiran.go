@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Jenis pemilik lampiran
+const (
+	LampiranPengumuman = "pengumuman"
+	LampiranTugas      = "tugas"
+)
+
+// Lampiran adalah satu file lampiran milik pengumuman atau tugas. Kolom
+// file di tabel pengumuman dan tugas (milik Laravel) tetap dipakai untuk
+// file utama; lampiran tambahan disimpan di sini dan diurutkan dengan Urutan.
+type Lampiran struct {
+	ID         uint      `json:"id" gorm:"column:id;primaryKey"`
+	Pemilik    string    `json:"pemilik" gorm:"column:pemilik;size:20;index:idx_lampiran_pemilik"`
+	PemilikID  uint      `json:"pemilik_id" gorm:"column:pemilik_id;index:idx_lampiran_pemilik"`
+	Urutan     int       `json:"urutan" gorm:"column:urutan"`
+	Nama       string    `json:"nama" gorm:"column:nama"`
+	FilePath   string    `json:"file" gorm:"column:file_path;index"`
+	Ukuran     int64     `json:"ukuran" gorm:"column:ukuran"`
+	MIME       string    `json:"mime" gorm:"column:mime;size:150"`
+	UploadedBy uint      `json:"uploaded_by" gorm:"column:uploaded_by"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (Lampiran) TableName() string {
+	return "lampiran"
+}
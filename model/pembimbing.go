@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Pembimbing represents a supervisor assigned to a kelompok. The table is
+// owned by Laravel, like penguji.
+type Pembimbing struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"column:user_id" json:"user_id"`
+	KelompokID uint      `gorm:"column:kelompok_id" json:"kelompok_id"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for Pembimbing
+func (Pembimbing) TableName() string {
+	return "pembimbing"
+}
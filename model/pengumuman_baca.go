@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// PengumumanBaca mencatat kapan seorang user pertama kali membaca pengumuman
+// dan, untuk pengumuman yang wajib dikonfirmasi, kapan ia mengonfirmasinya
+type PengumumanBaca struct {
+	ID             uint       `json:"-" gorm:"column:id;primaryKey"`
+	PengumumanID   uint       `json:"pengumuman_id" gorm:"column:pengumuman_id;uniqueIndex:idx_pengumuman_baca_user"`
+	UserID         uint       `json:"user_id" gorm:"column:user_id;uniqueIndex:idx_pengumuman_baca_user;index"`
+	ReadAt         time.Time  `json:"read_at" gorm:"column:read_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at" gorm:"column:acknowledged_at"`
+}
+
+func (PengumumanBaca) TableName() string {
+	return "pengumuman_baca"
+}
@@ -0,0 +1,83 @@
+package model
+
+import "time"
+
+// PengumumanMeta menyimpan data tambahan pengumuman yang tidak ada di tabel
+// pengumuman milik Laravel, seperti jadwal terbit dan kadaluarsa, format
+// isi, prioritas, kewajiban konfirmasi dan penanda hapus.
+// PublishedAt dan ExpiredAt diisi scheduler saat status diubah.
+// WajibKonfirmasi berarti penerima harus menekan konfirmasi, tidak cukup membuka.
+type PengumumanMeta struct {
+	PengumumanID    uint       `json:"pengumuman_id" gorm:"column:pengumuman_id;primaryKey;autoIncrement:false"`
+	PublishAt       *time.Time `json:"publish_at" gorm:"column:publish_at;index"`
+	ExpireAt        *time.Time `json:"expire_at" gorm:"column:expire_at;index"`
+	PublishedAt     *time.Time `json:"published_at" gorm:"column:published_at"`
+	ExpiredAt       *time.Time `json:"expired_at" gorm:"column:expired_at"`
+	Format          string     `json:"format" gorm:"column:format;size:20;default:teks"`
+	Prioritas       string     `json:"prioritas" gorm:"column:prioritas;size:20;default:normal"`
+	Pinned          bool       `json:"pinned" gorm:"column:pinned;default:false"`
+	WajibKonfirmasi bool       `json:"wajib_konfirmasi" gorm:"column:wajib_konfirmasi;default:false"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" gorm:"column:deleted_at;index"`
+	DeletedBy       *uint      `json:"deleted_by,omitempty" gorm:"column:deleted_by"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (PengumumanMeta) TableName() string {
+	return "pengumuman_meta"
+}
+
+// Format isi (deskripsi) pengumuman
+const (
+	FormatTeks     = "teks"
+	FormatMarkdown = "markdown"
+)
+
+// Tingkat prioritas pengumuman, dari yang terendah
+const (
+	PrioritasRendah  = "rendah"
+	PrioritasNormal  = "normal"
+	PrioritasTinggi  = "tinggi"
+	PrioritasDarurat = "darurat"
+)
+
+// PrioritasLevel mengembalikan urutan prioritas; nilai tidak dikenal
+// dianggap normal
+func PrioritasLevel(p string) int {
+	switch p {
+	case PrioritasRendah:
+		return 0
+	case PrioritasTinggi:
+		return 2
+	case PrioritasDarurat:
+		return 3
+	}
+	return 1
+}
+
+// Jenis target pengumuman
+const (
+	TargetCohort   = "cohort"
+	TargetKelompok = "kelompok"
+	TargetRole     = "role"
+)
+
+// PengumumanTarget adalah satu sasaran pengumuman. Pengumuman terlihat oleh
+// user yang cocok dengan salah satu targetnya:
+//   - cohort: prodi, kategori PA dan tahun masuk (0 berarti semua)
+//   - kelompok: anggota kelompok tertentu
+//   - role: role token (Mahasiswa, Dosen) atau role dosen (koordinator, pembimbing, penguji)
+type PengumumanTarget struct {
+	ID           uint   `json:"id" gorm:"column:id;primaryKey"`
+	PengumumanID uint   `json:"pengumuman_id" gorm:"column:pengumuman_id;index"`
+	Tipe         string `json:"tipe" gorm:"column:tipe;size:20"`
+	ProdiID      uint   `json:"prodi_id,omitempty" gorm:"column:prodi_id"`
+	KPAID        uint   `json:"kpa_id,omitempty" gorm:"column:KPA_id"`
+	TMID         uint   `json:"tm_id,omitempty" gorm:"column:TM_id"`
+	KelompokID   uint   `json:"kelompok_id,omitempty" gorm:"column:kelompok_id;index"`
+	Role         string `json:"role,omitempty" gorm:"column:role;size:50"`
+}
+
+func (PengumumanTarget) TableName() string {
+	return "pengumuman_target"
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// PengumumanRevisi menyimpan isi pengumuman sebelum diubah, sehingga riwayat
+// perubahan judul dan deskripsi bisa ditelusuri
+type PengumumanRevisi struct {
+	ID           uint      `json:"id" gorm:"column:id;primaryKey"`
+	PengumumanID uint      `json:"pengumuman_id" gorm:"column:pengumuman_id;index"`
+	Judul        string    `json:"judul" gorm:"column:judul"`
+	Deskripsi    string    `json:"deskripsi" gorm:"column:deskripsi;type:mediumtext"`
+	Format       string    `json:"format" gorm:"column:format;size:20"`
+	EditedBy     uint      `json:"edited_by" gorm:"column:edited_by"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (PengumumanRevisi) TableName() string {
+	return "pengumuman_revisi"
+}
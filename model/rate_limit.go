@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// RateLimitCounter menyimpan counter rate limit saat RATE_LIMIT_STORE=database,
+// sehingga semua instance service berbagi batas yang sama
+type RateLimitCounter struct {
+	Key       string    `json:"key" gorm:"column:key;primaryKey;size:191"`
+	Value     int64     `json:"value" gorm:"column:value"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;index"`
+}
+
+func (RateLimitCounter) TableName() string {
+	return "rate_limit_counters"
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// RefreshToken menyimpan refresh token yang sudah di-hash. Setiap refresh
+// menghasilkan token baru dalam family (sesi login) yang sama; token lama
+// ditandai UsedAt sehingga pemakaian ulang bisa dideteksi.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"column:id;primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"column:user_id;index"`
+	Role      string     `json:"role" gorm:"column:role;size:50"`
+	FamilyID  string     `json:"family_id" gorm:"column:family_id;size:64;index"` // sama dengan sid di access token
+	TokenHash string     `json:"-" gorm:"column:token_hash;size:64;uniqueIndex"`  // sha256 hex dari token
+	DeviceID  string     `json:"device_id" gorm:"column:device_id;size:191"`
+	UserAgent string     `json:"user_agent" gorm:"column:user_agent;size:255"`
+	IPAddress string     `json:"ip_address" gorm:"column:ip_address;size:64"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"column:expires_at;index"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	RevokedAt *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RevokedSession menandai sesi login yang sudah dicabut. Access token dengan
+// sid yang sama ditolak sampai ExpiresAt, setelah itu token tersebut memang
+// sudah kadaluarsa dan baris ini boleh dihapus.
+type RevokedSession struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey"`
+	SessionID string    `json:"session_id" gorm:"column:session_id;size:64;uniqueIndex"`
+	UserID    uint      `json:"user_id" gorm:"column:user_id;index"`
+	Reason    string    `json:"reason" gorm:"column:reason;size:50"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (RevokedSession) TableName() string {
+	return "revoked_sessions"
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Jenis dokumen yang diindeks untuk pencarian
+const (
+	DokumenPengumuman = "pengumuman"
+	DokumenTugas      = "tugas"
+	DokumenBimbingan  = "bimbingan"
+)
+
+// SearchDokumen adalah salinan teks pengumuman, tugas dan bimbingan dengan
+// indeks FULLTEXT. Tabel sumbernya milik Laravel sehingga indeks tidak
+// dipasang langsung di sana; isinya disinkronkan oleh package search.
+type SearchDokumen struct {
+	ID         uint      `json:"-" gorm:"column:id;primaryKey"`
+	Tipe       string    `json:"tipe" gorm:"column:tipe;size:20;uniqueIndex:idx_search_dokumen_ref"`
+	RefID      uint      `json:"id" gorm:"column:ref_id;uniqueIndex:idx_search_dokumen_ref"`
+	Judul      string    `json:"judul" gorm:"column:judul;type:text;index:ft_search_dokumen,class:FULLTEXT"`
+	Isi        string    `json:"-" gorm:"column:isi;type:mediumtext;index:ft_search_dokumen,class:FULLTEXT"`
+	ProdiID    uint      `json:"prodi_id" gorm:"column:prodi_id"`
+	KPAID      uint      `json:"kpa_id" gorm:"column:KPA_id"`
+	TMID       uint      `json:"tm_id" gorm:"column:TM_id"`
+	KelompokID uint      `json:"kelompok_id,omitempty" gorm:"column:kelompok_id;index"`
+	Tanggal    time.Time `json:"tanggal" gorm:"column:tanggal;index"`
+	// SourceUpdatedAt adalah updated_at baris sumber, dipakai sebagai
+	// penanda sinkronisasi berikutnya
+	SourceUpdatedAt time.Time `json:"-" gorm:"column:source_updated_at;index"`
+	IndexedAt       time.Time `json:"-" gorm:"column:indexed_at"`
+}
+
+func (SearchDokumen) TableName() string {
+	return "search_dokumen"
+}
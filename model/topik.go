@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+// TopikDosen adalah topik PA yang ditawarkan dosen untuk satu angkatan.
+// Kuota adalah jumlah kelompok yang bisa mengambil topik tersebut.
+type TopikDosen struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"column:user_id;index"` // dosen pemilik topik
+	ProdiID   uint      `json:"prodi_id" gorm:"column:prodi_id;index:idx_topik_cohort"`
+	KPAID     uint      `json:"kpa_id" gorm:"column:KPA_id;index:idx_topik_cohort"`
+	TMID      uint      `json:"tm_id" gorm:"column:TM_id;index:idx_topik_cohort"`
+	Judul     string    `json:"judul" gorm:"column:judul;size:255"`
+	Deskripsi string    `json:"deskripsi" gorm:"column:deskripsi;type:text"`
+	KataKunci string    `json:"kata_kunci" gorm:"column:kata_kunci;size:255"` // dipisah koma
+	Kuota     int       `json:"kuota" gorm:"column:kuota"`
+	Status    string    `json:"status" gorm:"column:status;size:20;default:'dibuka'"` // dibuka, ditutup
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (TopikDosen) TableName() string {
+	return "topik_dosen"
+}
+
+// TopikUsulan adalah topik yang diajukan sendiri oleh kelompok. DosenID
+// opsional, diisi jika kelompok sudah memilih calon pembimbing.
+type TopikUsulan struct {
+	ID         uint      `json:"id" gorm:"column:id;primaryKey"`
+	KelompokID uint      `json:"kelompok_id" gorm:"column:kelompok_id;index"`
+	UserID     uint      `json:"user_id" gorm:"column:user_id"` // mahasiswa yang mengajukan
+	DosenID    uint      `json:"dosen_id,omitempty" gorm:"column:dosen_id;index"`
+	Judul      string    `json:"judul" gorm:"column:judul;size:255"`
+	Abstrak    string    `json:"abstrak" gorm:"column:abstrak;type:text"`
+	KataKunci  string    `json:"kata_kunci" gorm:"column:kata_kunci;size:255"`
+	Status     string    `json:"status" gorm:"column:status;size:20;default:'diajukan'"` // diajukan, diterima, ditolak
+	Catatan    string    `json:"catatan,omitempty" gorm:"column:catatan;type:text"`
+	ReviewedBy uint      `json:"reviewed_by,omitempty" gorm:"column:reviewed_by"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (TopikUsulan) TableName() string {
+	return "topik_usulan"
+}
+
+// TopikPilihan adalah urutan topik dosen yang diinginkan kelompok.
+// Peringkat 1 adalah pilihan utama.
+type TopikPilihan struct {
+	ID         uint      `json:"id" gorm:"column:id;primaryKey"`
+	KelompokID uint      `json:"kelompok_id" gorm:"column:kelompok_id;uniqueIndex:idx_pilihan_topik;uniqueIndex:idx_pilihan_peringkat"`
+	TopikID    uint      `json:"topik_id" gorm:"column:topik_id;uniqueIndex:idx_pilihan_topik"`
+	Peringkat  int       `json:"peringkat" gorm:"column:peringkat;uniqueIndex:idx_pilihan_peringkat"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (TopikPilihan) TableName() string {
+	return "topik_pilihan"
+}
+
+// TopikPenetapan adalah hasil penetapan topik dan pembimbing untuk satu
+// kelompok. PembimbingID menunjuk baris pembimbing dosen tersebut; hanya
+// baris yang dibuat penetapan ini (PembimbingDibuat) yang boleh diubah atau
+// dihapus, sehingga baris pembimbing dari Laravel tidak tersentuh.
+type TopikPenetapan struct {
+	ID           uint  `json:"id" gorm:"column:id;primaryKey"`
+	KelompokID   uint  `json:"kelompok_id" gorm:"column:kelompok_id;uniqueIndex"`
+	TopikID      *uint `json:"topik_id" gorm:"column:topik_id;index"`
+	UsulanID     *uint `json:"usulan_id,omitempty" gorm:"column:usulan_id"`
+	DosenID      uint  `json:"dosen_id" gorm:"column:dosen_id"`
+	PembimbingID uint  `json:"pembimbing_id" gorm:"column:pembimbing_id"`
+	// PembimbingDibuat bernilai true jika baris pembimbing dibuat service ini
+	PembimbingDibuat bool      `json:"pembimbing_dibuat" gorm:"column:pembimbing_dibuat"`
+	Peringkat        int       `json:"peringkat"  gorm:"column:peringkat"`  // peringkat pilihan yang didapat, 0 jika bukan dari pilihan
+	Sumber           string    `json:"sumber" gorm:"column:sumber;size:20"` // matching, usulan, koordinator
+	CreatedBy        uint      `json:"created_by" gorm:"column:created_by"`
+	CreatedAt        time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (TopikPenetapan) TableName() string {
+	return "topik_penetapan"
+}
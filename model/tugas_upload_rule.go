@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// TugasUploadRule menyimpan aturan upload khusus untuk satu tugas.
+// Jika tidak ada baris untuk sebuah tugas, batasan default dari config dipakai.
+type TugasUploadRule struct {
+	ID          uint      `json:"id" gorm:"column:id;primaryKey"`
+	TugasID     uint      `json:"tugas_id" gorm:"column:tugas_id;uniqueIndex"`
+	AllowedMIME string    `json:"allowed_mime" gorm:"column:allowed_mime;type:text"` // dipisah koma, contoh: application/pdf,image/*
+	MaxSize     int64     `json:"max_size" gorm:"column:max_size"`                   // dalam byte, 0 = default config
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (TugasUploadRule) TableName() string {
+	return "tugas_upload_rules"
+}
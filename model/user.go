@@ -9,7 +9,8 @@ type User struct {
 	ID        uint           `gorm:"primaryKey;column:id" json:"id"`
 	Username  string         `gorm:"unique;not null" json:"username"`
 	Password  string         `gorm:"not null" json:"-"`
-	Email     string         `gorm:"unique" json:"email"`
+	// Email NULL jika CIS tidak mengirim email, karena kolomnya unique
+	Email     *string        `gorm:"unique" json:"email"`
 	Role      string         `gorm:"not null" json:"role"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
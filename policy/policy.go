@@ -0,0 +1,329 @@
+// Package policy menggabungkan role dari token dengan role dosen (dosen_roles)
+// menjadi daftar permission, agar pemeriksaan akses tidak lagi berupa
+// perbandingan string role yang tersebar di controller.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"gorm.io/gorm"
+)
+
+// Permission adalah nama aksi yang bisa diizinkan untuk seorang user
+type Permission string
+
+const (
+	// Bimbingan
+	BimbinganViewAll Permission = "bimbingan:view_all"
+	BimbinganApprove Permission = "bimbingan:approve"
+
+	// Tugas dan file
+	TugasManageUploadRule Permission = "tugas:manage_upload_rule"
+	FileReadAll           Permission = "file:read_all"
+
+	// Kelompok PA
+	KelompokView   Permission = "kelompok:view"
+	KelompokManage Permission = "kelompok:manage"
+	KelompokUji    Permission = "kelompok:uji"
+
+	// Topik PA dan penetapan pembimbing
+	TopikOffer Permission = "topik:offer"
+	TopikMatch Permission = "topik:match"
+
+	// Pengumuman
+	PengumumanCreate Permission = "pengumuman:create"
+	PengumumanManage Permission = "pengumuman:manage"
+
+	// Administrasi
+	RoleManage       Permission = "role:manage"
+	DosenRoleManage  Permission = "dosen_role:manage"
+	AdminDiagnostics Permission = "admin:diagnostics"
+	AdminImpersonate Permission = "admin:impersonate"
+	AdminAuditView   Permission = "admin:audit_view"
+)
+
+// Role token yang dikenal aplikasi
+const (
+	RoleMahasiswa = "Mahasiswa"
+	RoleDosen     = "Dosen"
+)
+
+// rolePermissions adalah permission global berdasarkan role di token.
+// Melihat dan menyetujui bimbingan kelompok lain tidak diberikan ke semua
+// dosen; permission itu hanya datang dari dosen_roles (koordinator dan
+// pembimbing) sesuai prodi dan jenis PA-nya.
+var rolePermissions = map[string][]Permission{
+	RoleDosen: {TugasManageUploadRule, FileReadAll, TopikOffer, PengumumanCreate},
+}
+
+// dosenRolePermissions adalah permission dari dosen_roles. Permission ini
+// hanya berlaku pada prodi dan jenis PA yang tercatat di baris dosen_roles.
+// Key dicocokkan dengan awalan nama_role, sehingga "Pembimbing 1" dan
+// "Pembimbing 2" sama-sama termasuk "pembimbing".
+var dosenRolePermissions = map[string][]Permission{
+	"koordinator": {KelompokView, KelompokManage, BimbinganViewAll, TugasManageUploadRule, TopikMatch, PengumumanManage},
+	"pembimbing":  {KelompokView, BimbinganApprove},
+	"penguji":     {KelompokView, KelompokUji},
+}
+
+// Scope adalah cakupan sebuah permission dari dosen_roles. Field kosong
+// berarti berlaku untuk semua nilai.
+type Scope struct {
+	Role    string `json:"role"`
+	Prodi   string `json:"prodi,omitempty"`
+	JenisPA string `json:"jenis_pa,omitempty"`
+}
+
+// MatchesProdi mencocokkan scope dengan prodi. dosen_roles.prodi bisa berisi
+// id atau nama prodi, jadi keduanya dibandingkan.
+func (s Scope) MatchesProdi(prodiID uint, namaProdi string) bool {
+	if s.Prodi == "" {
+		return true
+	}
+	if prodiID != 0 && s.Prodi == strconv.FormatUint(uint64(prodiID), 10) {
+		return true
+	}
+	return namaProdi != "" && strings.EqualFold(s.Prodi, namaProdi)
+}
+
+// MatchesJenisPA mencocokkan scope dengan jenis PA (kategori PA)
+func (s Scope) MatchesJenisPA(kpaID uint, kategori string) bool {
+	if s.JenisPA == "" {
+		return true
+	}
+	if kpaID != 0 && s.JenisPA == strconv.FormatUint(uint64(kpaID), 10) {
+		return true
+	}
+	return kategori != "" && strings.EqualFold(s.JenisPA, kategori)
+}
+
+// Subject adalah user yang sedang login beserta permission-nya
+type Subject struct {
+	UserID     uint
+	Role       string
+	Admin      bool
+	DosenRoles []model.DosenRole
+
+	global map[Permission]bool
+	scoped map[Permission][]Scope
+}
+
+// NewSubject menghitung permission dari role token dan dosen_roles
+func NewSubject(userID uint, role string, dosenRoles []model.DosenRole) *Subject {
+	s := &Subject{
+		UserID:     userID,
+		Role:       role,
+		Admin:      config.App.IsAdmin(role),
+		DosenRoles: dosenRoles,
+		global:     map[Permission]bool{},
+		scoped:     map[Permission][]Scope{},
+	}
+	for _, p := range rolePermissions[role] {
+		s.global[p] = true
+	}
+	for _, dr := range dosenRoles {
+		name := strings.ToLower(strings.TrimSpace(dr.NamaRole))
+		for prefix, perms := range dosenRolePermissions {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			scope := Scope{Role: prefix, Prodi: strings.TrimSpace(dr.Prodi), JenisPA: strings.TrimSpace(dr.JenisPA)}
+			for _, p := range perms {
+				s.scoped[p] = append(s.scoped[p], scope)
+			}
+		}
+	}
+	return s
+}
+
+// Can bernilai true jika user memiliki permission, baik global maupun pada
+// setidaknya satu scope
+func (s *Subject) Can(p Permission) bool {
+	return s.CanGlobal(p) || len(s.scoped[p]) > 0
+}
+
+// CanGlobal bernilai true jika permission berlaku tanpa batasan scope
+func (s *Subject) CanGlobal(p Permission) bool {
+	return s.Admin || s.global[p]
+}
+
+// Scopes mengembalikan cakupan permission dari dosen_roles. Hasil kosong
+// bersama CanGlobal false berarti user tidak memiliki permission tersebut.
+func (s *Subject) Scopes(p Permission) []Scope {
+	return s.scoped[p]
+}
+
+// CanFor memeriksa permission untuk data pada prodi dan jenis PA tertentu
+func (s *Subject) CanFor(p Permission, prodiID uint, namaProdi string, kpaID uint, kategori string) bool {
+	if s.CanGlobal(p) {
+		return true
+	}
+	for _, scope := range s.scoped[p] {
+		if scope.MatchesProdi(prodiID, namaProdi) && scope.MatchesJenisPA(kpaID, kategori) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanForCohort seperti CanFor, tetapi menerima id prodi dan kategori PA saja.
+// Nama prodi dan kategori PA hanya dibaca dari database jika ada scope yang
+// perlu dicocokkan dengan nama.
+func (s *Subject) CanForCohort(db *gorm.DB, p Permission, prodiID, kpaID uint) (bool, error) {
+	if s.CanFor(p, prodiID, "", kpaID, "") {
+		return true, nil
+	}
+	if len(s.scoped[p]) == 0 {
+		return false, nil
+	}
+
+	var prodi model.Prodi
+	if prodiID != 0 {
+		if err := db.Select("id", "nama_prodi").Where("id = ?", prodiID).Limit(1).Find(&prodi).Error; err != nil {
+			return false, err
+		}
+	}
+	var kategori model.KategoriPA
+	if kpaID != 0 {
+		if err := db.Select("id", "kategori_pa").Where("id = ?", kpaID).Limit(1).Find(&kategori).Error; err != nil {
+			return false, err
+		}
+	}
+	return s.CanFor(p, prodiID, prodi.NamaProdi, kpaID, kategori.KategoriPA), nil
+}
+
+// CohortScope adalah satu cakupan permission dalam bentuk id, untuk dipakai
+// di klausa WHERE. Slice nil berarti semua prodi atau semua kategori PA.
+type CohortScope struct {
+	ProdiIDs []uint
+	KPAIDs   []uint
+}
+
+// CohortScopes menerjemahkan cakupan dosen_roles sebuah permission menjadi id
+// prodi dan kategori PA, dengan pencocokan nama yang sama seperti CanFor.
+// Hasil kosong berarti permission tidak punya cakupan terbatas.
+func (s *Subject) CohortScopes(db *gorm.DB, p Permission) ([]CohortScope, error) {
+	scopes := s.scoped[p]
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+	var prodi []model.Prodi
+	if err := db.Select("id", "nama_prodi").Find(&prodi).Error; err != nil {
+		return nil, err
+	}
+	var kategori []model.KategoriPA
+	if err := db.Select("id", "kategori_pa").Find(&kategori).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]CohortScope, 0, len(scopes))
+	for _, scope := range scopes {
+		var cs CohortScope
+		if scope.Prodi != "" {
+			cs.ProdiIDs = []uint{}
+			for _, pr := range prodi {
+				if scope.MatchesProdi(pr.ID, pr.NamaProdi) {
+					cs.ProdiIDs = append(cs.ProdiIDs, pr.ID)
+				}
+			}
+		}
+		if scope.JenisPA != "" {
+			cs.KPAIDs = []uint{}
+			for _, k := range kategori {
+				if scope.MatchesJenisPA(k.ID, k.KategoriPA) {
+					cs.KPAIDs = append(cs.KPAIDs, k.ID)
+				}
+			}
+		}
+		result = append(result, cs)
+	}
+	return result, nil
+}
+
+// Permissions mengembalikan ringkasan permission untuk ditampilkan ke client
+func (s *Subject) Permissions() gin.H {
+	global := []Permission{}
+	for p := range s.global {
+		global = append(global, p)
+	}
+	sort.Slice(global, func(i, j int) bool { return global[i] < global[j] })
+	return gin.H{
+		"admin":  s.Admin,
+		"global": global,
+		"scoped": s.scoped,
+	}
+}
+
+const contextKey = "policy_subject"
+
+// Load mengambil Subject untuk request ini. Hasilnya disimpan di context
+// agar dosen_roles hanya dibaca sekali per request. Harus dipanggil setelah
+// middleware autentikasi mengisi user_id dan user_role.
+func Load(c *gin.Context) (*Subject, error) {
+	if v, ok := c.Get(contextKey); ok {
+		return v.(*Subject), nil
+	}
+
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return nil, errors.New("policy: user_id tidak ada di context")
+	}
+	uid, _ := userID.(uint)
+	role := c.GetString("user_role")
+
+	db, err := config.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	dosenRoles, err := loadDosenRoles(db, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSubject(uid, role, dosenRoles)
+	c.Set(contextKey, s)
+	return s, nil
+}
+
+func loadDosenRoles(db *gorm.DB, userID uint) ([]model.DosenRole, error) {
+	roles := []model.DosenRole{}
+	err := db.Where("user_id = ?", userID).Find(&roles).Error
+	return roles, err
+}
+
+// Deny menulis response 403 dengan format yang sama di semua endpoint
+func Deny(c *gin.Context, p Permission) {
+	userID, _ := c.Get("user_id")
+	fmt.Printf("Akses ditolak: user %v (%s) tidak memiliki %s untuk %s %s\n",
+		userID, c.GetString("user_role"), p, c.Request.Method, c.FullPath())
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":      "Anda tidak memiliki akses untuk aksi ini",
+		"code":       "forbidden",
+		"permission": p,
+		"role":       c.GetString("user_role"),
+	})
+}
+
+// Require memeriksa permission di dalam handler. Jika ditolak atau gagal,
+// response sudah ditulis dan fungsi mengembalikan nil.
+func Require(c *gin.Context, p Permission) *Subject {
+	s, err := Load(c)
+	if err != nil {
+		fmt.Printf("Gagal memuat permission user: %v\n", err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Gagal memeriksa hak akses"})
+		return nil
+	}
+	if !s.Can(p) {
+		Deny(c, p)
+		return nil
+	}
+	return s
+}
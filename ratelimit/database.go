@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rudychandra/lagi/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore menyimpan counter di tabel rate_limit_counters agar bisa dibagi
+// antar instance service
+type DBStore struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewDBStore membuat store berbasis database
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db, lastSweep: time.Now()}
+}
+
+// sweep menghapus baris kadaluarsa paling sering sekali per menit
+func (s *DBStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	if err := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&model.RateLimitCounter{}).Error; err != nil {
+		fmt.Printf("Gagal membersihkan counter rate limit: %v\n", err)
+	}
+}
+
+func (s *DBStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	s.sweep(ctx, now)
+
+	var value int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Urutan assignment penting: value dihitung dari expires_at lama
+		counter := model.RateLimitCounter{Key: key, Value: 1, ExpiresAt: now.Add(ttl)}
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "value"}, Value: gorm.Expr("CASE WHEN expires_at < ? THEN 1 ELSE value + 1 END", now)},
+				{Column: clause.Column{Name: "expires_at"}, Value: gorm.Expr("CASE WHEN expires_at < ? THEN ? ELSE expires_at END", now, counter.ExpiresAt)},
+			},
+		}).Create(&counter).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.RateLimitCounter{}).Where("`key` = ?", key).Select("value").Scan(&value).Error
+	})
+	return value, err
+}
+
+func (s *DBStore) Decr(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Model(&model.RateLimitCounter{}).
+		Where("`key` = ? AND value > 0", key).
+		Update("value", gorm.Expr("value - 1")).Error
+}
+
+func (s *DBStore) Get(ctx context.Context, key string) (int64, error) {
+	var counter model.RateLimitCounter
+	err := s.db.WithContext(ctx).Where("`key` = ? AND expires_at >= ?", key, time.Now()).First(&counter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return counter.Value, err
+}
+
+func (s *DBStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	counter := model.RateLimitCounter{Key: key, Value: value, ExpiresAt: time.Now().Add(ttl)}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at"}),
+	}).Create(&counter).Error
+}
+
+func (s *DBStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("`key` = ?", key).Delete(&model.RateLimitCounter{}).Error
+}
@@ -0,0 +1,227 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
+)
+
+// lockoutMemory adalah lama riwayat penguncian diingat untuk backoff
+const lockoutMemory = 24 * time.Hour
+
+// Result adalah hasil pemeriksaan rate limit
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter menghitung sliding window di atas Store. Window dibagi menjadi
+// bucket sepanjang window; jumlah request diperkirakan dari bucket saat ini
+// ditambah sisa bucket sebelumnya sesuai porsi waktu yang masih tercakup.
+type Limiter struct {
+	store Store
+	cfg   config.RateLimitConfig
+}
+
+// Default dipakai oleh middleware dan controller, diisi oleh Init
+var Default *Limiter
+
+// New membuat Limiter dengan store dan konfigurasi tertentu
+func New(store Store, cfg config.RateLimitConfig) *Limiter {
+	return &Limiter{store: store, cfg: cfg}
+}
+
+// Store mengembalikan store counter limiter. Store ini juga dipakai untuk
+// data sekali pakai yang harus dibagi antar instance, misalnya nonce signed URL.
+func (l *Limiter) Store() Store {
+	return l.store
+}
+
+// Init membuat Default sesuai konfigurasi RATE_LIMIT_STORE
+func Init(cfg config.RateLimitConfig) error {
+	var store Store
+	switch cfg.Store {
+	case "memory", "":
+		store = NewMemoryStore()
+	case "database":
+		db, err := config.GetDB()
+		if err != nil {
+			return err
+		}
+		store = NewDBStore(db)
+	default:
+		return fmt.Errorf("ratelimit: store %q tidak dikenal", cfg.Store)
+	}
+	Default = New(store, cfg)
+	return nil
+}
+
+// window menghitung key bucket saat ini dan sebelumnya, serta porsi bucket
+// saat ini yang sudah berjalan
+func window(key string, w time.Duration, now time.Time) (cur, prev string, elapsed float64, end time.Time) {
+	n := now.UnixNano() / int64(w)
+	start := time.Unix(0, n*int64(w))
+	cur = key + ":" + strconv.FormatInt(n, 10)
+	prev = key + ":" + strconv.FormatInt(n-1, 10)
+	elapsed = float64(now.Sub(start)) / float64(w)
+	return cur, prev, elapsed, start.Add(w)
+}
+
+// retryAfter memperkirakan kapan perkiraan jumlah request turun di bawah limit
+func retryAfter(q config.Quota, curCount, prevCount int64, elapsed float64, end, now time.Time) time.Duration {
+	d := end.Sub(now)
+	limit := float64(q.Limit)
+	if float64(curCount) < limit && prevCount > 0 {
+		// prev*(1-x) + cur < limit  =>  x > 1 - (limit-cur)/prev
+		x := 1 - (limit-float64(curCount))/float64(prevCount)
+		d = time.Duration((x - elapsed) * float64(q.Window))
+	}
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// Allow mencatat request untuk key lalu memeriksa kuotanya. Counter dinaikkan
+// lebih dulu dan keputusan diambil dari nilai hasil Incr yang atomik, sehingga
+// request bersamaan tidak bisa lolos dengan perkiraan yang sama. Request yang
+// ditolak dikembalikan dari counter. Limit 0 berarti tanpa batas.
+func (l *Limiter) Allow(ctx context.Context, key string, q config.Quota) (Result, error) {
+	if q.Limit <= 0 || q.Window <= 0 {
+		return Result{Allowed: true}, nil
+	}
+	now := time.Now()
+	cur, prev, elapsed, end := window(key, q.Window, now)
+	curCount, err := l.store.Incr(ctx, cur, 2*q.Window)
+	if err != nil {
+		return Result{}, err
+	}
+	prevCount, err := l.store.Get(ctx, prev)
+	if err != nil {
+		return Result{}, err
+	}
+
+	est := float64(prevCount)*(1-elapsed) + float64(curCount)
+	if est > float64(q.Limit) {
+		if err := l.store.Decr(ctx, cur); err != nil {
+			return Result{}, err
+		}
+		return Result{
+			Limit:      q.Limit,
+			RetryAfter: retryAfter(q, curCount-1, prevCount, elapsed, end, now),
+		}, nil
+	}
+
+	remaining := int(math.Floor(float64(q.Limit) - est))
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{Allowed: true, Limit: q.Limit, Remaining: remaining}, nil
+}
+
+// reset menghapus counter sliding window sebuah key
+func (l *Limiter) reset(ctx context.Context, key string, q config.Quota) error {
+	if q.Window <= 0 {
+		return nil
+	}
+	cur, prev, _, _ := window(key, q.Window, time.Now())
+	if err := l.store.Delete(ctx, cur); err != nil {
+		return err
+	}
+	return l.store.Delete(ctx, prev)
+}
+
+func loginUserKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// LoginAllowed memeriksa apakah percobaan login boleh diteruskan ke CIS:
+// IP belum melewati batas percobaan dan username tidak sedang dikunci.
+// Setiap percobaan dari IP dihitung, berhasil maupun gagal.
+func (l *Limiter) LoginAllowed(ctx context.Context, ip, username string) (Result, error) {
+	user := loginUserKey(username)
+	until, err := l.store.Get(ctx, "login:lock:"+user)
+	if err != nil {
+		return Result{}, err
+	}
+	if wait := time.Until(time.Unix(until, 0)); until > 0 && wait > 0 {
+		return Result{Limit: l.cfg.LoginUser.Limit, RetryAfter: wait.Round(time.Second)}, nil
+	}
+	return l.Allow(ctx, "login:ip:"+ip, l.cfg.LoginIP)
+}
+
+// LoginFailed mencatat login gagal untuk username. Jika batas login gagal
+// terlewati, username dikunci; lama kunci berlipat dua setiap kali terkunci
+// lagi dalam 24 jam, sampai LockoutMax. Mengembalikan lama kunci atau 0.
+func (l *Limiter) LoginFailed(ctx context.Context, username string) (time.Duration, error) {
+	q := l.cfg.LoginUser
+	if q.Limit <= 0 || q.Window <= 0 {
+		return 0, nil
+	}
+	user := loginUserKey(username)
+	res, err := l.Allow(ctx, "login:user:"+user, q)
+	if err != nil {
+		return 0, err
+	}
+	if res.Allowed && res.Remaining > 0 {
+		return 0, nil
+	}
+
+	lockouts, err := l.store.Incr(ctx, "login:lockouts:"+user, lockoutMemory)
+	if err != nil {
+		return 0, err
+	}
+	d := l.cfg.LockoutBase
+	for i := int64(1); i < lockouts && d < l.cfg.LockoutMax; i++ {
+		d *= 2
+	}
+	if d > l.cfg.LockoutMax {
+		d = l.cfg.LockoutMax
+	}
+	if err := l.store.Set(ctx, "login:lock:"+user, time.Now().Add(d).Unix(), d); err != nil {
+		return 0, err
+	}
+	// Setelah kunci berakhir, user mendapat jatah percobaan penuh lagi
+	return d, l.reset(ctx, "login:user:"+user, q)
+}
+
+// LoginSucceeded menghapus riwayat login gagal dan penguncian username
+func (l *Limiter) LoginSucceeded(ctx context.Context, username string) error {
+	user := loginUserKey(username)
+	if err := l.reset(ctx, "login:user:"+user, l.cfg.LoginUser); err != nil {
+		return err
+	}
+	return l.store.Delete(ctx, "login:lockouts:"+user)
+}
+
+// SetHeaders menulis header X-RateLimit-* untuk request yang diizinkan
+func SetHeaders(c *gin.Context, res Result) {
+	if res.Limit <= 0 {
+		return
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+}
+
+// Reject menulis response 429 dengan Retry-After
+func Reject(c *gin.Context, res Result, message string) {
+	seconds := int(math.Ceil(res.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	if res.Limit > 0 {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", "0")
+	}
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
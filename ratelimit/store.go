@@ -0,0 +1,110 @@
+// Package ratelimit menyediakan sliding window rate limiter dan penguncian
+// login. Counter disimpan lewat interface Store sehingga beberapa instance
+// service bisa berbagi counter yang sama.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store menyimpan counter dengan masa berlaku. Implementasi harus aman
+// dipakai bersamaan; Incr harus atomik agar counter tidak hilang saat
+// beberapa instance menulis key yang sama.
+type Store interface {
+	// Incr menambah counter sebesar 1 dan mengembalikan nilai barunya. Key
+	// yang belum ada atau sudah kadaluarsa dimulai dari 0 dengan masa
+	// berlaku ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Decr mengurangi counter sebesar 1 tanpa mengubah masa berlakunya.
+	// Counter yang tidak ada atau sudah 0 dibiarkan.
+	Decr(ctx context.Context, key string) error
+	// Get mengembalikan nilai counter, atau 0 jika tidak ada atau kadaluarsa
+	Get(ctx context.Context, key string) (int64, error)
+	// Set menimpa nilai counter dengan masa berlaku ttl
+	Set(ctx context.Context, key string, value int64, ttl time.Duration) error
+	// Delete menghapus counter
+	Delete(ctx context.Context, key string) error
+}
+
+type memoryEntry struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// MemoryStore menyimpan counter di memori proses. Cocok untuk satu instance;
+// counter hilang saat service restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore membuat MemoryStore kosong
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}, lastSweep: time.Now()}
+}
+
+// sweep membuang entry kadaluarsa paling sering sekali per menit.
+// Harus dipanggil dengan mu terkunci.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	for k, e := range m.entries {
+		if now.After(e.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+	m.lastSweep = now
+}
+
+func (m *MemoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.sweep(now)
+
+	e, ok := m.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		e = memoryEntry{expiresAt: now.Add(ttl)}
+	}
+	e.value++
+	m.entries[key] = e
+	return e.value, nil
+}
+
+func (m *MemoryStore) Decr(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok && e.value > 0 {
+		e.value--
+		m.entries[key] = e
+	}
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return 0, nil
+	}
+	return e.value, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = memoryEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
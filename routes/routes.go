@@ -2,13 +2,30 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/rudychandra/lagi/config"
 	"github.com/rudychandra/lagi/controllers"
 	"github.com/rudychandra/lagi/middlewares"
+	"github.com/rudychandra/lagi/policy"
 )
 
 func SetupRouter(r *gin.Engine) {
+	// Audit log untuk semua request tulis; harus dipasang sebelum route didaftarkan
+	r.Use(middleware.Audit())
+
 	// --- Auth ---
 	r.POST("/login", controllers.Login)
+	authGroup := r.Group("/auth")
+	{
+		authGroup.POST("/refresh", controllers.RefreshToken)
+		authGroup.POST("/logout", middleware.InternalAuthMiddleware(), controllers.Logout)
+		authGroup.POST("/logout-all", middleware.InternalAuthMiddleware(), controllers.LogoutAll)
+	}
+
+	// Profil user yang sedang login
+	r.GET("/me", middleware.InternalAuthMiddleware(), controllers.GetMe)
+
+	// Kunci publik token internal untuk verifikasi oleh aplikasi lain (Laravel)
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
 
 	// --- Bimbingan (Mahasiswa) ---
 	mahasiswa := r.Group("/bimbingan")
@@ -43,8 +60,19 @@ func SetupRouter(r *gin.Engine) {
 	{
 		pengumuman.GET("/", controllers.GetPengumuman)
 		pengumuman.GET("/:id", controllers.GetPengumumanByID)
-		pengumuman.POST("/", controllers.CreatePengumuman)
+		pengumuman.POST("/", middleware.RequirePermission(policy.PengumumanCreate), middleware.RateLimit("upload", config.RateLimit.Upload), controllers.CreatePengumuman)
+		// Ubah, hapus dan pulihkan hanya untuk penulis atau koordinator (diperiksa di controller)
+		pengumuman.PUT("/:id", controllers.UpdatePengumuman)
+		pengumuman.GET("/:id/riwayat", controllers.GetPengumumanRiwayat)
 		pengumuman.DELETE("/:id", controllers.DeletePengumuman)
+		pengumuman.POST("/:id/restore", controllers.RestorePengumuman)
+		pengumuman.POST("/:id/konfirmasi", controllers.KonfirmasiPengumuman)
+		// Statistik baca dan pengingat untuk penulis atau koordinator
+		pengumuman.GET("/:id/statistik", controllers.GetPengumumanStatistik)
+		pengumuman.POST("/:id/ingatkan", middleware.RateLimit("notification", config.RateLimit.Notification), controllers.IngatkanPengumuman)
+		pengumuman.POST("/:id/lampiran", middleware.RateLimit("upload", config.RateLimit.Upload), controllers.UploadPengumumanLampiran)
+		pengumuman.PUT("/:id/lampiran/urutan", controllers.ReorderPengumumanLampiran)
+		pengumuman.DELETE("/:id/lampiran/:lampiran_id", controllers.DeletePengumumanLampiran)
 	}
 
 	// --- Approval Request Bimbingan (Dosen) ---
@@ -53,7 +81,7 @@ func SetupRouter(r *gin.Engine) {
 	{
 		approve.GET("/", controllers.GetUpdateBimbingan)
 		approve.GET("/:id", controllers.GetUpdateBimbinganByID)
-		approve.PUT("/:id", controllers.UpdateRequestBimbingan)
+		approve.PUT("/:id", middleware.RequirePermission(policy.BimbinganApprove), controllers.UpdateRequestBimbingan)
 	}
 
 	// --- Jadwal (Mahasiswa + Dosen) ---
@@ -89,8 +117,12 @@ func SetupRouter(r *gin.Engine) {
 	// --- Role Management ---
 	RoleRoutes(r)
 	DosenRoleRoutes(r)
+	KelompokRoutes(r)
+	TopikRoutes(r)
+	SearchRoutes(r)
 	SetupFileRoutes(r)
 	notificationHandler(r)
+	AdminRoutes(r)
 }
 
 // Rest of your route setup functions...
@@ -106,10 +138,10 @@ func SetupPengumpulanRoutes(r *gin.Engine) {
 		pengumpulan.GET("/:id", controllers.GetSubmitanTugasByID)
 
 		// Create a new submission for an assignment
-		pengumpulan.POST("/:id/upload", controllers.UpdateUploadFileTugas)
+		pengumpulan.POST("/:id/upload", middleware.RateLimit("upload", config.RateLimit.Upload), controllers.UpdateUploadFileTugas)
 
 		// Update an existing submission 	- Add this missing route
-		pengumpulan.PUT("/:id/upload", controllers.UpdateUploadFileTugas)
+		pengumpulan.PUT("/:id/upload", middleware.RateLimit("upload", config.RateLimit.Upload), controllers.UpdateUploadFileTugas)
 	}
 }
 
@@ -119,11 +151,18 @@ func TugasRoutes(r *gin.Engine) {
 	{
 		tugasGroup.GET("/", controllers.GetSubmitanTugas)        // Get all tugas for mahasiswa
 		tugasGroup.GET("/:id", controllers.GetSubmitanTugasByID) // Get specific tugas by ID
+		tugasGroup.GET("/:id/upload-rule", controllers.GetTugasUploadRule)
+		tugasGroup.PUT("/:id/upload-rule", middleware.RequirePermission(policy.TugasManageUploadRule), controllers.SetTugasUploadRule)
+		// Lampiran tugas untuk pembuat tugas atau koordinator (diperiksa di controller)
+		tugasGroup.POST("/:id/lampiran", middleware.RateLimit("upload", config.RateLimit.Upload), controllers.UploadTugasLampiran)
+		tugasGroup.PUT("/:id/lampiran/urutan", controllers.ReorderTugasLampiran)
+		tugasGroup.DELETE("/:id/lampiran/:lampiran_id", controllers.DeleteTugasLampiran)
 	}
 }
 
 func RoleRoutes(r *gin.Engine) {
 	roleGroup := r.Group("/roles")
+	roleGroup.Use(middleware.InternalAuthMiddleware(), middleware.RequirePermission(policy.RoleManage))
 	{
 		roleGroup.POST("/", controllers.CreateRole)
 		roleGroup.GET("/", controllers.GetRoles)
@@ -135,6 +174,7 @@ func RoleRoutes(r *gin.Engine) {
 
 func DosenRoleRoutes(r *gin.Engine) {
 	roleGroup := r.Group("/dosenroles")
+	roleGroup.Use(middleware.InternalAuthMiddleware(), middleware.RequirePermission(policy.DosenRoleManage))
 	{
 		roleGroup.POST("/", controllers.CreateDosenRoles)
 		roleGroup.GET("/", controllers.GetDosenroles)
@@ -145,24 +185,82 @@ func DosenRoleRoutes(r *gin.Engine) {
 	}
 }
 
+// KelompokRoutes mendaftarkan endpoint pengelolaan kelompok PA
+func KelompokRoutes(r *gin.Engine) {
+	kelompok := r.Group("/kelompok")
+	kelompok.Use(middleware.InternalAuthMiddleware())
+	{
+		kelompok.GET("/", middleware.RequirePermission(policy.KelompokView), controllers.GetKelompokList)
+		kelompok.GET("/:id", controllers.GetKelompokDetail) // anggota boleh melihat kelompoknya sendiri
+		kelompok.POST("/", middleware.RequirePermission(policy.KelompokManage), controllers.CreateKelompok)
+		kelompok.POST("/:id/anggota", middleware.RequirePermission(policy.KelompokManage), controllers.AddKelompokAnggota)
+		kelompok.DELETE("/:id/anggota/:user_id", middleware.RequirePermission(policy.KelompokManage), controllers.RemoveKelompokAnggota)
+		kelompok.PUT("/:id/ketua", middleware.RequirePermission(policy.KelompokManage), controllers.SetKelompokKetua)
+
+		// Pembentukan kelompok otomatis: buat usulan, ubah, lalu commit
+		formasi := kelompok.Group("/formasi", middleware.RequirePermission(policy.KelompokManage))
+		formasi.POST("/", controllers.CreateKelompokFormasi)
+		formasi.GET("/:id", controllers.GetKelompokFormasi)
+		formasi.PUT("/:id", controllers.UpdateKelompokFormasi)
+		formasi.POST("/:id/commit", controllers.CommitKelompokFormasi)
+		formasi.DELETE("/:id", controllers.DeleteKelompokFormasi)
+	}
+}
+
+// TopikRoutes mendaftarkan endpoint topik PA dan penetapan pembimbing
+func TopikRoutes(r *gin.Engine) {
+	topik := r.Group("/topik")
+	topik.Use(middleware.InternalAuthMiddleware())
+	{
+		// Topik yang ditawarkan dosen
+		topik.GET("/", controllers.GetTopikDosen)
+		topik.POST("/", middleware.RequirePermission(policy.TopikOffer), controllers.CreateTopikDosen)
+		topik.PUT("/:id", middleware.RequirePermission(policy.TopikOffer), controllers.UpdateTopikDosen)
+
+		// Usulan topik dari kelompok
+		topik.GET("/usulan", controllers.GetTopikUsulan)
+		topik.POST("/usulan", controllers.CreateTopikUsulan)
+		topik.PUT("/usulan/:id/status", controllers.ReviewTopikUsulan)
+
+		// Urutan pilihan topik kelompok
+		topik.GET("/pilihan", controllers.GetTopikPilihan)
+		topik.PUT("/pilihan", controllers.SetTopikPilihan)
+
+		// Penetapan topik dan pembimbing oleh koordinator
+		topik.POST("/matching", middleware.RequirePermission(policy.TopikMatch), controllers.RunTopikMatching)
+		topik.GET("/penetapan", middleware.RequirePermission(policy.KelompokView), controllers.GetTopikPenetapan)
+		topik.PUT("/penetapan/:kelompok_id", middleware.RequirePermission(policy.TopikMatch), controllers.SetTopikPenetapan)
+		topik.DELETE("/penetapan/:kelompok_id", middleware.RequirePermission(policy.TopikMatch), controllers.DeleteTopikPenetapan)
+	}
+}
+
+// SearchRoutes mendaftarkan pencarian teks penuh; hasil disaring sesuai hak
+// lihat user di controller
+func SearchRoutes(r *gin.Engine) {
+	searchGroup := r.Group("/search")
+	searchGroup.Use(middleware.InternalAuthMiddleware())
+	{
+		searchGroup.GET("/", controllers.Search)
+	}
+}
+
 // Add this to your SetupFileRoutes function
 func SetupFileRoutes(r *gin.Engine) {
 	// Web file routes (Laravel storage proxy)
+	// Both groups accept a bearer token or a signed URL from /file-links
 	webFiles := r.Group("/files")
+	webFiles.Use(middleware.FileAuthMiddleware())
 	{
 		// View file (proxies to Laravel storage)
 		webFiles.GET("/view/*path", controllers.ProxyFile)
 
 		// Download file (with attachment header)
 		webFiles.GET("/download/*path", controllers.DownloadFile)
-
-		// Debug endpoint
-		webFiles.GET("/debug", controllers.DebugFileAccess)
-		webFiles.GET("/test", controllers.TestLaravelStorage)
 	}
 
-	// Mobile file routes (direct file access)
+	// Mobile file routes (direct file access from upload storage)
 	mobileFiles := r.Group("/mobile-files")
+	mobileFiles.Use(middleware.FileAuthMiddleware())
 	{
 		// View file directly from filesystem
 		mobileFiles.GET("/view/*path", controllers.MobileFileView)
@@ -170,13 +268,17 @@ func SetupFileRoutes(r *gin.Engine) {
 		// Download file directly from filesystem
 		mobileFiles.GET("/download/*path", controllers.MobileFileDownload)
 
-		// Test endpoint
-		mobileFiles.GET("/test", controllers.MobileTestFileAccess)
-
 		// List files in directory
 		mobileFiles.GET("/list", controllers.ListMobileFiles)
 	}
 
+	// Signed URL issuing (for PDF viewers and download managers without headers)
+	fileLinks := r.Group("/file-links")
+	fileLinks.Use(middleware.InternalAuthMiddleware())
+	{
+		fileLinks.POST("/", controllers.CreateSignedFileURL)
+	}
+
 	// Protected file routes (auth required)
 	protectedFiles := r.Group("/secure-files")
 	protectedFiles.Use(middleware.InternalAuthMiddleware())
@@ -188,6 +290,23 @@ func SetupFileRoutes(r *gin.Engine) {
 	}
 }
 
+// AdminRoutes mendaftarkan endpoint khusus admin
+func AdminRoutes(r *gin.Engine) {
+	admin := r.Group("/admin")
+	admin.Use(middleware.InternalAuthMiddleware())
+	{
+		// Token berumur pendek untuk melihat aplikasi sebagai user lain
+		admin.POST("/impersonate", middleware.RequirePermission(policy.AdminImpersonate), controllers.StartImpersonation)
+
+		admin.GET("/audit-logs", middleware.RequirePermission(policy.AdminAuditView), controllers.GetAuditLogs)
+
+		// Diagnostik membuka detail internal, tidak tersedia di production
+		if !config.App.IsProduction() {
+			admin.GET("/diagnostics", middleware.RequirePermission(policy.AdminDiagnostics), controllers.GetDiagnostics)
+		}
+	}
+}
+
 func SetupNotificationRoutes(r *gin.Engine) {
 	// Device token management
 	deviceToken := r.Group("/device-token")
@@ -200,9 +319,11 @@ func SetupNotificationRoutes(r *gin.Engine) {
 func notificationHandler(r *gin.Engine) {
 	// --- Notification Routes ---
 	notification := r.Group("/send-notification")
-	// notification.Use(middleware.InternalAuthMiddleware())
+	// Auth wajib: tanpa user_id kuota notifikasi jatuh ke key IP dan siapa pun
+	// bisa memakai endpoint ini untuk mengirim push FCM
+	notification.Use(middleware.InternalAuthMiddleware())
 	{
-		notification.POST("", controllers.SendNotification) // Send to specific user
+		notification.POST("", middleware.RateLimit("notification", config.RateLimit.Notification), controllers.SendNotification) // Send to specific user
 		// notification.POST("/send-all", controllers.SendNotificationToAll)       // Send to all users
 	}
 }
\ No newline at end of file
@@ -0,0 +1,200 @@
+// Package search menyinkronkan teks pengumuman, tugas dan bimbingan ke tabel
+// search_dokumen yang memiliki indeks FULLTEXT, serta menyiapkan query dan
+// cuplikan hasil pencarian.
+package search
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const batchSize = 500
+
+// watermark mengembalikan updated_at sumber terbaru yang sudah diindeks.
+// Baris dengan updated_at yang sama diindeks ulang agar tidak ada yang
+// terlewat saat beberapa baris berubah pada detik yang sama.
+func watermark(db *gorm.DB, tipe string) (time.Time, error) {
+	var latest *time.Time
+	err := db.Model(&model.SearchDokumen{}).Where("tipe = ?", tipe).
+		Select("MAX(source_updated_at)").Scan(&latest).Error
+	if err != nil || latest == nil {
+		return time.Time{}, err
+	}
+	return *latest, nil
+}
+
+// changedSince membatasi query ke baris yang berubah sejak watermark. Tanpa
+// watermark (indeks masih kosong) semua baris diindeks.
+func changedSince(query *gorm.DB, since time.Time) *gorm.DB {
+	if since.IsZero() {
+		return query
+	}
+	return query.Where("updated_at >= ?", since)
+}
+
+func upsert(db *gorm.DB, docs []model.SearchDokumen) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tipe"}, {Name: "ref_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"judul", "isi", "prodi_id", "KPA_id", "TM_id", "kelompok_id", "tanggal", "source_updated_at", "indexed_at"}),
+	}).Create(&docs).Error
+}
+
+func indexPengumuman(db *gorm.DB, now time.Time) (int, error) {
+	since, err := watermark(db, model.DokumenPengumuman)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	var rows []model.Pengumuman
+	err = changedSince(db.Model(&model.Pengumuman{}), since).FindInBatches(&rows, batchSize, func(tx *gorm.DB, _ int) error {
+		docs := make([]model.SearchDokumen, 0, len(rows))
+		for _, p := range rows {
+			docs = append(docs, model.SearchDokumen{
+				Tipe:            model.DokumenPengumuman,
+				RefID:           p.ID,
+				Judul:           p.Judul,
+				Isi:             p.Deskripsi,
+				ProdiID:         p.ProdiID,
+				KPAID:           p.KPAID,
+				TMID:            p.TMID,
+				Tanggal:         p.TanggalPenulisan,
+				SourceUpdatedAt: p.UpdatedAt,
+				IndexedAt:       now,
+			})
+		}
+		total += len(docs)
+		return upsert(db, docs)
+	}).Error
+	return total, err
+}
+
+func indexTugas(db *gorm.DB, now time.Time) (int, error) {
+	since, err := watermark(db, model.DokumenTugas)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	var rows []model.Tugas
+	err = changedSince(db.Model(&model.Tugas{}), since).FindInBatches(&rows, batchSize, func(tx *gorm.DB, _ int) error {
+		docs := make([]model.SearchDokumen, 0, len(rows))
+		for _, t := range rows {
+			docs = append(docs, model.SearchDokumen{
+				Tipe:            model.DokumenTugas,
+				RefID:           t.ID,
+				Judul:           t.JudulTugas,
+				Isi:             t.DeskripsiTugas,
+				ProdiID:         t.ProdiID,
+				KPAID:           t.KPAID,
+				TMID:            t.TMID,
+				Tanggal:         t.CreatedAt,
+				SourceUpdatedAt: t.UpdatedAt,
+				IndexedAt:       now,
+			})
+		}
+		total += len(docs)
+		return upsert(db, docs)
+	}).Error
+	return total, err
+}
+
+// indexBimbingan mengindeks keperluan dan hasil bimbingan. Cohort diambil
+// dari kelompok yang mengajukan bimbingan.
+func indexBimbingan(db *gorm.DB, now time.Time) (int, error) {
+	since, err := watermark(db, model.DokumenBimbingan)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	var rows []model.Bimbingan
+	err = changedSince(db.Model(&model.Bimbingan{}), since).FindInBatches(&rows, batchSize, func(tx *gorm.DB, _ int) error {
+		ids := make([]uint, 0, len(rows))
+		for _, b := range rows {
+			ids = append(ids, b.KelompokID)
+		}
+		var kelompok []model.Kelompok
+		if err := db.Where("id IN ?", ids).Find(&kelompok).Error; err != nil {
+			return err
+		}
+		byID := map[uint]model.Kelompok{}
+		for _, k := range kelompok {
+			byID[k.ID] = k
+		}
+
+		docs := make([]model.SearchDokumen, 0, len(rows))
+		for _, b := range rows {
+			k := byID[b.KelompokID]
+			docs = append(docs, model.SearchDokumen{
+				Tipe:            model.DokumenBimbingan,
+				RefID:           b.ID,
+				Judul:           b.Keperluan,
+				Isi:             b.HasilBimbingan,
+				ProdiID:         k.ProdiID,
+				KPAID:           k.KPAID,
+				TMID:            k.TMID,
+				KelompokID:      b.KelompokID,
+				Tanggal:         b.RencanaMulai,
+				SourceUpdatedAt: b.UpdatedAt,
+				IndexedAt:       now,
+			})
+		}
+		total += len(docs)
+		return upsert(db, docs)
+	}).Error
+	return total, err
+}
+
+// removeDeleted menghapus dokumen yang baris sumbernya sudah tidak ada
+func removeDeleted(db *gorm.DB) (int64, error) {
+	var removed int64
+	for tipe, table := range map[string]string{
+		model.DokumenPengumuman: "pengumuman",
+		model.DokumenTugas:      "tugas",
+		model.DokumenBimbingan:  "request_bimbingan",
+	} {
+		result := db.Exec("DELETE sd FROM search_dokumen sd LEFT JOIN "+table+" src ON src.id = sd.ref_id "+
+			"WHERE sd.tipe = ? AND src.id IS NULL", tipe)
+		if result.Error != nil {
+			return removed, result.Error
+		}
+		removed += result.RowsAffected
+	}
+	return removed, nil
+}
+
+// Run menyinkronkan indeks dengan tabel sumber dan mengembalikan jumlah
+// dokumen yang diindeks dan dihapus
+func Run(db *gorm.DB, now time.Time) (indexed int, removed int64, err error) {
+	for _, index := range []func(*gorm.DB, time.Time) (int, error){indexPengumuman, indexTugas, indexBimbingan} {
+		n, err := index(db, now)
+		indexed += n
+		if err != nil {
+			return indexed, 0, err
+		}
+	}
+	removed, err = removeDeleted(db)
+	return indexed, removed, err
+}
+
+// StartIndexer menjalankan Run secara berkala di background
+func StartIndexer(cfg config.SearchConfig) {
+	go func() {
+		for {
+			if db, err := config.GetDB(); err == nil {
+				// Dokumen dengan updated_at terakhir selalu diindeks ulang, jadi
+				// jumlah dokumen yang diperbarui tidak dicatat ke log
+				if _, _, err := Run(db, time.Now()); err != nil {
+					fmt.Printf("Gagal memperbarui indeks pencarian: %v\n", err)
+				}
+			}
+			time.Sleep(cfg.IndexInterval)
+		}
+	}()
+}
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTerms membatasi jumlah kata yang dipakai dari satu query
+const maxTerms = 10
+
+// Terms memecah query menjadi kata. Karakter selain huruf dan angka
+// (termasuk operator boolean MySQL seperti + - * " ( ) < > ~ @) dibuang.
+func Terms(q string) []string {
+	fields := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, f := range fields {
+		f = strings.ToLower(f)
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		terms = append(terms, f)
+		if len(terms) == maxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// BooleanQuery menyusun query MATCH ... AGAINST (... IN BOOLEAN MODE). Setiap
+// kata dicari sebagai awalan (kata*) dan dokumen diurutkan berdasarkan
+// relevansi, sehingga dokumen yang memuat lebih banyak kata berada di atas.
+func BooleanQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts = append(parts, t+"*")
+	}
+	return strings.Join(parts, " ")
+}
+
+// termPattern mencocokkan awal kata yang sama dengan salah satu term, tanpa
+// membedakan huruf besar dan kecil. Kata yang cocok ada di submatch pertama;
+// \b tidak dipakai karena di RE2 hanya mengenal huruf ASCII.
+func termPattern(terms []string) *regexp.Regexp {
+	if len(terms) == 0 {
+		return nil
+	}
+	quoted := make([]string, 0, len(terms))
+	for _, t := range terms {
+		quoted = append(quoted, regexp.QuoteMeta(t))
+	}
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])((?:` + strings.Join(quoted, "|") + `)[\p{L}\p{N}]*)`)
+}
+
+// termMatches mengembalikan posisi byte kata yang cocok dengan termPattern
+func termMatches(re *regexp.Regexp, text string, n int) [][2]int {
+	var out [][2]int
+	for _, m := range re.FindAllStringSubmatchIndex(text, n) {
+		out = append(out, [2]int{m[2], m[3]})
+	}
+	return out
+}
+
+// Highlight meng-escape teks sebagai HTML lalu membungkus kata yang cocok
+// dengan <mark>
+func Highlight(text string, terms []string) string {
+	re := termPattern(terms)
+	if re == nil {
+		return html.EscapeString(text)
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range termMatches(re, text, -1) {
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// Snippet mengambil potongan teks sekitar kata pertama yang cocok, paling
+// banyak width karakter, lalu menyorotnya dengan Highlight. Tanpa kata yang
+// cocok, potongan diambil dari awal teks.
+func Snippet(text string, terms []string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= width {
+		return Highlight(text, terms)
+	}
+
+	runes := []rune(text)
+	start := 0
+	if re := termPattern(terms); re != nil {
+		if loc := termMatches(re, text, 1); loc != nil {
+			// Mulai sekitar sepertiga lebar sebelum kata yang cocok
+			start = utf8.RuneCountInString(text[:loc[0][0]]) - width/3
+			if start < 0 {
+				start = 0
+			}
+		}
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+	}
+
+	// Buang potongan kata di tepi cuplikan; kata yang terpotong tepat di
+	// batasnya tetap dipakai
+	part := string(runes[start:end])
+	if start > 0 && runes[start-1] != ' ' {
+		if i := strings.IndexByte(part, ' '); i >= 0 {
+			part = part[i+1:]
+		}
+	}
+	if end < len(runes) && runes[end] != ' ' {
+		if i := strings.LastIndexByte(part, ' '); i >= 0 {
+			part = part[:i]
+		}
+	}
+	part = strings.TrimSpace(part)
+
+	snippet := Highlight(part, terms)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
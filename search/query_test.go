@@ -0,0 +1,93 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want []string
+	}{
+		{"kosong", "", []string{}},
+		{"hanya operator", `+-*"()<>~@`, []string{}},
+		{"huruf kecil dan tanpa duplikat", "Proposal proposal PROPOSAL akhir", []string{"proposal", "akhir"}},
+		{"operator boolean dibuang", `+jadwal -"seminar" (sidang*)`, []string{"jadwal", "seminar", "sidang"}},
+		{"angka ikut", "bab 2 revisi3", []string{"bab", "2", "revisi3"}},
+		{"unicode", "Café naïve Ünïcödé", []string{"café", "naïve", "ünïcödé"}},
+		{"aksara non-latin", "日本語 テスト", []string{"日本語", "テスト"}},
+		{"dibatasi maxTerms", "a b c d e f g h i j k l", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Terms(tt.q); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Terms(%q) = %#v, want %#v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBooleanQuery(t *testing.T) {
+	if got := BooleanQuery([]string{"jadwal", "sidang"}); got != "jadwal* sidang*" {
+		t.Fatalf("BooleanQuery = %q", got)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"tanpa term tetap di-escape", "<b>a & b</b>", nil, "&lt;b&gt;a &amp; b&lt;/b&gt;"},
+		{"awal teks", "Jadwal sidang", []string{"jadwal"}, "<mark>Jadwal</mark> sidang"},
+		{"akhir teks", "Jadwal sidang", []string{"sidang"}, "Jadwal <mark>sidang</mark>"},
+		{"seluruh teks", "sidang", []string{"sidang"}, "<mark>sidang</mark>"},
+		{"awalan kata", "Revisi proposal", []string{"prop"}, "Revisi <mark>proposal</mark>"},
+		{"tidak di tengah kata", "kompropos", []string{"prop"}, "kompropos"},
+		{"beberapa term", "bab 2 dan bab 3", []string{"bab", "3"}, "<mark>bab</mark> 2 dan <mark>bab</mark> <mark>3</mark>"},
+		{"HTML di-escape di dalam dan di luar mark", `<script>alert("x")</script>`, []string{"script"},
+			"&lt;<mark>script</mark>&gt;alert(&#34;x&#34;)&lt;/<mark>script</mark>&gt;"},
+		{"term berisi karakter regexp", "a.b a+b", []string{"a.b"}, "<mark>a.b</mark> a+b"},
+		{"unicode tanpa membedakan huruf besar", "Café CAFÉ", []string{"café"}, "<mark>Café</mark> <mark>CAFÉ</mark>"},
+		{"unicode di awal kata", "naïve élève", []string{"élè"}, "naïve <mark>élève</mark>"},
+		{"unicode tidak di tengah kata", "réélève", []string{"élè"}, "réélève"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms); got != tt.want {
+				t.Fatalf("Highlight(%q)\n got: %s\nwant: %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 20)
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		width int
+		want  string
+	}{
+		{"teks pendek utuh", "Jadwal  sidang\nakhir", []string{"sidang"}, 50, "Jadwal <mark>sidang</mark> akhir"},
+		{"cocok di awal", "sidang " + long, []string{"sidang"}, 30, "<mark>sidang</mark> lorem ipsum lorem ipsum…"},
+		{"cocok di akhir", long + "sidang", []string{"sidang"}, 30, "…lorem ipsum lorem ipsum <mark>sidang</mark>"},
+		{"tanpa kecocokan diambil dari awal", long, []string{"sidang"}, 20, "lorem ipsum lorem…"},
+		{"di tengah", long + "sidang akhir " + long, []string{"sidang"}, 30, "…ipsum <mark>sidang</mark> akhir lorem…"},
+		{"HTML di-escape", "<p>sidang</p> " + long, []string{"sidang"}, 20, "&lt;p&gt;<mark>sidang</mark>&lt;/p&gt; lorem…"},
+		{"unicode dihitung per karakter", "ééééé ééééé ééééé café ééééé ééééé", []string{"café"}, 20, "…ééééé <mark>café</mark> ééééé…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms, tt.width); got != tt.want {
+				t.Fatalf("Snippet\n got: %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheOptions mengatur cache disk untuk backend remote
+type CacheOptions struct {
+	// Dir adalah direktori penyimpanan cache
+	Dir string
+	// MaxSize adalah total ukuran cache dalam byte; file yang paling lama
+	// tidak dibuka dihapus jika batas ini terlampaui
+	MaxSize int64
+	// MaxObjectSize adalah ukuran file terbesar yang disimpan di cache. File
+	// yang lebih besar (atau lebih besar dari MaxSize) tidak disalin ke disk,
+	// melainkan dialirkan langsung dari server asal sehingga Range diteruskan.
+	// 0 berarti hanya dibatasi MaxSize.
+	MaxObjectSize int64
+	// FreshFor adalah lama file cache dipakai tanpa revalidasi ke server asal
+	FreshFor time.Duration
+}
+
+// errUncacheable menandai file yang terlalu besar untuk disimpan di cache
+var errUncacheable = errors.New("storage: object too large for cache")
+
+// cacheEntry adalah metadata satu file di cache, disimpan sebagai <hash>.json
+type cacheEntry struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	ETag        string    `json:"etag"`
+	ContentType string    `json:"content_type"`
+	CheckedAt   time.Time `json:"checked_at"`
+
+	elem *list.Element
+}
+
+func (e *cacheEntry) info() ObjectInfo {
+	return ObjectInfo{
+		Key:         e.Key,
+		Size:        e.Size,
+		ModTime:     e.ModTime,
+		ContentType: e.ContentType,
+		ETag:        e.ETag,
+	}
+}
+
+// sameVersion membandingkan metadata cache dengan metadata terbaru dari server asal
+func (e *cacheEntry) sameVersion(info ObjectInfo) bool {
+	if e.ETag != "" || info.ETag != "" {
+		return e.ETag == info.ETag
+	}
+	return e.ModTime.Equal(info.ModTime) && e.Size == info.Size
+}
+
+// CachedStorage membungkus backend remote (storage Laravel) dengan cache LRU di
+// disk. Key cache adalah path lengkap file. File di cache divalidasi ulang ke
+// server asal dengan ETag/Last-Modified, request bersamaan untuk file yang sama
+// hanya mengunduh sekali, dan salinan lama tetap dilayani saat server asal mati.
+type CachedStorage struct {
+	origin Storage
+	opts   CacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	lru     *list.List // depan = paling baru dipakai
+	size    int64
+
+	group singleflight.Group
+}
+
+// NewCached membuat CachedStorage dan memuat isi cache yang sudah ada di disk
+func NewCached(origin Storage, opts CacheOptions) (*CachedStorage, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &CachedStorage{
+		origin:  origin,
+		opts:    opts,
+		entries: map[string]*cacheEntry{},
+		lru:     list.New(),
+	}
+	s.load()
+	return s, nil
+}
+
+// CacheStats adalah ringkasan isi cache
+type CacheStats struct {
+	Entries int   `json:"entries"`
+	Size    int64 `json:"size"`
+	MaxSize int64 `json:"max_size"`
+}
+
+// Stats mengembalikan jumlah dan total ukuran file di cache
+func (s *CachedStorage) Stats() CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return CacheStats{Entries: len(s.entries), Size: s.size, MaxSize: s.opts.MaxSize}
+}
+
+func (s *CachedStorage) Name() string {
+	return "cache(" + s.origin.Name() + ")"
+}
+
+func (s *CachedStorage) paths(key string) (data, meta string) {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.opts.Dir, name+".data"), filepath.Join(s.opts.Dir, name+".json")
+}
+
+// load membaca metadata cache dari disk; urutan LRU diambil dari waktu akses file
+func (s *CachedStorage) load() {
+	// Sisa unduhan yang terputus saat service berhenti
+	leftovers, _ := filepath.Glob(filepath.Join(s.opts.Dir, ".fetch-*"))
+	for _, p := range leftovers {
+		os.Remove(p)
+	}
+
+	metas, _ := filepath.Glob(filepath.Join(s.opts.Dir, "*.json"))
+	type loaded struct {
+		entry *cacheEntry
+		used  time.Time
+	}
+	var all []loaded
+	for _, metaPath := range metas {
+		raw, err := os.ReadFile(metaPath)
+		if err != nil {
+			continue
+		}
+		var e cacheEntry
+		if json.Unmarshal(raw, &e) != nil || e.Key == "" {
+			os.Remove(metaPath)
+			continue
+		}
+		dataPath, _ := s.paths(e.Key)
+		fi, err := os.Stat(dataPath)
+		if err != nil || fi.Size() != e.Size || !s.cacheable(e.Size) {
+			os.Remove(metaPath)
+			os.Remove(dataPath)
+			continue
+		}
+		all = append(all, loaded{&e, fi.ModTime()})
+	}
+
+	// Yang paling lama dipakai dimasukkan lebih dulu, sehingga berada di belakang
+	sort.Slice(all, func(i, j int) bool { return all[i].used.Before(all[j].used) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range all {
+		l.entry.elem = s.lru.PushFront(l.entry)
+		s.entries[l.entry.Key] = l.entry
+		s.size += l.entry.Size
+	}
+	s.evictLocked()
+}
+
+// lookup mengambil entry cache dan menandainya sebagai baru dipakai
+func (s *CachedStorage) lookup(key string) (cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	s.lru.MoveToFront(e.elem)
+	return *e, true
+}
+
+// store mencatat entry baru (atau pengganti) lalu menghapus file lama jika cache penuh
+func (s *CachedStorage) store(e *cacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.entries[e.Key]; ok {
+		s.lru.Remove(old.elem)
+		s.size -= old.Size
+	}
+	e.elem = s.lru.PushFront(e)
+	s.entries[e.Key] = e
+	s.size += e.Size
+	s.evictLocked()
+}
+
+// cacheable bernilai true jika file dengan ukuran size boleh disimpan di cache
+func (s *CachedStorage) cacheable(size int64) bool {
+	limit := s.opts.MaxObjectSize
+	if limit <= 0 || (s.opts.MaxSize > 0 && limit > s.opts.MaxSize) {
+		limit = s.opts.MaxSize
+	}
+	return limit <= 0 || size <= limit
+}
+
+// evictLocked menghapus file yang paling lama tidak dipakai sampai total
+// ukuran cache kembali di bawah MaxSize. File yang tidak cacheable tidak
+// pernah disimpan, jadi entry terbaru selalu muat setelah eviction.
+func (s *CachedStorage) evictLocked() {
+	for s.opts.MaxSize > 0 && s.size > s.opts.MaxSize && s.lru.Len() > 0 {
+		e := s.lru.Back().Value.(*cacheEntry)
+		s.removeLocked(e.Key)
+	}
+}
+
+func (s *CachedStorage) removeLocked(key string) {
+	e, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	s.lru.Remove(e.elem)
+	s.size -= e.Size
+	delete(s.entries, key)
+	dataPath, metaPath := s.paths(key)
+	os.Remove(metaPath)
+	os.Remove(dataPath)
+}
+
+func (s *CachedStorage) invalidate(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(key)
+}
+
+// touch memperbarui waktu revalidasi entry yang masih sama dengan server asal
+func (s *CachedStorage) touch(key string, at time.Time) {
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	var snapshot cacheEntry
+	if ok {
+		e.CheckedAt = at
+		snapshot = *e
+	}
+	s.mu.Unlock()
+	if ok {
+		s.writeMeta(snapshot)
+	}
+}
+
+func (s *CachedStorage) writeMeta(e cacheEntry) {
+	_, metaPath := s.paths(e.Key)
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	tmp := metaPath + ".tmp"
+	if os.WriteFile(tmp, raw, 0644) == nil {
+		os.Rename(tmp, metaPath)
+	}
+}
+
+// originUnavailable bernilai true jika error berasal dari server asal yang
+// tidak bisa dihubungi atau sedang bermasalah (bukan file yang tidak ada)
+func originUnavailable(err error) bool {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidKey) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// File memastikan file ada di cache dan masih valid, lalu mengembalikan path
+// lokal beserta metadatanya. File yang terlalu besar untuk cache menghasilkan
+// errUncacheable.
+func (s *CachedStorage) File(ctx context.Context, key string) (string, ObjectInfo, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", ObjectInfo{}, err
+	}
+	dataPath, _ := s.paths(key)
+
+	cached, ok := s.lookup(key)
+	if ok && time.Since(cached.CheckedAt) < s.opts.FreshFor {
+		return dataPath, cached.info(), nil
+	}
+
+	// Revalidasi dan unduhan untuk key yang sama hanya dijalankan sekali
+	v, err, _ := s.group.Do(key, func() (interface{}, error) {
+		return s.refresh(ctx, key)
+	})
+	if err != nil {
+		if ok && originUnavailable(err) && !errors.Is(err, errUncacheable) {
+			log.Printf("Cache: server asal gagal untuk %s, memakai salinan lama: %v", key, err)
+			return dataPath, cached.info(), nil
+		}
+		return "", ObjectInfo{}, err
+	}
+	return dataPath, v.(ObjectInfo), nil
+}
+
+// refresh memvalidasi entry cache ke server asal dan mengunduh ulang jika berubah
+func (s *CachedStorage) refresh(ctx context.Context, key string) (ObjectInfo, error) {
+	// Request client bisa dibatalkan, sedangkan unduhan dipakai bersama
+	ctx = context.WithoutCancel(ctx)
+
+	info, err := s.origin.Stat(ctx, key)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.Is(err, ErrNotFound) || (errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound) {
+			s.invalidate(key)
+		}
+		return ObjectInfo{}, err
+	}
+
+	if !s.cacheable(info.Size) {
+		s.invalidate(key)
+		return info, errUncacheable
+	}
+	now := time.Now()
+	if cached, ok := s.lookup(key); ok && cached.sameVersion(info) {
+		s.touch(key, now)
+		return cached.info(), nil
+	}
+	return s.download(ctx, key, now)
+}
+
+func (s *CachedStorage) download(ctx context.Context, key string, now time.Time) (ObjectInfo, error) {
+	obj, info, err := s.origin.Open(ctx, key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer obj.Close()
+
+	tmp, err := os.CreateTemp(s.opts.Dir, ".fetch-*")
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	size, err := io.Copy(tmp, obj)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && !s.cacheable(size) {
+		// Ukuran dari Stat bisa berbeda dengan isi yang diunduh
+		err = errUncacheable
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return ObjectInfo{}, err
+	}
+
+	e := &cacheEntry{
+		Key:         key,
+		Size:        size,
+		ModTime:     info.ModTime,
+		ETag:        info.ETag,
+		ContentType: info.ContentType,
+		CheckedAt:   now,
+	}
+	dataPath, _ := s.paths(key)
+	// File lama yang sedang dibaca client tetap valid sampai ditutup
+	if err := os.Rename(tmp.Name(), dataPath); err != nil {
+		os.Remove(tmp.Name())
+		return ObjectInfo{}, err
+	}
+	s.writeMeta(*e)
+	s.store(e)
+	return e.info(), nil
+}
+
+// Open melayani file dari cache. File yang terlalu besar untuk cache
+// dialirkan langsung dari server asal, sehingga Range dari client diteruskan
+// ke server asal tanpa menunggu seluruh file diunduh.
+func (s *CachedStorage) Open(ctx context.Context, key string) (Object, ObjectInfo, error) {
+	dataPath, info, err := s.File(ctx, key)
+	if errors.Is(err, errUncacheable) {
+		return s.origin.Open(ctx, key)
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	f, err := os.Open(dataPath)
+	if err != nil {
+		// Entry bisa saja baru dihapus oleh eviction, ambil ulang sekali
+		s.invalidate(info.Key)
+		if dataPath, info, err = s.File(ctx, key); errors.Is(err, errUncacheable) {
+			return s.origin.Open(ctx, key)
+		} else if err != nil {
+			return nil, ObjectInfo{}, err
+		}
+		if f, err = os.Open(dataPath); err != nil {
+			return nil, ObjectInfo{}, err
+		}
+	}
+	now := time.Now()
+	os.Chtimes(dataPath, now, now)
+	return f, info, nil
+}
+
+func (s *CachedStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	if clean, err := CleanKey(key); err == nil {
+		if cached, ok := s.lookup(clean); ok && time.Since(cached.CheckedAt) < s.opts.FreshFor {
+			return cached.info(), nil
+		}
+	}
+	return s.origin.Stat(ctx, key)
+}
+
+func (s *CachedStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := s.origin.Put(ctx, key, r, size, contentType); err != nil {
+		return err
+	}
+	if clean, err := CleanKey(key); err == nil {
+		s.invalidate(clean)
+	}
+	return nil
+}
+
+func (s *CachedStorage) Delete(ctx context.Context, key string) error {
+	if err := s.origin.Delete(ctx, key); err != nil {
+		return err
+	}
+	if clean, err := CleanKey(key); err == nil {
+		s.invalidate(clean)
+	}
+	return nil
+}
+
+func (s *CachedStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	return s.origin.List(ctx, prefix)
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// StatusError dikembalikan jika server remote membalas dengan status selain sukses
+type StatusError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("storage: %s returned status %d", e.URL, e.StatusCode)
+}
+
+// HTTPStorage adalah backend read-only yang membaca file dari server HTTP,
+// dipakai untuk file yang disimpan di storage publik Laravel
+type HTTPStorage struct {
+	BaseURL   string
+	Client    *http.Client
+	UserAgent string
+}
+
+// NewHTTP membuat HTTPStorage untuk base URL tertentu, contoh "https://host/storage/"
+func NewHTTP(baseURL string, client *http.Client) *HTTPStorage {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &HTTPStorage{
+		BaseURL:   strings.TrimRight(baseURL, "/") + "/",
+		Client:    client,
+		UserAgent: "GoBackendProxy/1.0",
+	}
+}
+
+func (s *HTTPStorage) Name() string {
+	return "http:" + s.BaseURL
+}
+
+// URL mengembalikan URL lengkap sebuah key
+func (s *HTTPStorage) URL(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return s.BaseURL + strings.Join(parts, "/"), nil
+}
+
+func (s *HTTPStorage) do(ctx context.Context, method, key string, header http.Header) (*http.Response, error) {
+	fileURL, err := s.URL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", s.UserAgent)
+	return s.Client.Do(req)
+}
+
+func (s *HTTPStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		// Sebagian server tidak mendukung HEAD, ambil byte pertama saja
+		resp, err = s.do(ctx, http.MethodGet, key, http.Header{"Range": {"bytes=0-0"}})
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+		resp.Body.Close()
+	}
+	if err := checkStatus(resp); err != nil {
+		return ObjectInfo{}, err
+	}
+	return httpInfo(key, resp), nil
+}
+
+func (s *HTTPStorage) Open(ctx context.Context, key string) (Object, ObjectInfo, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	reader := newRangeReader(info.Size, func(offset, end int64) (io.ReadCloser, error) {
+		header := http.Header{}
+		if r := rangeHeader(offset, end); r != "" {
+			header.Set("Range", r)
+		}
+		resp, err := s.do(ctx, http.MethodGet, key, header)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStatus(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if offset > 0 && resp.StatusCode == http.StatusOK {
+			return skipReader(resp.Body, offset)
+		}
+		return resp.Body, nil
+	})
+	return reader, info, nil
+}
+
+func (s *HTTPStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	return ErrReadOnly
+}
+
+func (s *HTTPStorage) Delete(ctx context.Context, key string) error {
+	return ErrReadOnly
+}
+
+func (s *HTTPStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	return nil, ErrReadOnly
+}
+
+func checkStatus(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return &StatusError{StatusCode: resp.StatusCode, URL: resp.Request.URL.String()}
+	}
+	return nil
+}
+
+// httpInfo membaca metadata object dari header response
+func httpInfo(key string, resp *http.Response) ObjectInfo {
+	info := ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+	}
+	// Untuk response 206, ukuran total ada di Content-Range: bytes 0-0/12345
+	if cr := resp.Header.Get("Content-Range"); cr != "" {
+		if i := strings.LastIndex(cr, "/"); i >= 0 {
+			fmt.Sscanf(cr[i+1:], "%d", &info.Size)
+		}
+	}
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = lm
+	}
+	return info
+}
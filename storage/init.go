@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/upstream"
+)
+
+var (
+	// Uploads menyimpan file yang diupload lewat service ini (tugas, pengumuman)
+	Uploads Storage
+	// Quarantine menyimpan file upload yang ditolak oleh scanner
+	Quarantine Storage
+	// Laravel membaca file dari storage publik aplikasi Laravel (read-only)
+	Laravel Storage
+)
+
+// Init membuat backend storage sesuai konfigurasi
+func Init(cfg config.StorageConfig, uploadCfg config.UploadConfig, upstreams config.UpstreamsConfig) error {
+	switch cfg.Driver {
+	case "local", "":
+		uploads, err := NewLocal(cfg.Root)
+		if err != nil {
+			return err
+		}
+		quarantine, err := NewLocal(uploadCfg.QuarantineDir)
+		if err != nil {
+			return err
+		}
+		Uploads, Quarantine = uploads, quarantine
+
+	case "s3":
+		s3cfg := S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+			Prefix:    cfg.S3Prefix,
+		}
+		uploads, err := NewS3(s3cfg, nil)
+		if err != nil {
+			return err
+		}
+		s3cfg.Prefix = path.Join(cfg.S3Prefix, "quarantine")
+		quarantine, err := NewS3(s3cfg, nil)
+		if err != nil {
+			return err
+		}
+		Uploads, Quarantine = uploads, quarantine
+
+	default:
+		return fmt.Errorf("storage: unknown driver %q", cfg.Driver)
+	}
+
+	laravelClient, err := upstream.NewClient(upstreams.Laravel)
+	if err != nil {
+		return err
+	}
+	Laravel = NewHTTP(upstreams.LaravelStorageURL(), laravelClient)
+	if cfg.LaravelCacheMaxSize > 0 {
+		cached, err := NewCached(Laravel, CacheOptions{
+			Dir:           cfg.LaravelCacheDir,
+			MaxSize:       cfg.LaravelCacheMaxSize,
+			MaxObjectSize: cfg.LaravelCacheMaxObject,
+			FreshFor:      cfg.LaravelCacheFresh,
+		})
+		if err != nil {
+			return err
+		}
+		Laravel = cached
+	}
+
+	log.Printf("Storage upload: %s", Uploads.Name())
+	log.Printf("Storage Laravel: %s", Laravel.Name())
+	return nil
+}
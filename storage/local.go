@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage menyimpan file di filesystem lokal di bawah sebuah root directory
+type LocalStorage struct {
+	Root string
+}
+
+// NewLocal membuat LocalStorage dan memastikan root directory ada
+func NewLocal(root string) (*LocalStorage, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid root %q: %w", root, err)
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("storage: failed to create root %q: %w", abs, err)
+	}
+	return &LocalStorage{Root: abs}, nil
+}
+
+func (s *LocalStorage) Name() string {
+	return "local:" + s.Root
+}
+
+// fullPath mengubah key menjadi path absolut dan memastikan path tetap di dalam root
+func (s *LocalStorage) fullPath(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	full := filepath.Join(s.Root, filepath.FromSlash(key))
+	if full != s.Root && !strings.HasPrefix(full, s.Root+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+	return full, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	full, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return fmt.Errorf("storage: failed to create directory: %w", err)
+	}
+
+	// Tulis ke file sementara lalu rename agar pembaca tidak melihat file setengah jadi
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("storage: failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("storage: failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("storage: failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("storage: failed to set permissions: %w", err)
+	}
+	return os.Rename(tmp.Name(), full)
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (Object, ObjectInfo, error) {
+	full, err := s.fullPath(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	f, err := os.Open(full)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, ObjectInfo{}, err
+	}
+	if fi.IsDir() {
+		f.Close()
+		return nil, ObjectInfo{}, ErrNotFound
+	}
+	return f, localInfo(key, fi), nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	full, err := s.fullPath(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(full)
+	if errors.Is(err, os.ErrNotExist) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return localInfo(key, fi), nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	full, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(full)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	full, err := s.fullPath(prefix)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(full)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, _ = CleanKey(prefix)
+	var out []ObjectInfo
+	for _, entry := range entries {
+		// File sementara dari Put tidak ikut ditampilkan
+		if strings.HasPrefix(entry.Name(), ".upload-") {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, localInfo(path.Join(prefix, entry.Name()), fi))
+	}
+	return out, nil
+}
+
+func localInfo(key string, fi os.FileInfo) ObjectInfo {
+	info := ObjectInfo{
+		Key:     key,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+	}
+	if !fi.IsDir() {
+		info.ContentType = mime.TypeByExtension(strings.ToLower(path.Ext(key)))
+		// ETag kuat dari waktu modifikasi dan ukuran; berubah setiap file ditulis ulang
+		info.ETag = fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size())
+	}
+	return info
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	minRangeChunk = 1 << 20  // 1MB
+	maxRangeChunk = 16 << 20 // 16MB
+)
+
+// rangeFetcher membuka stream isi object dari offset sampai end (inklusif).
+// end bernilai -1 jika ukuran object tidak diketahui.
+type rangeFetcher func(offset, end int64) (io.ReadCloser, error)
+
+// rangeReader membuat object remote (S3, HTTP) bisa di-seek. Setiap Seek hanya
+// memindahkan offset; request ke server baru dibuat saat Read berikutnya dengan
+// header Range. Isi file diambil per potongan yang membesar selama dibaca
+// berurutan, sehingga request Range dari client diteruskan ke server asal
+// tanpa mengunduh seluruh file.
+type rangeReader struct {
+	fetch  rangeFetcher
+	size   int64
+	offset int64
+	chunk  int64
+	body   io.ReadCloser
+}
+
+func newRangeReader(size int64, fetch rangeFetcher) *rangeReader {
+	return &rangeReader{fetch: fetch, size: size, chunk: minRangeChunk}
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for {
+		if r.size >= 0 && r.offset >= r.size {
+			return 0, io.EOF
+		}
+
+		fresh := false
+		if r.body == nil {
+			end := int64(-1)
+			if r.size >= 0 {
+				end = r.offset + r.chunk - 1
+				if end >= r.size {
+					end = r.size - 1
+				}
+			}
+			body, err := r.fetch(r.offset, end)
+			if err != nil {
+				return 0, err
+			}
+			r.body, fresh = body, true
+		}
+
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if err != io.EOF {
+			return n, err
+		}
+
+		// Potongan ini habis: lanjutkan dengan potongan berikutnya yang lebih besar
+		r.body.Close()
+		r.body = nil
+		if r.chunk < maxRangeChunk {
+			r.chunk *= 2
+		}
+		switch {
+		case n > 0:
+			return n, nil
+		case r.size < 0:
+			return 0, io.EOF
+		case fresh:
+			// Server mengirim body kosong padahal file belum selesai
+			return 0, io.ErrUnexpectedEOF
+		}
+	}
+}
+
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = r.offset + offset
+	case io.SeekEnd:
+		if r.size < 0 {
+			return 0, errors.New("storage: object size unknown")
+		}
+		next = r.size + offset
+	default:
+		return 0, errors.New("storage: invalid whence")
+	}
+	if next < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+	if next != r.offset {
+		if r.body != nil {
+			r.body.Close()
+			r.body = nil
+		}
+		// Lompatan posisi berarti akses acak, mulai lagi dari potongan kecil
+		r.chunk = minRangeChunk
+	}
+	r.offset = next
+	return next, nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body != nil {
+		err := r.body.Close()
+		r.body = nil
+		return err
+	}
+	return nil
+}
+
+// rangeHeader membuat nilai header Range untuk offset dan end (inklusif)
+func rangeHeader(offset, end int64) string {
+	if end < 0 {
+		if offset == 0 {
+			return ""
+		}
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, end)
+}
+
+// skipReader membuang n byte pertama dari body, dipakai jika server
+// mengabaikan header Range dan mengirim seluruh file
+func skipReader(body io.ReadCloser, n int64) (io.ReadCloser, error) {
+	if n == 0 {
+		return body, nil
+	}
+	if _, err := io.CopyN(io.Discard, body, n); err != nil {
+		body.Close()
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config berisi parameter koneksi ke storage S3-compatible (AWS S3, MinIO, dll.)
+type S3Config struct {
+	Endpoint  string // contoh: https://s3.ap-southeast-1.amazonaws.com atau http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle memakai URL endpoint/bucket/key, dibutuhkan oleh MinIO
+	PathStyle bool
+	// Prefix ditambahkan di depan setiap key, contoh "uploads/"
+	Prefix string
+}
+
+// S3Storage menyimpan file di bucket S3-compatible. Request ditandatangani
+// dengan AWS Signature Version 4 sehingga tidak butuh SDK tambahan.
+type S3Storage struct {
+	cfg      S3Config
+	endpoint *url.URL
+	Client   *http.Client
+}
+
+// NewS3 membuat S3Storage dari konfigurasi
+func NewS3(cfg S3Config, client *http.Client) (*S3Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("storage: S3 endpoint and bucket are required")
+	}
+	u, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Prefix != "" {
+		cfg.Prefix = strings.Trim(cfg.Prefix, "/") + "/"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &S3Storage{cfg: cfg, endpoint: u, Client: client}, nil
+}
+
+func (s *S3Storage) Name() string {
+	return fmt.Sprintf("s3:%s/%s/%s", s.endpoint.Host, s.cfg.Bucket, s.cfg.Prefix)
+}
+
+// objectURL membangun URL object dengan path-style atau virtual-hosted style
+func (s *S3Storage) objectURL(objectKey string, query url.Values) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket + "/" + objectKey
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + objectKey
+	}
+	// RawPath diisi agar path yang dikirim sama persis dengan yang ditandatangani
+	u.RawPath = escapePath(u.Path)
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	return &u
+}
+
+func (s *S3Storage) fullKey(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.cfg.Prefix + key, nil
+}
+
+func (s *S3Storage) do(ctx context.Context, method, objectKey string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := s.objectURL(objectKey, query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, time.Now().UTC())
+	return s.Client.Do(req)
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	objectKey, err := s.fullKey(key)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, objectKey, nil, header, r, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp)
+}
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	objectKey, err := s.fullKey(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, objectKey, nil, nil, nil, 0)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return ObjectInfo{}, err
+	}
+	info := httpInfo(key, resp)
+	return info, nil
+}
+
+func (s *S3Storage) Open(ctx context.Context, key string) (Object, ObjectInfo, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	objectKey, _ := s.fullKey(key)
+
+	reader := newRangeReader(info.Size, func(offset, end int64) (io.ReadCloser, error) {
+		header := http.Header{}
+		if r := rangeHeader(offset, end); r != "" {
+			header.Set("Range", r)
+		}
+		resp, err := s.do(ctx, http.MethodGet, objectKey, nil, header, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStatus(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return resp.Body, nil
+	})
+	return reader, info, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	objectKey, err := s.fullKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, objectKey, nil, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp)
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	prefix, err := CleanKey(prefix)
+	if err != nil {
+		return nil, err
+	}
+	listPrefix := s.cfg.Prefix
+	if prefix != "" {
+		listPrefix += prefix + "/"
+	}
+
+	var out []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", listPrefix)
+		query.Set("delimiter", "/")
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		// Untuk operasi bucket, path hanya berisi nama bucket
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStatus(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("storage: failed to parse S3 listing: %w", err)
+		}
+
+		for _, p := range result.CommonPrefixes {
+			key := strings.TrimSuffix(strings.TrimPrefix(p.Prefix, s.cfg.Prefix), "/")
+			out = append(out, ObjectInfo{Key: key, IsDir: true})
+		}
+		for _, obj := range result.Contents {
+			key := strings.TrimPrefix(obj.Key, s.cfg.Prefix)
+			out = append(out, ObjectInfo{
+				Key:     key,
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+				ETag:    obj.ETag,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	if len(out) == 0 && prefix != "" {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+// sign menambahkan header Authorization AWS Signature Version 4 ke request.
+// Payload tidak di-hash (UNSIGNED-PAYLOAD) agar upload bisa di-stream.
+func (s *S3Storage) sign(req *http.Request, now time.Time) {
+	s.signPayload(req, now, "UNSIGNED-PAYLOAD")
+}
+
+// signPayload menandatangani request dengan payloadHash, yaitu hex SHA-256
+// isi request atau "UNSIGNED-PAYLOAD"
+func (s *S3Storage) signPayload(req *http.Request, now time.Time, payloadHash string) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Header yang ditandatangani: host dan semua x-amz-*
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" || lk == "range" {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.cfg.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// escapePath meng-encode setiap segmen path sesuai aturan SigV4 untuk S3
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = awsEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape meng-encode semua karakter kecuali unreserved (RFC 3986)
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+	ErrReadOnly   = errors.New("storage: backend is read-only")
+)
+
+// ObjectInfo adalah metadata sebuah file (object) di storage
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+	ETag        string
+	IsDir       bool
+}
+
+// Object adalah isi file yang bisa dibaca dan di-seek, sehingga bisa dilayani
+// dengan http.ServeContent
+type Object interface {
+	io.ReadSeekCloser
+}
+
+// Storage adalah abstraksi penyimpanan file. Key selalu relatif terhadap root
+// backend dan memakai "/" sebagai separator, contoh "tugas/tugas_1_2_1745.pdf".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (Object, ObjectInfo, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	// List mengembalikan isi satu "direktori" (tidak rekursif)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Name dipakai untuk logging dan diagnostik
+	Name() string
+}
+
+// CleanKey menormalkan key dan menolak key yang keluar dari root (misalnya "../")
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	key = strings.TrimPrefix(key, "/")
+	if key == "" {
+		return "", nil
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	if strings.ContainsRune(key, 0) {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." {
+		return "", nil
+	}
+	return cleaned, nil
+}
+
+// DBPrefix adalah prefix path file yang disimpan di database sejak sebelum
+// storage dipisahkan, contoh "uploads/tugas/tugas_1_2_1745.pdf"
+const DBPrefix = "uploads/"
+
+// DBPath mengubah key storage menjadi path yang disimpan di database
+func DBPath(key string) string {
+	return DBPrefix + key
+}
+
+// KeyFromDBPath mengubah path dari database menjadi key storage
+func KeyFromDBPath(p string) string {
+	return strings.TrimPrefix(strings.ReplaceAll(p, "\\", "/"), DBPrefix)
+}
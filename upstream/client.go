@@ -0,0 +1,117 @@
+// Package upstream membuat HTTP client untuk layanan eksternal (CIS, Laravel)
+// sesuai pengaturan timeout, retry dan TLS masing-masing.
+package upstream
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rudychandra/lagi/config"
+)
+
+// NewClient membuat http.Client untuk satu upstream. Client ini dipakai
+// bersama oleh semua request agar koneksi bisa digunakan ulang.
+func NewClient(cfg config.UpstreamConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.TLS.ServerName,
+		InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+	}
+	if cfg.TLS.CAFile != "" {
+		pem, err := os.ReadFile(cfg.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("upstream: read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("upstream: no certificate found in %s", cfg.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &retryTransport{
+			base:    transport,
+			retries: cfg.Retries,
+			backoff: cfg.RetryBackoff,
+		},
+	}, nil
+}
+
+// retryTransport mengulang request yang gagal karena gangguan jaringan atau
+// karena upstream sedang bermasalah (502, 503, 504). Status 5xx hanya diulang
+// untuk method yang aman diulang (GET, HEAD).
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !t.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		// Body request harus bisa dibuat ulang sebelum dikirim lagi
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, errors.New("upstream: request body cannot be retried")
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(t.backoff * time.Duration(1<<attempt)):
+		}
+	}
+}
+
+// shouldRetry hanya mengulang GET dan HEAD. Request lain (misalnya login CIS)
+// mungkin sudah diproses upstream walau response-nya hilang, jadi hanya
+// diulang jika koneksi gagal dibuat sehingga belum ada byte yang terkirim.
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if err != nil {
+		if idempotent {
+			return true
+		}
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	if !idempotent {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
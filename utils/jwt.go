@@ -1,42 +1,266 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/rudychandra/lagi/config"
 )
 
-var jwtSecret = []byte("dicky245")
-
 // Custom Claims
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Role   string `json:"role"`
+	// SessionID adalah id sesi login (family refresh token), dipakai untuk pencabutan
+	SessionID string `json:"sid,omitempty"`
+	// ImpersonatorID adalah id admin yang membuat token impersonasi untuk user ini
+	ImpersonatorID uint `json:"imp,omitempty"`
+	// ImpersonationWrite mengizinkan request tulis dengan token impersonasi
+	ImpersonationWrite bool `json:"imp_write,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateInternalToken(userID uint, role string) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(6 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// jwtKey adalah satu kunci di keyring token internal
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	// signKey hanya diisi untuk kunci aktif
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// jwtKeyring berisi kunci aktif untuk menandatangani token baru dan kunci
+// lama yang masih diterima selama masa rotasi
+type jwtKeyring struct {
+	active *jwtKey
+	keys   map[string]*jwtKey
+	ttl    time.Duration
+	issuer string
+	// legacySecret dan legacyUntil menerima token lama tanpa kid selama masa migrasi
+	legacySecret []byte
+	legacyUntil  time.Time
+}
+
+var internalKeys *jwtKeyring
+
+// InitJWTKeys menyiapkan keyring token internal dari konfigurasi
+func InitJWTKeys(cfg config.JWTConfig) error {
+	ring := &jwtKeyring{
+		keys:   map[string]*jwtKey{},
+		ttl:    cfg.TTL,
+		issuer: cfg.Issuer,
+	}
+	if cfg.LegacySecret != "" {
+		ring.legacySecret = []byte(cfg.LegacySecret)
+		ring.legacyUntil = cfg.LegacyUntil
+	}
+
+	active := &jwtKey{kid: cfg.KID}
+	switch cfg.Algorithm {
+	case "HS256":
+		active.method = jwt.SigningMethodHS256
+		active.signKey = []byte(cfg.Secret)
+		active.verifyKey = []byte(cfg.Secret)
+	case "RS256", "EdDSA":
+		priv, err := readPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		method, pub, err := methodForPublicKey(priv.Public())
+		if err != nil {
+			return err
+		}
+		if method.Alg() != cfg.Algorithm {
+			return fmt.Errorf("jwt: %s berisi kunci %s, bukan %s", cfg.PrivateKeyFile, method.Alg(), cfg.Algorithm)
+		}
+		active.method = method
+		active.signKey = priv
+		active.verifyKey = pub
+	default:
+		return fmt.Errorf("jwt: algoritma %q tidak didukung", cfg.Algorithm)
+	}
+	ring.active = active
+	ring.keys[active.kid] = active
+
+	for _, prev := range cfg.PreviousKeys {
+		key := &jwtKey{kid: prev.KID}
+		if prev.PublicKeyFile != "" {
+			pub, err := readPublicKey(prev.PublicKeyFile)
+			if err != nil {
+				return err
+			}
+			if key.method, key.verifyKey, err = methodForPublicKey(pub); err != nil {
+				return err
+			}
+		} else {
+			key.method = jwt.SigningMethodHS256
+			key.verifyKey = []byte(prev.Secret)
+		}
+		ring.keys[key.kid] = key
+	}
+
+	internalKeys = ring
+	return nil
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: %s bukan file PEM", file)
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (crypto.Signer, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %s: %w", file, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("jwt: %s: tipe kunci tidak didukung", file)
+	}
+	return signer, nil
+}
+
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %s: %w", file, err)
+	}
+	return key, nil
+}
+
+// methodForPublicKey menentukan algoritma JWT dari tipe kunci publik
+func methodForPublicKey(pub crypto.PublicKey) (jwt.SigningMethod, crypto.PublicKey, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, k, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, k, nil
+	}
+	return nil, nil, errors.New("jwt: tipe kunci publik tidak didukung (RSA atau Ed25519)")
+}
+
+// GenerateInternalToken membuat access token untuk sesi login tertentu dan
+// mengembalikan waktu kadaluarsanya
+func GenerateInternalToken(userID uint, role, sessionID string) (string, time.Time, error) {
+	if internalKeys == nil {
+		return "", time.Time{}, errors.New("jwt keys not initialized")
+	}
+	return signInternalToken(Claims{
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
+	}, internalKeys.ttl)
+}
+
+// GenerateImpersonationToken membuat access token berumur pendek atas nama
+// user lain untuk admin. Token ini ditandai klaim imp sehingga setiap request
+// bisa diaudit dengan id admin aslinya.
+func GenerateImpersonationToken(userID uint, role, sessionID string, impersonatorID uint, allowWrite bool, ttl time.Duration) (string, time.Time, error) {
+	if internalKeys == nil {
+		return "", time.Time{}, errors.New("jwt keys not initialized")
+	}
+	if impersonatorID == 0 {
+		return "", time.Time{}, errors.New("impersonator id required")
 	}
+	return signInternalToken(Claims{
+		UserID:             userID,
+		Role:               role,
+		SessionID:          sessionID,
+		ImpersonatorID:     impersonatorID,
+		ImpersonationWrite: allowWrite,
+	}, ttl)
+}
+
+func signInternalToken(claims Claims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    internalKeys.issuer,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	active := internalKeys.active
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	signed, err := token.SignedString(active.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+// AccessTokenTTL adalah umur access token yang dibuat GenerateInternalToken
+func AccessTokenTTL() time.Duration {
+	if internalKeys == nil {
+		return 0
+	}
+	return internalKeys.ttl
 }
 
 // Verifikasi token internal
 func VerifyInternalToken(tokenString string) (*Claims, error) {
+	if internalKeys == nil {
+		return nil, errors.New("jwt keys not initialized")
+	}
+
+	var opts []jwt.ParserOption
+	if internalKeys.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(internalKeys.issuer))
+	}
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		// Token tanpa kid dibuat sebelum rotasi kunci didukung, cocokkan dengan kunci aktif
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = internalKeys.active.kid
+		}
+		key, ok := internalKeys.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		// Algoritma harus sesuai dengan kunci, agar kunci publik tidak dipakai sebagai secret HMAC
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.verifyKey, nil
+	}, opts...)
 
 	if err != nil || !token.Valid {
+		if claims, legacyErr := verifyLegacyToken(tokenString); legacyErr == nil {
+			return claims, nil
+		}
 		return nil, errors.New("invalid token")
 	}
 
@@ -47,3 +271,80 @@ func VerifyInternalToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// verifyLegacyToken menerima token HS256 tanpa kid yang ditandatangani
+// secret lama, hanya sampai JWT_LEGACY_UNTIL. Token yang masa berlakunya
+// melewati batas itu ditolak karena tidak mungkin dibuat sebelum migrasi.
+func verifyLegacyToken(tokenString string) (*Claims, error) {
+	if internalKeys.legacySecret == nil || !time.Now().Before(internalKeys.legacyUntil) {
+		return nil, errors.New("legacy key disabled")
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if kid, _ := token.Header["kid"].(string); kid != "" {
+			return nil, errors.New("legacy token has kid")
+		}
+		return internalKeys.legacySecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.ExpiresAt.After(internalKeys.legacyUntil) {
+		return nil, errors.New("invalid token")
+	}
+	// Token lama tidak punya sid, impersonasi, atau issuer
+	if claims.SessionID != "" || claims.ImpersonatorID != 0 {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// JWK adalah kunci publik dalam format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS mengembalikan kunci publik token internal (aktif dan lama) agar pihak
+// lain, misalnya aplikasi Laravel, bisa memverifikasi token. Kunci HMAC tidak
+// pernah dipublikasikan.
+func JWKS() []JWK {
+	keys := []JWK{}
+	if internalKeys == nil {
+		return keys
+	}
+	for _, key := range internalKeys.keys {
+		jwk := JWK{Kid: key.kid, Alg: key.method.Alg(), Use: "sig"}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		keys = append(keys, jwk)
+	}
+	// Kunci aktif di depan, sisanya urut kid agar response stabil
+	active := internalKeys.active.kid
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i].Kid == active) != (keys[j].Kid == active) {
+			return keys[i].Kid == active
+		}
+		return keys[i].Kid < keys[j].Kid
+	})
+	return keys
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown dibuat tanpa html.WithUnsafe, sehingga HTML mentah di dalam teks
+// dibuang dan link berbahaya (javascript:, vbscript:, data: selain gambar)
+// diganti dengan link kosong
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// RenderMarkdown mengubah teks Markdown menjadi HTML yang aman ditampilkan
+func RenderMarkdown(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderPlainText mengubah teks biasa menjadi HTML dengan karakter khusus
+// di-escape dan baris baru menjadi <br>
+func RenderPlainText(src string) string {
+	return strings.ReplaceAll(html.EscapeString(src), "\n", "<br>\n")
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/rudychandra/lagi/config"
+)
+
+// randomToken membuat string acak aman untuk URL dari n byte
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewRefreshToken membuat refresh token baru. Token hanya dikirim ke client;
+// yang disimpan di database adalah hash-nya.
+func NewRefreshToken() (token, hash string, err error) {
+	token, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashRefreshToken(token), nil
+}
+
+// SuccessorRefreshToken menurunkan refresh token pengganti dari token lama
+// dengan HMAC JWT_SECRET. Karena hasilnya selalu sama, token lama yang
+// dikirim ulang dalam masa grace bisa dijawab dengan pengganti yang sama
+// tanpa menyimpan token asli di database.
+func SuccessorRefreshToken(token string) (successor, hash string) {
+	mac := hmac.New(sha256.New, []byte(config.JwtSecret))
+	mac.Write([]byte("refresh-successor\n" + token))
+	successor = base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return successor, HashRefreshToken(successor)
+}
+
+// HashRefreshToken menghitung hash sha256 refresh token untuk pencarian di database
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewSessionID membuat id sesi login (family refresh token)
+func NewSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/storage"
+)
+
+// ScanResult adalah hasil pemindaian sebuah file
+type ScanResult struct {
+	Clean     bool
+	Signature string // nama signature jika file terdeteksi berbahaya
+}
+
+// FileScanner adalah hook pemindaian file upload. Implementasi lain (misalnya
+// layanan antivirus pihak ketiga) cukup memenuhi interface ini.
+type FileScanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// NoopScanner menerima semua file tanpa memindai
+type NoopScanner struct{}
+
+func (NoopScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	return ScanResult{Clean: true}, nil
+}
+
+// ClamAVScanner memindai file lewat protokol INSTREAM clamd.
+// Address berformat "tcp://host:port" atau "unix:///path/clamd.sock", sehingga
+// stub lokal yang berbicara protokol yang sama bisa dipakai saat development.
+type ClamAVScanner struct {
+	Address string
+	Timeout time.Duration
+}
+
+const clamAVChunkSize = 64 * 1024
+
+func (s ClamAVScanner) dial(ctx context.Context) (net.Conn, error) {
+	network, address := "tcp", s.Address
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+
+	dialer := net.Dialer{Timeout: s.Timeout}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// Scan mengirim isi file ke clamd dan membaca hasilnya
+func (s ClamAVScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("clamav: failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("clamav: failed to start stream: %w", err)
+	}
+
+	buf := make([]byte, clamAVChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return ScanResult{}, fmt.Errorf("clamav: failed to send chunk: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return ScanResult{}, fmt.Errorf("clamav: failed to send chunk: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, fmt.Errorf("clamav: failed to read file: %w", readErr)
+		}
+	}
+
+	// Chunk dengan panjang nol menandai akhir stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return ScanResult{}, fmt.Errorf("clamav: failed to end stream: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("clamav: failed to read reply: %w", err)
+	}
+	return parseClamAVReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamAVReply mengubah balasan clamd, contoh "stream: OK" atau
+// "stream: Eicar-Test-Signature FOUND", menjadi ScanResult
+func parseClamAVReply(reply string) (ScanResult, error) {
+	reply = strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case reply == "OK":
+		return ScanResult{Clean: true}, nil
+	case strings.HasSuffix(reply, "FOUND"):
+		return ScanResult{Signature: strings.TrimSpace(strings.TrimSuffix(reply, "FOUND"))}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamav: unexpected reply %q", reply)
+	}
+}
+
+// Scanner adalah scanner aktif yang dipakai oleh controller upload
+var Scanner FileScanner = NoopScanner{}
+
+// InitScanner memilih implementasi scanner berdasarkan konfigurasi upload
+func InitScanner(cfg config.UploadConfig) {
+	switch cfg.Scanner {
+	case "clamav":
+		Scanner = ClamAVScanner{Address: cfg.ClamAVAddress, Timeout: cfg.ClamAVTimeout}
+		log.Printf("Scanner upload: ClamAV (%s)", cfg.ClamAVAddress)
+	case "", "none":
+		Scanner = NoopScanner{}
+	default:
+		log.Fatalf("UPLOAD_SCANNER tidak dikenal: %s", cfg.Scanner)
+	}
+}
+
+var ErrScannerUnavailable = errors.New("file scanner unavailable")
+
+// ScanUpload memindai file upload dengan scanner aktif
+func ScanUpload(ctx context.Context, file *multipart.FileHeader) (ScanResult, error) {
+	f, err := file.Open()
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer f.Close()
+
+	result, err := Scanner.Scan(ctx, f)
+	if err != nil {
+		log.Printf("Scan upload gagal: %v", err)
+		return ScanResult{}, ErrScannerUnavailable
+	}
+	return result, nil
+}
+
+// QuarantineUpload menyimpan file mencurigakan ke storage karantina agar bisa
+// diperiksa admin, dan mengembalikan key file karantina tersebut
+func QuarantineUpload(ctx context.Context, file *multipart.FileHeader, reason string) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer src.Close()
+
+	key := fmt.Sprintf("%d_%s.quarantine", time.Now().UnixNano(), SanitizeFilename(file.Filename))
+	if err := storage.Quarantine.Put(ctx, key, src, file.Size, "application/octet-stream"); err != nil {
+		return "", fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+
+	log.Printf("File upload %q dikarantina ke %s/%s: %s", file.Filename, storage.Quarantine.Name(), key, reason)
+	return key, nil
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestParseClamAVReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    ScanResult
+		wantErr bool
+	}{
+		{"bersih", "stream: OK", ScanResult{Clean: true}, false},
+		{"bersih tanpa prefix", "OK", ScanResult{Clean: true}, false},
+		{"terdeteksi", "stream: Eicar-Test-Signature FOUND", ScanResult{Signature: "Eicar-Test-Signature"}, false},
+		{"signature dengan titik", "stream: Win.Test.EICAR_HDB-1 FOUND", ScanResult{Signature: "Win.Test.EICAR_HDB-1"}, false},
+		{"error clamd", "stream: INSTREAM size limit exceeded. ERROR", ScanResult{}, true},
+		{"kosong", "", ScanResult{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClamAVReply(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClamAVReply(%q) error = %v, wantErr %v", tt.reply, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseClamAVReply(%q) = %+v, want %+v", tt.reply, got, tt.want)
+			}
+		})
+	}
+}
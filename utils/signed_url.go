@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rudychandra/lagi/config"
+	"github.com/rudychandra/lagi/ratelimit"
+)
+
+var (
+	ErrSignatureInvalid = errors.New("invalid signature")
+	ErrSignatureExpired = errors.New("signed url expired")
+	ErrSignatureUsed    = errors.New("signed url already used")
+	ErrNonceStore       = errors.New("signed url nonce store unavailable")
+)
+
+// FileURLClaims adalah isi signed URL: file mana, untuk siapa, dan sampai kapan
+type FileURLClaims struct {
+	Path      string
+	UserID    uint
+	Role      string
+	ExpiresAt time.Time
+	SingleUse bool
+	// ClientIP diisi jika URL hanya boleh dipakai dari IP tertentu. IP tidak
+	// ditulis di URL, hanya ikut dihitung dalam tanda tangan.
+	ClientIP string
+	// SessionID adalah sesi login pembuat URL, agar URL ikut tidak berlaku
+	// saat sesi tersebut logout atau dicabut
+	SessionID string
+	// ImpersonatorID adalah admin yang membuat URL saat impersonasi, agar
+	// unduhan lewat URL ini tetap tercatat atas namanya di audit
+	ImpersonatorID uint
+	nonce          string
+}
+
+func signFileURL(c FileURLClaims, ip string) string {
+	payload := strings.Join([]string{
+		c.Path,
+		strconv.FormatUint(uint64(c.UserID), 10),
+		c.Role,
+		strconv.FormatInt(c.ExpiresAt.Unix(), 10),
+		c.nonce,
+		ip,
+		c.SessionID,
+		strconv.FormatUint(uint64(c.ImpersonatorID), 10),
+	}, "\n")
+	mac := hmac.New(sha256.New, []byte(config.SignedURL.Secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignFileURL membuat query string signed URL untuk claims yang diberikan
+func SignFileURL(c FileURLClaims) (url.Values, error) {
+	if c.SingleUse {
+		buf := make([]byte, 12)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		c.nonce = hex.EncodeToString(buf)
+	}
+
+	q := url.Values{}
+	q.Set("uid", strconv.FormatUint(uint64(c.UserID), 10))
+	q.Set("role", c.Role)
+	q.Set("exp", strconv.FormatInt(c.ExpiresAt.Unix(), 10))
+	if c.nonce != "" {
+		q.Set("nonce", c.nonce)
+	}
+	if c.ClientIP != "" {
+		q.Set("ipb", "1")
+	}
+	if c.SessionID != "" {
+		q.Set("sid", c.SessionID)
+	}
+	if c.ImpersonatorID != 0 {
+		q.Set("imp", strconv.FormatUint(uint64(c.ImpersonatorID), 10))
+	}
+	q.Set("sig", signFileURL(c, c.ClientIP))
+	return q, nil
+}
+
+// VerifyFileURL memeriksa tanda tangan, masa berlaku dan IP. Pemakaian URL
+// sekali pakai dicatat terpisah oleh UseFileURL.
+func VerifyFileURL(path string, q url.Values, clientIP string) (*FileURLClaims, error) {
+	uid, err := strconv.ParseUint(q.Get("uid"), 10, 64)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+
+	var impersonatorID uint64
+	if raw := q.Get("imp"); raw != "" {
+		if impersonatorID, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			return nil, ErrSignatureInvalid
+		}
+	}
+
+	claims := FileURLClaims{
+		Path:           path,
+		UserID:         uint(uid),
+		Role:           q.Get("role"),
+		ExpiresAt:      time.Unix(exp, 0),
+		SingleUse:      q.Get("nonce") != "",
+		SessionID:      q.Get("sid"),
+		ImpersonatorID: uint(impersonatorID),
+		nonce:          q.Get("nonce"),
+	}
+	ip := ""
+	if q.Get("ipb") == "1" {
+		ip = clientIP
+		claims.ClientIP = clientIP
+	}
+
+	expected := signFileURL(claims, ip)
+	// URL yang terikat IP dan dipakai dari IP lain juga gagal di sini
+	if !hmac.Equal([]byte(expected), []byte(q.Get("sig"))) {
+		return nil, ErrSignatureInvalid
+	}
+	if time.Now().After(claims.ExpiresAt) {
+		return nil, ErrSignatureExpired
+	}
+	return &claims, nil
+}
+
+// UseFileURL mencatat pemakaian signed URL sekali pakai di store counter
+// ratelimit, sehingga semua instance service melihat nonce yang sama.
+// "Sekali pakai" berarti satu sesi unduhan: request pertama membuka sesi
+// untuk IP client, lalu selama SessionWindow URL yang sama masih diterima
+// untuk request Range dari IP tersebut, karena PDF viewer dan download
+// manager mengambil file per potongan. Request tanpa Range atau dari IP lain
+// setelah itu ditolak dengan ErrSignatureUsed.
+func UseFileURL(ctx context.Context, claims *FileURLClaims, clientIP string, ranged bool) error {
+	if !claims.SingleUse {
+		return nil
+	}
+	if ratelimit.Default == nil {
+		return ErrNonceStore
+	}
+	store := ratelimit.Default.Store()
+
+	ttl := time.Until(claims.ExpiresAt)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	key := "signed_url:" + claims.nonce
+	used, err := store.Incr(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	sessionKey := key + ":" + clientIP
+	if used == 1 {
+		return store.Set(ctx, sessionKey, 1, config.SignedURL.SessionWindow)
+	}
+	if ranged {
+		open, err := store.Get(ctx, sessionKey)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return nil
+		}
+	}
+	return ErrSignatureUsed
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+var (
+	ErrFileTooLarge    = errors.New("file too large")
+	ErrMIMENotAllowed  = errors.New("file type not allowed")
+	unsafeFilenameChar = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	// mimePattern adalah "type/subtype" atau wildcard "type/*" (RFC 6838)
+	mimePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9!#$&^_.+-]*/([a-z0-9][a-z0-9!#$&^_.+-]*|\*)$`)
+)
+
+// SanitizeFilename membuang komponen direktori dan karakter berbahaya dari nama file
+// yang dikirim client, sehingga aman dipakai sebagai bagian dari path penyimpanan.
+func SanitizeFilename(name string) string {
+	// Normalisasi separator Windows sebelum mengambil basename
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = unsafeFilenameChar.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "._-")
+	if len(name) > 100 {
+		ext := filepath.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = name[:100-len(ext)] + ext
+	}
+	if name == "" {
+		name = "file"
+	}
+	return name
+}
+
+// ReplaceExt mengganti ekstensi nama file dengan ekstensi yang sesuai hasil deteksi MIME
+func ReplaceExt(name, ext string) string {
+	if ext == "" {
+		return name
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ext
+}
+
+// DetectMIME membaca isi file upload dan menentukan MIME type berdasarkan konten,
+// bukan berdasarkan ekstensi atau header Content-Type dari client.
+func DetectMIME(file *multipart.FileHeader) (*mimetype.MIME, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer f.Close()
+
+	return mimetype.DetectReader(f)
+}
+
+// MIMEAllowed mengecek MIME terhadap allow-list. Entri boleh berupa wildcard seperti "image/*".
+func MIMEAllowed(detected *mimetype.MIME, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	base := strings.SplitN(detected.String(), ";", 2)[0]
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(base, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+		if detected.Is(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeMIMEList merapikan allow-list MIME dari input user (huruf kecil,
+// tanpa spasi dan entri kosong) dan menolak entri yang bukan MIME type
+func NormalizeMIMEList(list []string) ([]string, error) {
+	out := []string{}
+	for _, m := range list {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m == "" {
+			continue
+		}
+		if !mimePattern.MatchString(m) {
+			return nil, fmt.Errorf("MIME type %q tidak valid", m)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// ValidateUpload mengecek ukuran dan isi file upload terhadap batasan yang diberikan.
+// MIME hasil deteksi dikembalikan agar pemanggil bisa memakai ekstensi yang benar.
+func ValidateUpload(file *multipart.FileHeader, allowed []string, maxSize int64) (*mimetype.MIME, error) {
+	if maxSize > 0 && file.Size > maxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	detected, err := DetectMIME(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !MIMEAllowed(detected, allowed) {
+		return detected, ErrMIMENotAllowed
+	}
+	return detected, nil
+}
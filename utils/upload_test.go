@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	long := strings.Repeat("a", 120)
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"nama biasa", "laporan-akhir_v2.pdf", "laporan-akhir_v2.pdf"},
+		{"path unix dibuang", "../../etc/passwd", "passwd"},
+		{"path windows dibuang", `C:\Users\mhs\tugas.docx`, "tugas.docx"},
+		{"spasi dan simbol diganti", "tugas akhir (final)!.pdf", "tugas_akhir_final_.pdf"},
+		{"unicode diganti", "résumé.pdf", "r_sum_.pdf"},
+		{"titik di awal dibuang", ".htaccess", "htaccess"},
+		{"kosong", "", "file"},
+		{"hanya simbol", "../..", "file"},
+		{"dipotong dengan ekstensi", long + ".pdf", strings.Repeat("a", 96) + ".pdf"},
+		{"ekstensi terlalu panjang ikut dipotong", long + ".abcdefghijkl", strings.Repeat("a", 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilename(tt.in); got != tt.want {
+				t.Fatalf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMIMEAllowed(t *testing.T) {
+	pdf := mimetype.Detect([]byte("%PDF-1.4\n%âãÏÓ\n1 0 obj\n"))
+	png := mimetype.Detect([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+	text := mimetype.Detect([]byte("catatan bimbingan biasa\n"))
+
+	tests := []struct {
+		name     string
+		detected *mimetype.MIME
+		allowed  []string
+		want     bool
+	}{
+		{"allow-list kosong", pdf, nil, true},
+		{"cocok persis", pdf, []string{"application/pdf"}, true},
+		{"huruf besar dan spasi", pdf, []string{" Application/PDF "}, true},
+		{"tidak ada di daftar", pdf, []string{"image/png", "image/jpeg"}, false},
+		{"wildcard", png, []string{"image/*"}, true},
+		{"wildcard tipe lain", pdf, []string{"image/*"}, false},
+		{"parameter charset diabaikan", text, []string{"text/plain"}, true},
+		{"wildcard teks dengan charset", text, []string{"text/*"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MIMEAllowed(tt.detected, tt.allowed); got != tt.want {
+				t.Fatalf("MIMEAllowed(%s, %v) = %v, want %v", tt.detected, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMIMEList(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr string
+	}{
+		{"kosong", nil, []string{}, ""},
+		{"dirapikan", []string{" Application/PDF", "", "image/*"}, []string{"application/pdf", "image/*"}, ""},
+		{"vendor type", []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+			[]string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}, ""},
+		{"tanpa subtype", []string{"application"}, nil, `"application"`},
+		{"wildcard penuh", []string{"*/*"}, nil, `"*/*"`},
+		{"berisi koma", []string{"image/png,image/jpeg"}, nil, "tidak valid"},
+		{"berisi spasi", []string{"image/ png"}, nil, "tidak valid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeMIMEList(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want berisi %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeMIMEList: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("NormalizeMIMEList(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}